@@ -2,26 +2,29 @@ package main
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"flashlight-ratings-go/internal/amazon"
+	"flashlight-ratings-go/internal/telemetry"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 func main() {
+	telemetry.SetupLogging("amazon-sync")
+
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
-		log.Fatal("DATABASE_URL is required")
+		telemetry.Fatal("DATABASE_URL is required")
 	}
 
 	db, err := sql.Open("pgx", databaseURL)
 	if err != nil {
-		log.Fatalf("open db: %v", err)
+		telemetry.Fatal("open db", "err", err)
 	}
 	defer db.Close()
 
@@ -29,7 +32,7 @@ func main() {
 	if v := os.Getenv("AMAZON_SYNC_TIMEOUT_SEC"); v != "" {
 		sec, err := strconv.Atoi(v)
 		if err != nil || sec <= 0 {
-			log.Fatalf("invalid AMAZON_SYNC_TIMEOUT_SEC: %q", v)
+			telemetry.Fatal("invalid AMAZON_SYNC_TIMEOUT_SEC", "value", v)
 		}
 		timeout = time.Duration(sec) * time.Second
 	}
@@ -59,16 +62,16 @@ func main() {
 			Marketplace:     envOr("AMAZON_MARKETPLACE", ""),
 		})
 		if err != nil {
-			log.Fatalf("configure paapi client: %v", err)
+			telemetry.Fatal("configure paapi client", "err", err)
 		}
 		client = realClient
 	}
 
 	syncer := amazon.NewSyncer(db, client, cfg)
 	if err := syncer.Run(ctx); err != nil {
-		log.Fatalf("amazon sync failed: %v", err)
+		telemetry.Fatal("amazon sync failed", "err", err)
 	}
-	log.Println("amazon sync completed")
+	slog.Info("amazon sync completed")
 }
 
 func envOr(key, fallback string) string {
@@ -1,21 +1,30 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"flashlight-ratings-go/internal/alerts"
 	"flashlight-ratings-go/internal/api"
+	"flashlight-ratings-go/internal/telemetry"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 func main() {
+	telemetry.SetupLogging("api")
+
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
-		log.Fatal("DATABASE_URL is required")
+		telemetry.Fatal("DATABASE_URL is required")
 	}
 
 	addr := os.Getenv("API_ADDR")
@@ -25,7 +34,7 @@ func main() {
 
 	db, err := sql.Open("pgx", databaseURL)
 	if err != nil {
-		log.Fatalf("open db: %v", err)
+		telemetry.Fatal("open db", "err", err)
 	}
 	defer db.Close()
 
@@ -33,10 +42,167 @@ func main() {
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(25)
 
-	srv := api.NewServer(db)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	telemetry.RegisterDBStats(db, "flashlight")
+	telemetry.ServeMetrics(ctx, envOr("METRICS_ADDR", ":9090"))
+
+	srv := api.NewServer(db, api.Config{
+		PublicBaseURL:    envOr("PUBLIC_API_URL", "http://localhost"+addr),
+		Mailer:           buildMailer(),
+		AdminTokens:      parseAdminTokens(os.Getenv("ADMIN_TOKENS")),
+		AmazonPartnerTag: os.Getenv("AMAZON_PARTNER_TAG"),
+
+		AnonymousRatePerMinute: envIntOr("ANON_RATE_PER_MINUTE", 120),
+		AnonymousBurst:         envIntOr("ANON_RATE_BURST", 60),
+		TrustProxyHeaders:      os.Getenv("TRUST_PROXY_HEADERS") == "true",
+
+		MaxScoringAge:      time.Duration(envIntOr("STATUS_MAX_SCORING_AGE_MIN", 180)) * time.Minute,
+		MaxSyncAge:         time.Duration(envIntOr("STATUS_MAX_SYNC_AGE_MIN", 180)) * time.Minute,
+		PriceStaleAfter:    time.Duration(envIntOr("STATUS_PRICE_STALE_HOURS", 24)) * time.Hour,
+		MaxStalePriceShare: envFloatOr("STATUS_MAX_STALE_PRICE_SHARE", 0.05),
+
+		CORSAllowedOrigins: parseCSVList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		LegacySunset:       envDateOr("API_LEGACY_SUNSET", time.Time{}),
+	})
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           srv.Routes(),
+		ReadHeaderTimeout: envSecondsOr("API_READ_HEADER_TIMEOUT_SEC", 5),
+		ReadTimeout:       envSecondsOr("API_READ_TIMEOUT_SEC", 15),
+		WriteTimeout:      envSecondsOr("API_WRITE_TIMEOUT_SEC", 30),
+		IdleTimeout:       envSecondsOr("API_IDLE_TIMEOUT_SEC", 120),
+		MaxHeaderBytes:    1 << 20,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("api listening", "addr", addr)
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		telemetry.Fatal("server failed", "err", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Fail /readyz first so the proxy stops routing here, keep serving
+	// through the drain period, then stop accepting and wait for in-flight
+	// requests.
+	drain := envSecondsOr("API_SHUTDOWN_DRAIN_SEC", 5)
+	slog.Info("api draining", "drain", drain.String())
+	srv.Drain()
+	time.Sleep(drain)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), envSecondsOr("API_SHUTDOWN_TIMEOUT_SEC", 20))
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("api shutdown incomplete", "err", err)
+	}
+	if err := srv.Flush(shutdownCtx); err != nil {
+		slog.Error("flush api key usage", "err", err)
+	}
+	slog.Info("api stopped")
+}
+
+// buildMailer returns an SMTP sender when SMTP_HOST is set and otherwise logs
+// emails, which is what local development wants.
+func buildMailer() alerts.Sender {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return alerts.LogSender{}
+	}
+	return alerts.NewSMTPSender(alerts.SMTPConfig{
+		Host:     host,
+		Port:     envIntOr("SMTP_PORT", 587),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     envOr("SMTP_FROM", "alerts@flashlightratings.com"),
+	})
+}
+
+// parseAdminTokens reads ADMIN_TOKENS, a comma-separated list of actor:token
+// pairs. Malformed entries and tokens under 24 characters are skipped.
+func parseAdminTokens(raw string) map[string]string {
+	tokens := make(map[string]string)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		actor, token, ok := strings.Cut(entry, ":")
+		actor, token = strings.TrimSpace(actor), strings.TrimSpace(token)
+		if !ok || actor == "" || len(token) < 24 {
+			slog.Warn("ignoring malformed ADMIN_TOKENS entry", "actor", actor)
+			continue
+		}
+		tokens[token] = actor
+	}
+	return tokens
+}
+
+func envOr(key, fallback string) string {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	return v
+}
 
-	log.Printf("api listening on %s", addr)
-	if err := http.ListenAndServe(addr, srv.Routes()); err != nil {
-		log.Fatalf("server failed: %v", err)
+func envFloatOr(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fallback
+	}
+	return f
+}
+
+// envDateOr reads a YYYY-MM-DD date as midnight UTC.
+func envDateOr(key string, fallback time.Time) time.Time {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		slog.Warn("ignoring malformed date setting", "key", key, "value", v)
+		return fallback
+	}
+	return t
+}
+
+func envSecondsOr(key string, fallback int) time.Duration {
+	return time.Duration(envIntOr(key, fallback)) * time.Second
+}
+
+// parseCSVList splits a comma-separated setting, dropping empty entries.
+func parseCSVList(raw string) []string {
+	var out []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func envIntOr(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
 	}
+	return n
 }
@@ -4,11 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"flag"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
 	"flashlight-ratings-go/internal/catalog"
+	"flashlight-ratings-go/internal/telemetry"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
@@ -19,32 +20,30 @@ func main() {
 	partnerTag := flag.String("partner-tag", "", "Amazon partner tag (overrides AMAZON_PARTNER_TAG env)")
 	flag.Parse()
 
-	log.SetFlags(log.LstdFlags | log.Lmsgprefix)
-	log.SetPrefix("catalog-build: ")
+	telemetry.SetupLogging("catalog-build")
 
 	cat, err := catalog.ParseFile(*catalogFile)
 	if err != nil {
-		log.Fatalf("parse: %v", err)
+		telemetry.Fatal("parse catalog", "file", *catalogFile, "err", err)
 	}
-	log.Printf("loaded %d products from %s", len(cat.Products), *catalogFile)
+	slog.Info("catalog loaded", "products", len(cat.Products), "file", *catalogFile)
 
 	warnings := cat.Validate()
 	for _, w := range warnings {
-		log.Printf("WARN %s", w)
+		slog.Warn("catalog warning", "warning", w)
 	}
 
 	if *validateOnly {
 		if len(warnings) > 0 {
-			log.Printf("%d warnings found", len(warnings))
-			os.Exit(1)
+			telemetry.Fatal("catalog has warnings", "warnings", len(warnings))
 		}
-		log.Println("catalog is valid")
+		slog.Info("catalog is valid")
 		return
 	}
 
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
-		log.Fatal("DATABASE_URL is required")
+		telemetry.Fatal("DATABASE_URL is required")
 	}
 
 	tag := *partnerTag
@@ -52,12 +51,12 @@ func main() {
 		tag = os.Getenv("AMAZON_PARTNER_TAG")
 	}
 	if tag == "" {
-		log.Fatal("AMAZON_PARTNER_TAG is required (env or -partner-tag flag)")
+		telemetry.Fatal("AMAZON_PARTNER_TAG is required (env or -partner-tag flag)")
 	}
 
 	db, err := sql.Open("pgx", databaseURL)
 	if err != nil {
-		log.Fatalf("open db: %v", err)
+		telemetry.Fatal("open db", "err", err)
 	}
 	defer db.Close()
 
@@ -65,15 +64,20 @@ func main() {
 	defer cancel()
 
 	if err := db.PingContext(ctx); err != nil {
-		log.Fatalf("db ping: %v", err)
+		telemetry.Fatal("db ping", "err", err)
 	}
 
 	builder := catalog.NewBuilder(db, tag)
 	result, err := builder.Build(ctx, cat)
 	if err != nil {
-		log.Fatalf("build failed: %v", err)
+		telemetry.Fatal("catalog build failed", "err", err)
 	}
 
-	log.Printf("done: %d brands, %d products, %d images, %d affiliate links",
-		result.Brands, result.Products, result.Images, result.Affiliates)
+	slog.Info("catalog build completed",
+		"brands", result.Brands,
+		"products", result.Products,
+		"images", result.Images,
+		"affiliate_links", result.Affiliates,
+		"slug_redirects", result.Redirects,
+	)
 }
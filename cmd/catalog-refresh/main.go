@@ -2,11 +2,12 @@ package main
 
 import (
 	"flag"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
 	"flashlight-ratings-go/internal/catalog"
+	"flashlight-ratings-go/internal/telemetry"
 )
 
 func main() {
@@ -20,13 +21,13 @@ func main() {
 	dryRun := flag.Bool("dry-run", false, "validate only, don't write output")
 	flag.Parse()
 
-	log.SetFlags(log.Ltime)
+	telemetry.SetupLogging("catalog-refresh")
 
 	cat, err := catalog.ParseFile(*catalogFile)
 	if err != nil {
-		log.Fatalf("parse catalog: %v", err)
+		telemetry.Fatal("parse catalog", "file", *catalogFile, "err", err)
 	}
-	log.Printf("catalog-refresh: loaded %d products from %s", len(cat.Products), *catalogFile)
+	slog.Info("catalog loaded", "products", len(cat.Products), "file", *catalogFile)
 
 	cfg := catalog.DefaultRefreshConfig()
 	cfg.Concurrency = *concurrency
@@ -40,10 +41,10 @@ func main() {
 	}
 
 	refreshed, report := catalog.Refresh(cat, cfg)
-	report.Print()
+	report.Log()
 
 	if *dryRun {
-		log.Printf("dry-run: would keep %d of %d products", len(refreshed.Products), len(cat.Products))
+		slog.Info("dry run: catalog not written", "kept", len(refreshed.Products), "products", len(cat.Products))
 		if report.Dropped > 0 {
 			os.Exit(1)
 		}
@@ -56,7 +57,7 @@ func main() {
 	}
 
 	if err := catalog.WriteCatalog(out, refreshed); err != nil {
-		log.Fatalf("write catalog: %v", err)
+		telemetry.Fatal("write catalog", "file", out, "err", err)
 	}
-	log.Printf("catalog-refresh: wrote %d products to %s", len(refreshed.Products), out)
+	slog.Info("catalog written", "products", len(refreshed.Products), "file", out)
 }
@@ -3,25 +3,27 @@ package main
 import (
 	"context"
 	"database/sql"
-	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
 	"flashlight-ratings-go/internal/scoring"
+	"flashlight-ratings-go/internal/telemetry"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 func main() {
+	telemetry.SetupLogging("scorejob")
+
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
-		log.Fatal("DATABASE_URL is required")
+		telemetry.Fatal("DATABASE_URL is required")
 	}
 
 	db, err := sql.Open("pgx", databaseURL)
 	if err != nil {
-		log.Fatalf("open db: %v", err)
+		telemetry.Fatal("open db", "err", err)
 	}
 	defer db.Close()
 
@@ -35,10 +37,10 @@ func main() {
 		InitiatedBy:    envOr("SCORING_INITIATED_BY", "scorejob"),
 	})
 	if err != nil {
-		log.Fatalf("scoring run failed: %v", err)
+		telemetry.Fatal("scoring run failed", "err", err)
 	}
 
-	fmt.Printf("scoring run completed: run_id=%d\n", runID)
+	slog.Info("scoring run completed", "run_id", runID)
 }
 
 func envOr(key, fallback string) string {
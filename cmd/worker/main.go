@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"strconv"
@@ -11,8 +11,11 @@ import (
 	"syscall"
 	"time"
 
+	"flashlight-ratings-go/internal/alerts"
 	"flashlight-ratings-go/internal/amazon"
 	"flashlight-ratings-go/internal/scoring"
+	"flashlight-ratings-go/internal/telemetry"
+	"flashlight-ratings-go/internal/velocity"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
@@ -25,56 +28,111 @@ type workerConfig struct {
 	scoreFormula     string
 	scoreInitiatedBy string
 	amazonSync       amazon.SyncConfig
+	velocityDays     int
+	velocityTimeout  time.Duration
+	alertsEnabled    bool
+	alertsTimeout    time.Duration
+	alertNotifier    alerts.NotifierConfig
+	metricsAddr      string
 }
 
 func main() {
+	telemetry.SetupLogging("worker")
+
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
-		log.Fatal("DATABASE_URL is required")
+		telemetry.Fatal("DATABASE_URL is required")
 	}
 
 	cfg := loadConfig()
 
 	db, err := sql.Open("pgx", databaseURL)
 	if err != nil {
-		log.Fatalf("open db: %v", err)
+		telemetry.Fatal("open db", "err", err)
 	}
 	defer db.Close()
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	telemetry.RegisterDBStats(db, "flashlight")
+	telemetry.ServeMetrics(ctx, cfg.metricsAddr)
+
 	client, err := buildAmazonClient(cfg.amazonSync)
 	if err != nil {
-		log.Fatalf("configure paapi client: %v", err)
+		telemetry.Fatal("configure paapi client", "err", err)
 	}
 
+	notifier := alerts.NewNotifier(db, buildMailer(), cfg.alertNotifier)
+
 	runCycle := func() {
-		log.Println("worker cycle started")
+		cycleStart := time.Now()
+		var cycleErr error
+		defer func() {
+			telemetry.WorkerCycleDuration.WithLabelValues("cycle", telemetry.Result(cycleErr)).Observe(telemetry.Since(cycleStart))
+		}()
+		slog.Info("worker cycle started")
 
 		syncCtx, cancelSync := context.WithTimeout(ctx, cfg.syncTimeout)
 		syncer := amazon.NewSyncer(db, client, cfg.amazonSync)
-		if err := syncer.Run(syncCtx); err != nil {
-			cancelSync()
-			log.Printf("amazon sync failed: %v", err)
+		err := timeStage("amazon_sync", func() error { return syncer.Run(syncCtx) })
+		cancelSync()
+		if err != nil {
+			cycleErr = err
+			slog.Error("amazon sync failed", "err", err)
 			return
 		}
-		cancelSync()
-		log.Println("amazon sync completed")
+		slog.Info("amazon sync completed")
 
 		scoreCtx, cancelScore := context.WithTimeout(ctx, cfg.scoreTimeout)
 		engine := scoring.NewEngine(db)
-		runID, err := engine.RunBatch(scoreCtx, scoring.RunOptions{
-			RunLabel:       "worker-" + time.Now().UTC().Format("20060102-150405"),
-			FormulaVersion: cfg.scoreFormula,
-			InitiatedBy:    cfg.scoreInitiatedBy,
+		var runID int64
+		err = timeStage("scoring", func() (err error) {
+			runID, err = engine.RunBatch(scoreCtx, scoring.RunOptions{
+				RunLabel:       "worker-" + time.Now().UTC().Format("20060102-150405"),
+				FormulaVersion: cfg.scoreFormula,
+				InitiatedBy:    cfg.scoreInitiatedBy,
+			})
+			return err
 		})
 		cancelScore()
 		if err != nil {
-			log.Printf("score batch failed: %v", err)
+			cycleErr = err
+			slog.Error("score batch failed", "run_id", runID, "err", err)
+			return
+		}
+		slog.Info("score batch completed", "run_id", runID)
+
+		velocityCtx, cancelVelocity := context.WithTimeout(ctx, cfg.velocityTimeout)
+		var rolled int
+		err = timeStage("velocity", func() (err error) {
+			rolled, err = velocity.NewRollup(db, velocity.RollupConfig{Days: cfg.velocityDays}).Run(velocityCtx, time.Now())
+			return err
+		})
+		cancelVelocity()
+		if err != nil {
+			cycleErr = err
+			slog.Error("review velocity rollup failed", "err", err)
+		} else {
+			slog.Info("review velocity rollup completed", "rows", rolled)
+		}
+
+		if !cfg.alertsEnabled {
+			return
+		}
+		alertCtx, cancelAlerts := context.WithTimeout(ctx, cfg.alertsTimeout)
+		var sent int
+		err = timeStage("alerts", func() (err error) {
+			sent, err = notifier.Run(alertCtx)
+			return err
+		})
+		cancelAlerts()
+		if err != nil {
+			cycleErr = err
+			slog.Error("price alerts failed", "sent", sent, "err", err)
 			return
 		}
-		log.Printf("score batch completed: run_id=%d", runID)
+		slog.Info("price alerts completed", "sent", sent)
 	}
 
 	if cfg.runOnStart {
@@ -83,12 +141,12 @@ func main() {
 
 	ticker := time.NewTicker(cfg.interval)
 	defer ticker.Stop()
-	log.Printf("worker scheduler running interval=%s", cfg.interval)
+	slog.Info("worker scheduler running", "interval", cfg.interval.String())
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("worker shutting down")
+			slog.Info("worker shutting down")
 			return
 		case <-ticker.C:
 			runCycle()
@@ -96,6 +154,14 @@ func main() {
 	}
 }
 
+// timeStage runs one cycle stage and records its duration.
+func timeStage(stage string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	telemetry.WorkerCycleDuration.WithLabelValues(stage, telemetry.Result(err)).Observe(telemetry.Since(start))
+	return err
+}
+
 func buildAmazonClient(cfg amazon.SyncConfig) (amazon.Client, error) {
 	if cfg.DryRun {
 		return nil, nil
@@ -110,6 +176,22 @@ func buildAmazonClient(cfg amazon.SyncConfig) (amazon.Client, error) {
 	})
 }
 
+// buildMailer returns an SMTP sender when SMTP_HOST is set and otherwise logs
+// emails.
+func buildMailer() alerts.Sender {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return alerts.LogSender{}
+	}
+	return alerts.NewSMTPSender(alerts.SMTPConfig{
+		Host:     host,
+		Port:     envIntOr("SMTP_PORT", 587),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     envOr("SMTP_FROM", "alerts@flashlightratings.com"),
+	})
+}
+
 func loadConfig() workerConfig {
 	return workerConfig{
 		interval:         time.Duration(envIntOr("WORKER_INTERVAL_SEC", 1800)) * time.Second,
@@ -129,6 +211,18 @@ func loadConfig() workerConfig {
 			AllowedBrands:  parseCSVSet(envOr("AMAZON_ALLOWED_BRANDS", "")),
 			AllowedSellers: parseCSVSet(envOr("AMAZON_ALLOWED_SELLERS", "")),
 		},
+		metricsAddr:     envOr("METRICS_ADDR", ":9091"),
+		velocityDays:    envIntOr("VELOCITY_ROLLUP_DAYS", 3),
+		velocityTimeout: time.Duration(envIntOr("VELOCITY_TIMEOUT_SEC", 60)) * time.Second,
+		alertsEnabled:   envOr("ALERTS_ENABLED", "true") == "true",
+		alertsTimeout:   time.Duration(envIntOr("ALERTS_TIMEOUT_SEC", 120)) * time.Second,
+		alertNotifier: alerts.NotifierConfig{
+			Cooldown: time.Duration(envIntOr("ALERT_COOLDOWN_HOURS", 72)) * time.Hour,
+			// Links in alert emails point at the versioned API.
+			BaseURL:   strings.TrimRight(envOr("PUBLIC_API_URL", "http://localhost:8080"), "/") + "/v1",
+			SiteURL:   envOr("PUBLIC_SITE_URL", "https://flashlightratings.com"),
+			BatchSize: envIntOr("ALERTS_BATCH_SIZE", 200),
+		},
 	}
 }
 
@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Trigram indexes back fuzzy matching on the short identifying fields.
+CREATE INDEX IF NOT EXISTS idx_flashlights_name_trgm
+    ON flashlights USING GIN (lower(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_flashlights_model_code_trgm
+    ON flashlights USING GIN (lower(model_code) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_brands_name_trgm
+    ON brands USING GIN (lower(name::TEXT) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_specs_led_model_trgm
+    ON flashlight_specs USING GIN (lower(led_model) gin_trgm_ops);
+
+COMMIT;
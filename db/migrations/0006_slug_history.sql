@@ -0,0 +1,16 @@
+BEGIN;
+
+-- Retired slugs keep resolving after a product is renamed in catalog.yaml.
+-- Rows are keyed by the old slug text, not by id, because ids are not stable
+-- across catalog rebuilds.
+CREATE TABLE IF NOT EXISTS flashlight_slug_history (
+    old_slug TEXT PRIMARY KEY,
+    flashlight_id BIGINT NOT NULL REFERENCES flashlights(id) ON DELETE CASCADE,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (old_slug ~ '^[a-z0-9]+(?:-[a-z0-9]+)*$')
+);
+
+CREATE INDEX IF NOT EXISTS idx_slug_history_flashlight
+    ON flashlight_slug_history (flashlight_id);
+
+COMMIT;
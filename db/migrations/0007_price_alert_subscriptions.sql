@@ -0,0 +1,34 @@
+BEGIN;
+
+-- Double opt-in: alerts start as pending until the confirmation link is used.
+-- Only a hash of the confirmation token is stored. The manage token is kept
+-- as-is because every notification has to carry the unsubscribe link.
+ALTER TABLE price_drop_alerts
+    ADD COLUMN IF NOT EXISTS confirm_token_hash TEXT,
+    ADD COLUMN IF NOT EXISTS manage_token TEXT,
+    ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMPTZ;
+
+ALTER TABLE price_drop_alerts DROP CONSTRAINT IF EXISTS price_drop_alerts_status_check;
+ALTER TABLE price_drop_alerts
+    ADD CONSTRAINT price_drop_alerts_status_check
+    CHECK (status IN ('pending', 'active', 'paused', 'triggered', 'unsubscribed'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_price_drop_alerts_confirm_token
+    ON price_drop_alerts (confirm_token_hash) WHERE confirm_token_hash IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_price_drop_alerts_manage_token
+    ON price_drop_alerts (manage_token) WHERE manage_token IS NOT NULL;
+
+-- One live subscription per address, light and currency.
+CREATE UNIQUE INDEX IF NOT EXISTS uq_price_drop_alerts_subscriber
+    ON price_drop_alerts (flashlight_id, lower(email), currency_code)
+    WHERE status <> 'unsubscribed';
+
+ALTER TABLE price_drop_events
+    ADD COLUMN IF NOT EXISTS alert_id BIGINT REFERENCES price_drop_alerts(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS target_price NUMERIC(12,2),
+    ADD COLUMN IF NOT EXISTS currency_code CHAR(3) NOT NULL DEFAULT 'USD';
+
+CREATE INDEX IF NOT EXISTS idx_price_drop_events_alert
+    ON price_drop_events (alert_id, event_at DESC);
+
+COMMIT;
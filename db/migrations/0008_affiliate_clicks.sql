@@ -0,0 +1,24 @@
+BEGIN;
+
+-- One row per outbound affiliate click through GET /go/{flashlight_id}.
+-- No IP address, cookie or raw user agent is stored: only the page the click
+-- came from, its position on that page and a coarse device class.
+CREATE TABLE IF NOT EXISTS affiliate_click_events (
+    id BIGSERIAL PRIMARY KEY,
+    flashlight_id BIGINT NOT NULL REFERENCES flashlights(id) ON DELETE CASCADE,
+    affiliate_link_id BIGINT REFERENCES affiliate_links(id) ON DELETE SET NULL,
+    provider TEXT NOT NULL,
+    region_code CHAR(2) NOT NULL,
+    source_page TEXT NOT NULL DEFAULT 'unknown',
+    position INTEGER CHECK (position IS NULL OR position > 0),
+    user_agent_class TEXT NOT NULL DEFAULT 'other',
+    clicked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (user_agent_class IN ('desktop', 'mobile', 'tablet', 'bot', 'other'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_affiliate_clicks_time
+    ON affiliate_click_events (clicked_at DESC);
+CREATE INDEX IF NOT EXISTS idx_affiliate_clicks_flashlight_time
+    ON affiliate_click_events (flashlight_id, clicked_at DESC);
+
+COMMIT;
@@ -0,0 +1,27 @@
+BEGIN;
+
+-- One row per write through the /admin API. before_data and after_data hold
+-- the affected rows as JSON (NULL when the entity did not exist before or
+-- was removed) so any change can be reviewed or reverted by hand.
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    flashlight_id BIGINT REFERENCES flashlights(id) ON DELETE SET NULL,
+    entity_key TEXT,
+    reason TEXT NOT NULL,
+    before_data JSONB,
+    after_data JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (action IN ('create', 'update', 'deactivate')),
+    CHECK (entity_type IN ('flashlight', 'specs', 'modes', 'media', 'affiliate_link')),
+    CHECK (length(trim(reason)) > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_time
+    ON admin_audit_log (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_flashlight_time
+    ON admin_audit_log (flashlight_id, created_at DESC);
+
+COMMIT;
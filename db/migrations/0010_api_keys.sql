@@ -0,0 +1,37 @@
+BEGIN;
+
+-- Partner API keys. Only the SHA-256 of the secret is stored; key_prefix is
+-- the first characters of the secret so a key can be recognised in support
+-- requests. NULL limits mean unlimited. Internal keys (our own web app) skip
+-- rate limits and quotas but are still counted.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    key_prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    rate_per_minute INTEGER CHECK (rate_per_minute IS NULL OR rate_per_minute > 0),
+    burst INTEGER CHECK (burst IS NULL OR burst > 0),
+    daily_quota INTEGER CHECK (daily_quota IS NULL OR daily_quota > 0),
+    is_internal BOOLEAN NOT NULL DEFAULT FALSE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    CHECK (length(trim(name)) > 0)
+);
+
+-- Requests per key, UTC day and endpoint (the API route pattern).
+-- rejected_count covers requests refused by the rate limit or quota.
+CREATE TABLE IF NOT EXISTS api_key_usage_daily (
+    api_key_id BIGINT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    usage_date DATE NOT NULL,
+    endpoint TEXT NOT NULL,
+    request_count BIGINT NOT NULL DEFAULT 0 CHECK (request_count >= 0),
+    rejected_count BIGINT NOT NULL DEFAULT 0 CHECK (rejected_count >= 0),
+    PRIMARY KEY (api_key_id, usage_date, endpoint)
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_key_usage_date
+    ON api_key_usage_daily (usage_date DESC);
+
+COMMIT;
@@ -0,0 +1,53 @@
+BEGIN;
+
+-- data_versions.version goes up with every statement that changes data the
+-- read API serves. The API combines it with the latest completed scoring run
+-- into ETags and drops its response cache when either changes. Bumping a row
+-- (rather than a sequence) keeps the new version invisible until the writing
+-- transaction commits.
+CREATE TABLE IF NOT EXISTS data_versions (
+    id SMALLINT PRIMARY KEY DEFAULT 1,
+    version BIGINT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (id = 1)
+);
+
+INSERT INTO data_versions (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
+
+CREATE OR REPLACE FUNCTION bump_data_version() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE data_versions SET version = version + 1, updated_at = NOW() WHERE id = 1;
+    RETURN NULL;
+END;
+$$;
+
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'brands',
+        'flashlights',
+        'flashlight_specs',
+        'flashlight_modes',
+        'flashlight_media',
+        'flashlight_battery_compatibility',
+        'flashlight_slug_history',
+        'affiliate_links',
+        'flashlight_price_snapshots',
+        'amazon_product_snapshots',
+        'flashlight_review_velocity_daily',
+        'use_cases',
+        'flashlight_use_cases',
+        'scoring_runs'
+    ] LOOP
+        EXECUTE format('DROP TRIGGER IF EXISTS trg_bump_data_version ON %I', t);
+        EXECUTE format(
+            'CREATE TRIGGER trg_bump_data_version AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON %I
+             FOR EACH STATEMENT EXECUTE FUNCTION bump_data_version()', t);
+    END LOOP;
+END;
+$$;
+
+COMMIT;
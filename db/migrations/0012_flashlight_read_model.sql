@@ -0,0 +1,193 @@
+BEGIN;
+
+-- flashlight_read_model holds, per light, everything the read API used to
+-- derive per request from snapshot and score tables: the latest USD price and
+-- Amazon stats, the first image, the pivoted scores of the latest completed
+-- run, and the active Amazon link and latest price per region/currency.
+-- Listing, ranking and comparison queries join it by primary key instead of
+-- running DISTINCT ON and GROUP BY over history tables.
+--
+-- It is rebuilt by refresh_flashlight_read_model(), which writers call inside
+-- the transaction that publishes new data: after each Amazon sync, when a
+-- scoring run is marked completed, and after catalog builds and admin edits.
+CREATE TABLE IF NOT EXISTS flashlight_read_model (
+    flashlight_id BIGINT PRIMARY KEY REFERENCES flashlights(id) ON DELETE CASCADE,
+    image_url TEXT,
+    price_usd NUMERIC(12,2),
+    price_usd_captured_at TIMESTAMPTZ,
+    -- Latest price per currency, e.g. {"USD": 49.95, "EUR": 54.90}.
+    prices JSONB NOT NULL DEFAULT '{}'::JSONB,
+    -- Active Amazon link per region, e.g. {"US": {"url": ..., "asin": ..., "region": "US"}}.
+    offers JSONB NOT NULL DEFAULT '{}'::JSONB,
+    amazon_rating_count INTEGER,
+    amazon_average_rating NUMERIC(3,2),
+    amazon_captured_at TIMESTAMPTZ,
+    run_id BIGINT REFERENCES scoring_runs(id) ON DELETE SET NULL,
+    tactical_score NUMERIC(6,3),
+    edc_score NUMERIC(6,3),
+    value_score NUMERIC(6,3),
+    throw_score NUMERIC(6,3),
+    flood_score NUMERIC(6,3),
+    refreshed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_read_model_price ON flashlight_read_model(price_usd);
+CREATE INDEX IF NOT EXISTS idx_read_model_tactical ON flashlight_read_model(tactical_score DESC);
+
+-- refresh_flashlight_read_model rebuilds every row and returns the number of
+-- rows that changed. Unchanged rows are left alone so refreshed_at says when
+-- a light's public data last moved. Refreshes are serialised with an
+-- advisory lock so concurrent writers cannot deadlock on the upsert.
+CREATE OR REPLACE FUNCTION refresh_flashlight_read_model() RETURNS INTEGER
+LANGUAGE plpgsql AS $$
+DECLARE
+    changed INTEGER;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('flashlight_read_model'));
+
+    WITH latest_run AS (
+        SELECT id
+        FROM scoring_runs
+        WHERE status = 'completed'
+        ORDER BY completed_at DESC NULLS LAST, id DESC
+        LIMIT 1
+    ),
+    latest_usd AS (
+        SELECT DISTINCT ON (p.flashlight_id)
+            p.flashlight_id,
+            p.price,
+            p.captured_at
+        FROM flashlight_price_snapshots p
+        WHERE p.currency_code = 'USD'
+        ORDER BY p.flashlight_id, p.captured_at DESC
+    ),
+    latest_prices AS (
+        SELECT flashlight_id, jsonb_object_agg(currency_code, price) AS prices
+        FROM (
+            SELECT DISTINCT ON (p.flashlight_id, p.currency_code)
+                p.flashlight_id,
+                TRIM(p.currency_code) AS currency_code,
+                p.price
+            FROM flashlight_price_snapshots p
+            ORDER BY p.flashlight_id, p.currency_code, p.captured_at DESC
+        ) latest
+        GROUP BY flashlight_id
+    ),
+    latest_amazon AS (
+        SELECT DISTINCT ON (aps.flashlight_id)
+            aps.flashlight_id,
+            aps.rating_count,
+            aps.average_rating,
+            aps.captured_at
+        FROM amazon_product_snapshots aps
+        ORDER BY aps.flashlight_id, aps.captured_at DESC
+    ),
+    active_offers AS (
+        SELECT
+            flashlight_id,
+            jsonb_object_agg(region_code, jsonb_build_object('url', affiliate_url, 'asin', asin, 'region', region_code)) AS offers
+        FROM (
+            SELECT DISTINCT ON (a.flashlight_id, a.region_code)
+                a.flashlight_id,
+                TRIM(a.region_code) AS region_code,
+                a.affiliate_url,
+                a.asin
+            FROM affiliate_links a
+            WHERE a.provider = 'amazon'
+              AND a.is_active = TRUE
+            ORDER BY a.flashlight_id, a.region_code, a.is_primary DESC, a.updated_at DESC, a.id DESC
+        ) links
+        GROUP BY flashlight_id
+    ),
+    first_image AS (
+        SELECT DISTINCT ON (m.flashlight_id)
+            m.flashlight_id,
+            m.url
+        FROM flashlight_media m
+        WHERE m.media_type = 'image'
+        ORDER BY m.flashlight_id, m.sort_order ASC, m.id ASC
+    ),
+    latest_scores AS (
+        SELECT
+            fs.flashlight_id,
+            fs.run_id,
+            MAX(CASE WHEN sp.slug = 'tactical' THEN fs.score END) AS tactical_score,
+            MAX(CASE WHEN sp.slug = 'edc' THEN fs.score END) AS edc_score,
+            MAX(CASE WHEN sp.slug = 'value' THEN fs.score END) AS value_score,
+            MAX(CASE WHEN sp.slug = 'throw' THEN fs.score END) AS throw_score,
+            MAX(CASE WHEN sp.slug = 'flood' THEN fs.score END) AS flood_score
+        FROM flashlight_scores fs
+        JOIN scoring_profiles sp ON sp.id = fs.profile_id
+        JOIN latest_run lr ON lr.id = fs.run_id
+        GROUP BY fs.flashlight_id, fs.run_id
+    )
+    INSERT INTO flashlight_read_model AS rm (
+        flashlight_id, image_url, price_usd, price_usd_captured_at, prices, offers,
+        amazon_rating_count, amazon_average_rating, amazon_captured_at,
+        run_id, tactical_score, edc_score, value_score, throw_score, flood_score, refreshed_at
+    )
+    SELECT
+        f.id,
+        fi.url,
+        lu.price,
+        lu.captured_at,
+        COALESCE(lp.prices, '{}'::JSONB),
+        COALESCE(ao.offers, '{}'::JSONB),
+        la.rating_count,
+        la.average_rating,
+        la.captured_at,
+        ls.run_id,
+        ls.tactical_score,
+        ls.edc_score,
+        ls.value_score,
+        ls.throw_score,
+        ls.flood_score,
+        NOW()
+    FROM flashlights f
+    LEFT JOIN first_image fi ON fi.flashlight_id = f.id
+    LEFT JOIN latest_usd lu ON lu.flashlight_id = f.id
+    LEFT JOIN latest_prices lp ON lp.flashlight_id = f.id
+    LEFT JOIN active_offers ao ON ao.flashlight_id = f.id
+    LEFT JOIN latest_amazon la ON la.flashlight_id = f.id
+    LEFT JOIN latest_scores ls ON ls.flashlight_id = f.id
+    ORDER BY f.id
+    ON CONFLICT (flashlight_id) DO UPDATE SET
+        image_url = EXCLUDED.image_url,
+        price_usd = EXCLUDED.price_usd,
+        price_usd_captured_at = EXCLUDED.price_usd_captured_at,
+        prices = EXCLUDED.prices,
+        offers = EXCLUDED.offers,
+        amazon_rating_count = EXCLUDED.amazon_rating_count,
+        amazon_average_rating = EXCLUDED.amazon_average_rating,
+        amazon_captured_at = EXCLUDED.amazon_captured_at,
+        run_id = EXCLUDED.run_id,
+        tactical_score = EXCLUDED.tactical_score,
+        edc_score = EXCLUDED.edc_score,
+        value_score = EXCLUDED.value_score,
+        throw_score = EXCLUDED.throw_score,
+        flood_score = EXCLUDED.flood_score,
+        refreshed_at = EXCLUDED.refreshed_at
+    WHERE (
+        rm.image_url, rm.price_usd, rm.price_usd_captured_at, rm.prices, rm.offers,
+        rm.amazon_rating_count, rm.amazon_average_rating, rm.amazon_captured_at,
+        rm.run_id, rm.tactical_score, rm.edc_score, rm.value_score, rm.throw_score, rm.flood_score
+    ) IS DISTINCT FROM (
+        EXCLUDED.image_url, EXCLUDED.price_usd, EXCLUDED.price_usd_captured_at, EXCLUDED.prices, EXCLUDED.offers,
+        EXCLUDED.amazon_rating_count, EXCLUDED.amazon_average_rating, EXCLUDED.amazon_captured_at,
+        EXCLUDED.run_id, EXCLUDED.tactical_score, EXCLUDED.edc_score, EXCLUDED.value_score, EXCLUDED.throw_score, EXCLUDED.flood_score
+    );
+
+    GET DIAGNOSTICS changed = ROW_COUNT;
+    RETURN changed;
+END;
+$$;
+
+-- The API's response cache keys on data_versions, so a refresh that commits
+-- after the source writes it reflects must bump the version too.
+DROP TRIGGER IF EXISTS trg_bump_data_version ON flashlight_read_model;
+CREATE TRIGGER trg_bump_data_version AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON flashlight_read_model
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_data_version();
+
+SELECT refresh_flashlight_read_model();
+
+COMMIT;
@@ -0,0 +1,31 @@
+BEGIN;
+
+-- schema_migrations records which numbered migrations have been applied. The
+-- API's /readyz compares the highest version with the schema the binary was
+-- built against, so a deploy that skipped a migration is never put into
+-- rotation. Every migration from here on inserts its own row before COMMIT.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Migrations are applied in order, so reaching this one means the earlier
+-- ones ran.
+INSERT INTO schema_migrations (version, name) VALUES
+    (1, '0001_initial_schema'),
+    (2, '0002_market_intelligence'),
+    (3, '0003_flashlight_detail_fields'),
+    (4, '0004_intelligence_runs'),
+    (5, '0005_search'),
+    (6, '0006_slug_history'),
+    (7, '0007_price_alert_subscriptions'),
+    (8, '0008_affiliate_clicks'),
+    (9, '0009_admin_audit'),
+    (10, '0010_api_keys'),
+    (11, '0011_data_version'),
+    (12, '0012_flashlight_read_model'),
+    (13, '0013_schema_migrations')
+ON CONFLICT (version) DO NOTHING;
+
+COMMIT;
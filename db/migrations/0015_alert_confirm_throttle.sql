@@ -0,0 +1,18 @@
+BEGIN;
+
+-- confirm_sent_at is when a pending alert's confirmation email last went out.
+-- Subscribe uses it to limit confirmation emails per alert and per address, so
+-- repeated POST /alerts calls cannot flood an inbox.
+ALTER TABLE price_drop_alerts
+    ADD COLUMN IF NOT EXISTS confirm_sent_at TIMESTAMPTZ;
+
+UPDATE price_drop_alerts SET confirm_sent_at = updated_at WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_price_drop_alerts_confirm_sent
+    ON price_drop_alerts (lower(email), confirm_sent_at)
+    WHERE status = 'pending';
+
+INSERT INTO schema_migrations (version, name) VALUES (15, '0015_alert_confirm_throttle')
+ON CONFLICT (version) DO NOTHING;
+
+COMMIT;
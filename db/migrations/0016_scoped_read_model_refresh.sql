@@ -0,0 +1,194 @@
+BEGIN;
+
+-- refresh_flashlight_read_model can now rebuild just the given lights. The
+-- Amazon sync refreshes each light inside the transaction that writes its
+-- snapshot, so a sync no longer pays for a full rebuild or publishes prices
+-- before their read model row. Without an argument it still rebuilds every
+-- light. The old zero-argument function is dropped first so calls without an
+-- argument are not ambiguous.
+DROP FUNCTION IF EXISTS refresh_flashlight_read_model();
+
+CREATE FUNCTION refresh_flashlight_read_model(target_ids BIGINT[] DEFAULT NULL) RETURNS INTEGER
+LANGUAGE plpgsql AS $$
+DECLARE
+    changed INTEGER;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('flashlight_read_model'));
+
+    WITH latest_run AS (
+        SELECT id
+        FROM scoring_runs
+        WHERE status = 'completed'
+        ORDER BY completed_at DESC NULLS LAST, id DESC
+        LIMIT 1
+    ),
+    latest_usd AS (
+        SELECT DISTINCT ON (p.flashlight_id)
+            p.flashlight_id,
+            p.price,
+            p.captured_at
+        FROM flashlight_price_snapshots p
+        WHERE p.currency_code = 'USD'
+          AND (target_ids IS NULL OR p.flashlight_id = ANY(target_ids))
+        ORDER BY p.flashlight_id, p.captured_at DESC
+    ),
+    latest_prices AS (
+        SELECT flashlight_id, jsonb_object_agg(currency_code, price) AS prices
+        FROM (
+            SELECT DISTINCT ON (p.flashlight_id, p.currency_code)
+                p.flashlight_id,
+                TRIM(p.currency_code) AS currency_code,
+                p.price
+            FROM flashlight_price_snapshots p
+            WHERE target_ids IS NULL OR p.flashlight_id = ANY(target_ids)
+            ORDER BY p.flashlight_id, p.currency_code, p.captured_at DESC
+        ) latest
+        GROUP BY flashlight_id
+    ),
+    latest_amazon AS (
+        SELECT DISTINCT ON (aps.flashlight_id)
+            aps.flashlight_id,
+            aps.rating_count,
+            aps.average_rating,
+            aps.captured_at
+        FROM amazon_product_snapshots aps
+        WHERE target_ids IS NULL OR aps.flashlight_id = ANY(target_ids)
+        ORDER BY aps.flashlight_id, aps.captured_at DESC
+    ),
+    active_offers AS (
+        SELECT
+            flashlight_id,
+            jsonb_object_agg(region_code, jsonb_build_object('url', affiliate_url, 'asin', asin, 'region', region_code)) AS offers
+        FROM (
+            SELECT DISTINCT ON (a.flashlight_id, a.region_code)
+                a.flashlight_id,
+                TRIM(a.region_code) AS region_code,
+                a.affiliate_url,
+                a.asin
+            FROM affiliate_links a
+            WHERE a.provider = 'amazon'
+              AND a.is_active = TRUE
+              AND (target_ids IS NULL OR a.flashlight_id = ANY(target_ids))
+            ORDER BY a.flashlight_id, a.region_code, a.is_primary DESC, a.updated_at DESC, a.id DESC
+        ) links
+        GROUP BY flashlight_id
+    ),
+    first_image AS (
+        SELECT DISTINCT ON (m.flashlight_id)
+            m.flashlight_id,
+            m.url
+        FROM flashlight_media m
+        WHERE m.media_type = 'image'
+          AND (target_ids IS NULL OR m.flashlight_id = ANY(target_ids))
+        ORDER BY m.flashlight_id, m.sort_order ASC, m.id ASC
+    ),
+    latest_scores AS (
+        SELECT
+            fs.flashlight_id,
+            fs.run_id,
+            MAX(CASE WHEN sp.slug = 'tactical' THEN fs.score END) AS tactical_score,
+            MAX(CASE WHEN sp.slug = 'edc' THEN fs.score END) AS edc_score,
+            MAX(CASE WHEN sp.slug = 'value' THEN fs.score END) AS value_score,
+            MAX(CASE WHEN sp.slug = 'throw' THEN fs.score END) AS throw_score,
+            MAX(CASE WHEN sp.slug = 'flood' THEN fs.score END) AS flood_score
+        FROM flashlight_scores fs
+        JOIN scoring_profiles sp ON sp.id = fs.profile_id
+        JOIN latest_run lr ON lr.id = fs.run_id
+        WHERE target_ids IS NULL OR fs.flashlight_id = ANY(target_ids)
+        GROUP BY fs.flashlight_id, fs.run_id
+    ),
+    search AS (
+        SELECT
+            f.id AS flashlight_id,
+            setweight(to_tsvector('simple', b.name::TEXT || ' ' || f.name || ' ' || COALESCE(f.model_code, '')), 'A') ||
+            setweight(to_tsvector('simple', COALESCE(s.led_model, '')), 'B') ||
+            setweight(to_tsvector('simple', COALESCE(f.description, '')), 'C') AS doc,
+            lower(b.name::TEXT || ' ' || f.name || ' ' || COALESCE(f.model_code, '')) AS title,
+            lower(COALESCE(f.model_code, '')) AS model_code,
+            lower(COALESCE(s.led_model, '')) AS led_model,
+            regexp_replace(lower(b.name::TEXT || f.name || COALESCE(f.model_code, '') || COALESCE(s.led_model, '')), '[^a-z0-9]', '', 'g') AS compact
+        FROM flashlights f
+        JOIN brands b ON b.id = f.brand_id
+        LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
+        WHERE target_ids IS NULL OR f.id = ANY(target_ids)
+    )
+    INSERT INTO flashlight_read_model AS rm (
+        flashlight_id, image_url, price_usd, price_usd_captured_at, prices, offers,
+        amazon_rating_count, amazon_average_rating, amazon_captured_at,
+        run_id, tactical_score, edc_score, value_score, throw_score, flood_score,
+        search_doc, search_title, search_model_code, search_led_model, search_compact, refreshed_at
+    )
+    SELECT
+        f.id,
+        fi.url,
+        lu.price,
+        lu.captured_at,
+        COALESCE(lp.prices, '{}'::JSONB),
+        COALESCE(ao.offers, '{}'::JSONB),
+        la.rating_count,
+        la.average_rating,
+        la.captured_at,
+        ls.run_id,
+        ls.tactical_score,
+        ls.edc_score,
+        ls.value_score,
+        ls.throw_score,
+        ls.flood_score,
+        se.doc,
+        se.title,
+        se.model_code,
+        se.led_model,
+        se.compact,
+        NOW()
+    FROM flashlights f
+    LEFT JOIN first_image fi ON fi.flashlight_id = f.id
+    LEFT JOIN latest_usd lu ON lu.flashlight_id = f.id
+    LEFT JOIN latest_prices lp ON lp.flashlight_id = f.id
+    LEFT JOIN active_offers ao ON ao.flashlight_id = f.id
+    LEFT JOIN latest_amazon la ON la.flashlight_id = f.id
+    LEFT JOIN latest_scores ls ON ls.flashlight_id = f.id
+    LEFT JOIN search se ON se.flashlight_id = f.id
+    WHERE target_ids IS NULL OR f.id = ANY(target_ids)
+    ORDER BY f.id
+    ON CONFLICT (flashlight_id) DO UPDATE SET
+        image_url = EXCLUDED.image_url,
+        price_usd = EXCLUDED.price_usd,
+        price_usd_captured_at = EXCLUDED.price_usd_captured_at,
+        prices = EXCLUDED.prices,
+        offers = EXCLUDED.offers,
+        amazon_rating_count = EXCLUDED.amazon_rating_count,
+        amazon_average_rating = EXCLUDED.amazon_average_rating,
+        amazon_captured_at = EXCLUDED.amazon_captured_at,
+        run_id = EXCLUDED.run_id,
+        tactical_score = EXCLUDED.tactical_score,
+        edc_score = EXCLUDED.edc_score,
+        value_score = EXCLUDED.value_score,
+        throw_score = EXCLUDED.throw_score,
+        flood_score = EXCLUDED.flood_score,
+        search_doc = EXCLUDED.search_doc,
+        search_title = EXCLUDED.search_title,
+        search_model_code = EXCLUDED.search_model_code,
+        search_led_model = EXCLUDED.search_led_model,
+        search_compact = EXCLUDED.search_compact,
+        refreshed_at = EXCLUDED.refreshed_at
+    WHERE (
+        rm.image_url, rm.price_usd, rm.price_usd_captured_at, rm.prices, rm.offers,
+        rm.amazon_rating_count, rm.amazon_average_rating, rm.amazon_captured_at,
+        rm.run_id, rm.tactical_score, rm.edc_score, rm.value_score, rm.throw_score, rm.flood_score,
+        rm.search_doc, rm.search_title, rm.search_model_code, rm.search_led_model, rm.search_compact
+    ) IS DISTINCT FROM (
+        EXCLUDED.image_url, EXCLUDED.price_usd, EXCLUDED.price_usd_captured_at, EXCLUDED.prices, EXCLUDED.offers,
+        EXCLUDED.amazon_rating_count, EXCLUDED.amazon_average_rating, EXCLUDED.amazon_captured_at,
+        EXCLUDED.run_id, EXCLUDED.tactical_score, EXCLUDED.edc_score, EXCLUDED.value_score, EXCLUDED.throw_score, EXCLUDED.flood_score,
+        EXCLUDED.search_doc, EXCLUDED.search_title, EXCLUDED.search_model_code, EXCLUDED.search_led_model, EXCLUDED.search_compact
+    );
+
+    GET DIAGNOSTICS changed = ROW_COUNT;
+    RETURN changed;
+END;
+$$;
+
+INSERT INTO schema_migrations (version, name) VALUES (16, '0016_scoped_read_model_refresh')
+ON CONFLICT (version) DO NOTHING;
+
+COMMIT;
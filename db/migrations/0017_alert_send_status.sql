@@ -0,0 +1,16 @@
+BEGIN;
+
+-- The notifier commits its claim on an alert and the price_drop_events row
+-- before it sends the email, then records the outcome here: sent_at when the
+-- email went out, send_error when it did not. Earlier events were only
+-- committed after a successful send.
+ALTER TABLE price_drop_events
+    ADD COLUMN IF NOT EXISTS sent_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS send_error TEXT;
+
+UPDATE price_drop_events SET sent_at = event_at WHERE alert_id IS NOT NULL AND sent_at IS NULL;
+
+INSERT INTO schema_migrations (version, name) VALUES (17, '0017_alert_send_status')
+ON CONFLICT (version) DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,251 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type NotifierConfig struct {
+	// Cooldown is the minimum time between two notifications for one alert.
+	Cooldown time.Duration
+	// BaseURL is the public API address used for the manage links.
+	BaseURL string
+	// SiteURL is the public web address used for the product link.
+	SiteURL   string
+	BatchSize int
+}
+
+type Notifier struct {
+	db     *sql.DB
+	sender Sender
+	cfg    NotifierConfig
+}
+
+func NewNotifier(db *sql.DB, sender Sender, cfg NotifierConfig) *Notifier {
+	if sender == nil {
+		sender = LogSender{}
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 72 * time.Hour
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 200
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	cfg.SiteURL = strings.TrimRight(cfg.SiteURL, "/")
+	return &Notifier{db: db, sender: sender, cfg: cfg}
+}
+
+type dueAlert struct {
+	ID           int64
+	FlashlightID int64
+	Email        string
+	TargetPrice  float64
+	Currency     string
+	ManageToken  string
+	Price        float64
+	OldPrice     float64
+	Source       string
+	Name         string
+}
+
+// Run compares the latest price snapshot of every actively watched light to
+// its alert targets and notifies each subscriber whose target is met and
+// whose cooldown has passed. It returns the number of emails sent. A failed
+// send is recorded on its event and the alert is retried on the next cycle.
+func (n *Notifier) Run(ctx context.Context) (int, error) {
+	due, err := n.dueAlerts(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var errs []error
+	for _, a := range due {
+		ok, err := n.notify(ctx, a)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("alert %d: %w", a.ID, err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+func (n *Notifier) dueAlerts(ctx context.Context) ([]dueAlert, error) {
+	rows, err := n.db.QueryContext(ctx, `
+WITH watched AS (
+	SELECT DISTINCT flashlight_id, currency_code
+	FROM price_drop_alerts
+	WHERE status = 'active'
+),
+ranked AS (
+	SELECT
+		p.flashlight_id,
+		p.currency_code,
+		p.price,
+		p.source,
+		ROW_NUMBER() OVER (PARTITION BY p.flashlight_id, p.currency_code ORDER BY p.captured_at DESC, p.id DESC) AS rn
+	FROM flashlight_price_snapshots p
+	JOIN watched w ON w.flashlight_id = p.flashlight_id AND w.currency_code = p.currency_code
+)
+SELECT
+	a.id,
+	a.flashlight_id,
+	a.email,
+	a.target_price::FLOAT8,
+	a.currency_code,
+	a.manage_token,
+	cur.price::FLOAT8,
+	COALESCE(prev.price, cur.price)::FLOAT8,
+	cur.source,
+	b.name || ' ' || f.name
+FROM price_drop_alerts a
+JOIN ranked cur ON cur.flashlight_id = a.flashlight_id AND cur.currency_code = a.currency_code AND cur.rn = 1
+LEFT JOIN ranked prev ON prev.flashlight_id = a.flashlight_id AND prev.currency_code = a.currency_code AND prev.rn = 2
+JOIN flashlights f ON f.id = a.flashlight_id
+JOIN brands b ON b.id = f.brand_id
+WHERE a.status = 'active'
+  AND a.manage_token IS NOT NULL
+  AND f.is_active = TRUE
+  AND cur.price <= a.target_price
+  AND (a.last_notified_at IS NULL OR a.last_notified_at < NOW() - make_interval(secs => $1))
+ORDER BY a.id
+LIMIT $2
+`, n.cfg.Cooldown.Seconds(), n.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]dueAlert, 0)
+	for rows.Next() {
+		var a dueAlert
+		if err := rows.Scan(
+			&a.ID,
+			&a.FlashlightID,
+			&a.Email,
+			&a.TargetPrice,
+			&a.Currency,
+			&a.ManageToken,
+			&a.Price,
+			&a.OldPrice,
+			&a.Source,
+			&a.Name,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// notify claims the alert and records the event in one short transaction,
+// then sends the email and records the outcome on the event. The claim
+// re-checks status and cooldown so concurrent workers never notify the same
+// alert twice, and because it commits before the send, a failed commit can
+// never be followed by a second email. A failed send releases the claim so
+// the alert is retried on the next cycle.
+func (n *Notifier) notify(ctx context.Context, a dueAlert) (bool, error) {
+	c, ok, err := n.claim(ctx, a)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if sendErr := n.sender.Send(ctx, n.message(a)); sendErr != nil {
+		_, err := n.db.ExecContext(ctx, `UPDATE price_drop_events SET send_error = $2 WHERE id = $1`, c.eventID, sendErr.Error())
+		if err == nil {
+			_, err = n.db.ExecContext(ctx, `
+				UPDATE price_drop_alerts
+				SET last_notified_at = $2, updated_at = NOW()
+				WHERE id = $1 AND last_notified_at = $3
+			`, a.ID, c.previous, c.at)
+		}
+		return false, errors.Join(sendErr, err)
+	}
+	_, err = n.db.ExecContext(ctx, `UPDATE price_drop_events SET sent_at = NOW() WHERE id = $1`, c.eventID)
+	return true, err
+}
+
+// claim is a committed claim on an alert.
+type claim struct {
+	eventID int64
+	// at is the last_notified_at the claim set, previous the one it replaced.
+	at       time.Time
+	previous sql.NullTime
+}
+
+// claim sets the alert's last_notified_at and inserts its price_drop_events
+// row. It reports false when another worker claimed the alert first or it is
+// no longer active.
+func (n *Notifier) claim(ctx context.Context, a dueAlert) (claim, bool, error) {
+	tx, err := n.db.BeginTx(ctx, nil)
+	if err != nil {
+		return claim{}, false, err
+	}
+	defer tx.Rollback()
+
+	var c claim
+	err = tx.QueryRowContext(ctx, `
+		SELECT last_notified_at
+		FROM price_drop_alerts
+		WHERE id = $1
+		  AND status = 'active'
+		  AND (last_notified_at IS NULL OR last_notified_at < NOW() - make_interval(secs => $2))
+		FOR UPDATE
+	`, a.ID, n.cfg.Cooldown.Seconds()).Scan(&c.previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return claim{}, false, nil
+	}
+	if err != nil {
+		return claim{}, false, err
+	}
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE price_drop_alerts
+		SET last_notified_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING last_notified_at
+	`, a.ID).Scan(&c.at); err != nil {
+		return claim{}, false, err
+	}
+
+	oldPrice := a.OldPrice
+	if oldPrice < a.Price {
+		oldPrice = a.Price
+	}
+	dropPct := 0.0
+	if oldPrice > 0 {
+		dropPct = (oldPrice - a.Price) / oldPrice * 100
+	}
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO price_drop_events (flashlight_id, alert_id, old_price, new_price, drop_percent, source, target_price, currency_code)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, a.FlashlightID, a.ID, oldPrice, a.Price, dropPct, a.Source, a.TargetPrice, a.Currency).Scan(&c.eventID); err != nil {
+		return claim{}, false, err
+	}
+	return c, true, tx.Commit()
+}
+
+func (n *Notifier) message(a dueAlert) Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Good news: the %s is now %.2f %s, at or below your target of %.2f %s.\n\n",
+		a.Name, a.Price, a.Currency, a.TargetPrice, a.Currency)
+	if n.cfg.SiteURL != "" {
+		fmt.Fprintf(&b, "See it here:\n%s/flashlights/%d\n\n", n.cfg.SiteURL, a.FlashlightID)
+	}
+	fmt.Fprintf(&b, "Prices change often, so check the current price before you buy.\n\n")
+	fmt.Fprintf(&b, "Pause this alert:\n%s/alerts/pause?token=%s\n", n.cfg.BaseURL, a.ManageToken)
+	fmt.Fprintf(&b, "Unsubscribe:\n%s/alerts/unsubscribe?token=%s\n", n.cfg.BaseURL, a.ManageToken)
+	return Message{
+		To:      a.Email,
+		Subject: fmt.Sprintf("Price drop: %s is now %.2f %s", a.Name, a.Price, a.Currency),
+		Body:    b.String(),
+	}
+}
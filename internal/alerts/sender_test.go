@@ -0,0 +1,215 @@
+package alerts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts one session and records the envelope and message data.
+type fakeSMTP struct {
+	ln   net.Listener
+	done chan struct{}
+	from string
+	rcpt []string
+	data string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeSMTP{ln: ln, done: make(chan struct{})}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeSMTP) port() int {
+	return f.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) serve() {
+	defer close(f.done)
+	conn, err := f.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		upper := strings.ToUpper(cmd)
+		switch {
+		case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			f.from = strings.Trim(cmd[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			f.rcpt = append(f.rcpt, strings.Trim(cmd[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case upper == "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			f.data = b.String()
+			reply("250 OK")
+		case upper == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPSenderDeliversToLocalServer(t *testing.T) {
+	srv := newFakeSMTP(t)
+	sender := NewSMTPSender(SMTPConfig{
+		Host:    "127.0.0.1",
+		Port:    srv.port(),
+		From:    "alerts@example.com",
+		Timeout: 5 * time.Second,
+	})
+
+	err := sender.Send(context.Background(), Message{
+		To:      "reader@example.com",
+		Subject: "Price drop: Acme X1 is now 49.99 USD",
+		Body:    "line one\nline two",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-srv.done
+
+	if srv.from != "alerts@example.com" {
+		t.Fatalf("MAIL FROM = %q", srv.from)
+	}
+	if len(srv.rcpt) != 1 || srv.rcpt[0] != "reader@example.com" {
+		t.Fatalf("RCPT TO = %v", srv.rcpt)
+	}
+	for _, want := range []string{
+		"To: reader@example.com\r\n",
+		"Subject: Price drop: Acme X1 is now 49.99 USD\r\n",
+		"\r\n\r\nline one\r\nline two\r\n",
+	} {
+		if !strings.Contains(srv.data, want) {
+			t.Fatalf("message data missing %q:\n%s", want, srv.data)
+		}
+	}
+}
+
+func TestSMTPSenderRejectsHeaderInjection(t *testing.T) {
+	sender := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: 1, From: "alerts@example.com"})
+	err := sender.Send(context.Background(), Message{
+		To:      "reader@example.com\r\nBcc: other@example.com",
+		Subject: "hi",
+		Body:    "x",
+	})
+	if err == nil {
+		t.Fatal("expected an error for a recipient with a line break")
+	}
+}
+
+func TestValidateSubscribe(t *testing.T) {
+	req := SubscribeRequest{FlashlightID: 7, Email: "  Reader@Example.com ", TargetPrice: 39.5, Currency: "usd"}
+	if err := ValidateSubscribe(&req); err != nil {
+		t.Fatalf("ValidateSubscribe: %v", err)
+	}
+	if req.Email != "Reader@Example.com" || req.Currency != "USD" {
+		t.Fatalf("normalized = %+v", req)
+	}
+
+	for field, bad := range map[string]SubscribeRequest{
+		"flashlight_id": {FlashlightID: 0, Email: "a@b.co", TargetPrice: 10},
+		"email":         {FlashlightID: 1, Email: "Reader <a@b.co>", TargetPrice: 10},
+		"target_price":  {FlashlightID: 1, Email: "a@b.co", TargetPrice: 0},
+		"currency":      {FlashlightID: 1, Email: "a@b.co", TargetPrice: 10, Currency: "dollars"},
+	} {
+		var fe *FieldError
+		if err := ValidateSubscribe(&bad); !errors.As(err, &fe) || fe.Field != field {
+			t.Fatalf("%+v: error %v, want a %s field error", bad, err, field)
+		}
+	}
+	if err := ValidateSubscribe(&SubscribeRequest{FlashlightID: 1, Email: "not-an-email", TargetPrice: 10}); err == nil {
+		t.Fatal("expected error for not-an-email")
+	}
+}
+
+func TestNotifierMessageCarriesManageLinks(t *testing.T) {
+	n := NewNotifier(nil, nil, NotifierConfig{BaseURL: "https://api.example.com/", SiteURL: "https://example.com"})
+	msg := n.message(dueAlert{
+		ID:           3,
+		FlashlightID: 12,
+		Email:        "reader@example.com",
+		TargetPrice:  50,
+		Currency:     "USD",
+		ManageToken:  "tok",
+		Price:        44.95,
+		Name:         "Acme X1",
+	})
+	if msg.To != "reader@example.com" {
+		t.Fatalf("To = %q", msg.To)
+	}
+	for _, want := range []string{
+		"https://example.com/flashlights/12",
+		"https://api.example.com/alerts/unsubscribe?token=tok",
+		"https://api.example.com/alerts/pause?token=tok",
+		"44.95 USD",
+	} {
+		if !strings.Contains(msg.Body, want) {
+			t.Fatalf("body missing %q:\n%s", want, msg.Body)
+		}
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		t.Fatalf("subject has a line break: %q", msg.Subject)
+	}
+}
+
+func TestLogSenderHidesTokensAndAddress(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(prev)
+
+	err := LogSender{}.Send(context.Background(), Message{
+		To:      "rider@example.com",
+		Subject: "Confirm your price alert",
+		Body:    "http://example.test/alerts/confirm?token=secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "secret") || strings.Contains(out, "rider@") || !strings.Contains(out, "r***@example.com") {
+		t.Fatalf("log line %q", out)
+	}
+	if got := maskAddress("not-an-address"); got != "***" {
+		t.Fatalf("maskAddress = %q", got)
+	}
+}
@@ -0,0 +1,280 @@
+package alerts
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("alert not found")
+	ErrInvalidState = errors.New("alert is not in a state that allows this change")
+)
+
+type SubscribeRequest struct {
+	FlashlightID int64
+	Email        string
+	TargetPrice  float64
+	Currency     string
+}
+
+type Alert struct {
+	ID           int64
+	FlashlightID int64
+	TargetPrice  float64
+	Currency     string
+	Status       string
+	// Name is the light's brand and name. Only Pending and Managed set it.
+	Name string
+}
+
+// Subscriptions manages the double opt-in lifecycle of price_drop_alerts:
+// pending -> active <-> paused, and any state -> unsubscribed.
+type Subscriptions struct {
+	db      *sql.DB
+	sender  Sender
+	baseURL string
+}
+
+// NewSubscriptions uses baseURL, the public address of the API, to build the
+// confirmation and manage links in emails.
+func NewSubscriptions(db *sql.DB, sender Sender, baseURL string) *Subscriptions {
+	if sender == nil {
+		sender = LogSender{}
+	}
+	return &Subscriptions{db: db, sender: sender, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// FieldError is returned by ValidateSubscribe. Field is named as in the API's
+// JSON body and Value is the rejected input.
+type FieldError struct {
+	Field   string
+	Value   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidateSubscribe normalizes req and reports the first invalid field as a
+// *FieldError.
+func ValidateSubscribe(req *SubscribeRequest) error {
+	if req.FlashlightID <= 0 {
+		return &FieldError{"flashlight_id", strconv.FormatInt(req.FlashlightID, 10), "must be a positive integer"}
+	}
+	addr, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil || addr.Name != "" || len(addr.Address) > 254 {
+		return &FieldError{"email", req.Email, "must be a plain email address"}
+	}
+	req.Email = addr.Address
+	if req.TargetPrice <= 0 || req.TargetPrice >= 1e10 {
+		return &FieldError{"target_price", strconv.FormatFloat(req.TargetPrice, 'f', -1, 64), "must be a positive amount"}
+	}
+	req.Currency = strings.ToUpper(strings.TrimSpace(req.Currency))
+	if req.Currency == "" {
+		req.Currency = "USD"
+	}
+	if len(req.Currency) != 3 {
+		return &FieldError{"currency", req.Currency, "must be a three-letter ISO code"}
+	}
+	return nil
+}
+
+// Confirmation emails are limited so POST /alerts cannot be used to flood an
+// inbox: a pending alert is re-sent its link at most every
+// confirmResendAfter, and one address gets at most maxConfirmsPerHour links
+// across all lights.
+const (
+	confirmResendAfter = 10 * time.Minute
+	maxConfirmsPerHour = 5
+)
+
+// Subscribe creates a pending alert and emails a confirmation link. Re-posting
+// for a pending alert refreshes its target and link, unless a link went out
+// recently. An address that is already subscribed, or has had too many links
+// this hour, is left unchanged. The caller cannot tell these cases apart, so
+// the endpoint does not reveal who is subscribed.
+func (s *Subscriptions) Subscribe(ctx context.Context, req SubscribeRequest) error {
+	if err := ValidateSubscribe(&req); err != nil {
+		return err
+	}
+
+	var name string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT b.name || ' ' || f.name
+		FROM flashlights f
+		JOIN brands b ON b.id = f.brand_id
+		WHERE f.id = $1 AND f.is_active = TRUE
+	`, req.FlashlightID).Scan(&name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	var recent int
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM price_drop_alerts
+		WHERE lower(email) = lower($1)
+		  AND status = 'pending'
+		  AND confirm_sent_at > NOW() - INTERVAL '1 hour'
+	`, req.Email).Scan(&recent)
+	if err != nil {
+		return err
+	}
+	if recent >= maxConfirmsPerHour {
+		return nil
+	}
+
+	confirmToken, err := newToken()
+	if err != nil {
+		return err
+	}
+	manageToken, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO price_drop_alerts (flashlight_id, email, target_price, currency_code, status, confirm_token_hash, manage_token, confirm_sent_at)
+		VALUES ($1, $2, $3, $4, 'pending', $5, $6, NOW())
+		ON CONFLICT (flashlight_id, lower(email), currency_code) WHERE status <> 'unsubscribed'
+		DO UPDATE SET
+			target_price = EXCLUDED.target_price,
+			confirm_token_hash = EXCLUDED.confirm_token_hash,
+			confirm_sent_at = NOW(),
+			updated_at = NOW()
+		WHERE price_drop_alerts.status = 'pending'
+		  AND (price_drop_alerts.confirm_sent_at IS NULL
+		       OR price_drop_alerts.confirm_sent_at < NOW() - make_interval(secs => $7))
+		RETURNING id
+	`, req.FlashlightID, req.Email, req.TargetPrice, req.Currency, hashToken(confirmToken), manageToken,
+		confirmResendAfter.Seconds()).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.sender.Send(ctx, Message{
+		To:      req.Email,
+		Subject: "Confirm your price alert for " + name,
+		Body: fmt.Sprintf(
+			"You asked to be told when the %s drops to %.2f %s or less.\n\n"+
+				"Confirm the alert:\n%s/alerts/confirm?token=%s\n\n"+
+				"If this wasn't you, ignore this email and no alerts will be sent.\n",
+			name, req.TargetPrice, req.Currency, s.baseURL, confirmToken),
+	})
+}
+
+func (s *Subscriptions) Confirm(ctx context.Context, token string) (Alert, error) {
+	return s.transition(ctx, `
+		UPDATE price_drop_alerts
+		SET status = 'active', confirmed_at = NOW(), confirm_token_hash = NULL, updated_at = NOW()
+		WHERE confirm_token_hash = $1 AND status = 'pending'
+		RETURNING id, flashlight_id, target_price::FLOAT8, currency_code, status
+	`, hashToken(token))
+}
+
+func (s *Subscriptions) Unsubscribe(ctx context.Context, token string) (Alert, error) {
+	return s.transition(ctx, `
+		UPDATE price_drop_alerts
+		SET status = 'unsubscribed', confirm_token_hash = NULL, updated_at = NOW()
+		WHERE manage_token = $1
+		RETURNING id, flashlight_id, target_price::FLOAT8, currency_code, status
+	`, token)
+}
+
+func (s *Subscriptions) Pause(ctx context.Context, token string) (Alert, error) {
+	return s.changeStatus(ctx, token, "active", "paused")
+}
+
+func (s *Subscriptions) Resume(ctx context.Context, token string) (Alert, error) {
+	return s.changeStatus(ctx, token, "paused", "active")
+}
+
+func (s *Subscriptions) changeStatus(ctx context.Context, token, from, to string) (Alert, error) {
+	a, err := s.transition(ctx, `
+		UPDATE price_drop_alerts
+		SET status = $3, updated_at = NOW()
+		WHERE manage_token = $1 AND status = $2
+		RETURNING id, flashlight_id, target_price::FLOAT8, currency_code, status
+	`, token, from, to)
+	if !errors.Is(err, ErrNotFound) {
+		return a, err
+	}
+	var status string
+	if err := s.db.QueryRowContext(ctx, `SELECT status FROM price_drop_alerts WHERE manage_token = $1`, token).Scan(&status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Alert{}, ErrNotFound
+		}
+		return Alert{}, err
+	}
+	return Alert{}, ErrInvalidState
+}
+
+// Pending returns the pending alert a confirmation token belongs to, without
+// confirming it.
+func (s *Subscriptions) Pending(ctx context.Context, token string) (Alert, error) {
+	return s.lookup(ctx, `a.confirm_token_hash = $1 AND a.status = 'pending'`, hashToken(token))
+}
+
+// Managed returns the alert a manage token belongs to, without changing it.
+func (s *Subscriptions) Managed(ctx context.Context, token string) (Alert, error) {
+	return s.lookup(ctx, `a.manage_token = $1`, token)
+}
+
+func (s *Subscriptions) lookup(ctx context.Context, where, token string) (Alert, error) {
+	if token == "" {
+		return Alert{}, ErrNotFound
+	}
+	var a Alert
+	err := s.db.QueryRowContext(ctx, `
+		SELECT a.id, a.flashlight_id, a.target_price::FLOAT8, a.currency_code, a.status, b.name || ' ' || f.name
+		FROM price_drop_alerts a
+		JOIN flashlights f ON f.id = a.flashlight_id
+		JOIN brands b ON b.id = f.brand_id
+		WHERE `+where, token).Scan(&a.ID, &a.FlashlightID, &a.TargetPrice, &a.Currency, &a.Status, &a.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Alert{}, ErrNotFound
+	}
+	return a, err
+}
+
+func (s *Subscriptions) transition(ctx context.Context, query string, args ...any) (Alert, error) {
+	if token, _ := args[0].(string); token == "" {
+		return Alert{}, ErrNotFound
+	}
+	var a Alert
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&a.ID, &a.FlashlightID, &a.TargetPrice, &a.Currency, &a.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Alert{}, ErrNotFound
+	}
+	return a, err
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
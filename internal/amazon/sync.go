@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"flashlight-ratings-go/internal/readmodel"
+	"flashlight-ratings-go/internal/telemetry"
 )
 
 type SyncConfig struct {
@@ -70,7 +73,13 @@ func NewSyncer(db *sql.DB, client Client, cfg SyncConfig) *Syncer {
 	return &Syncer{db: db, client: client, cfg: cfg}
 }
 
-func (s *Syncer) Run(ctx context.Context) error {
+func (s *Syncer) Run(ctx context.Context) (err error) {
+	start := time.Now()
+	defer func() {
+		telemetry.AmazonSyncRuns.WithLabelValues(telemetry.Result(err)).Inc()
+		telemetry.AmazonSyncDuration.Observe(telemetry.Since(start))
+	}()
+
 	targets, err := s.loadTargets(ctx)
 	if err != nil {
 		return err
@@ -85,6 +94,12 @@ func (s *Syncer) Run(ctx context.Context) error {
 		return errors.New("amazon PA-API client is not configured")
 	}
 
+	// Each light's snapshot and read model row commit together, so there is
+	// nothing left to publish once the batches are done.
+	return s.syncTargets(ctx, targets)
+}
+
+func (s *Syncer) syncTargets(ctx context.Context, targets []targetASIN) error {
 	indexByASIN := make(map[string]int64, len(targets))
 	asins := make([]string, 0, len(targets))
 	for _, t := range targets {
@@ -135,29 +150,54 @@ func (s *Syncer) Run(ctx context.Context) error {
 	return nil
 }
 
+type marketplace struct {
+	Host     string
+	Currency string
+}
+
+// marketplaces lists the Amazon stores affiliate links can point at, keyed
+// by affiliate_links.region_code.
+var marketplaces = map[string]marketplace{
+	"US": {Host: "www.amazon.com", Currency: "USD"},
+	"CA": {Host: "www.amazon.ca", Currency: "CAD"},
+	"UK": {Host: "www.amazon.co.uk", Currency: "GBP"},
+	"DE": {Host: "www.amazon.de", Currency: "EUR"},
+	"FR": {Host: "www.amazon.fr", Currency: "EUR"},
+	"IT": {Host: "www.amazon.it", Currency: "EUR"},
+	"ES": {Host: "www.amazon.es", Currency: "EUR"},
+	"JP": {Host: "www.amazon.co.jp", Currency: "JPY"},
+	"IN": {Host: "www.amazon.in", Currency: "INR"},
+}
+
 func defaultMarketplace(region string) string {
-	switch strings.ToUpper(region) {
-	case "US":
-		return "www.amazon.com"
-	case "CA":
-		return "www.amazon.ca"
-	case "UK":
-		return "www.amazon.co.uk"
-	case "DE":
-		return "www.amazon.de"
-	case "FR":
-		return "www.amazon.fr"
-	case "IT":
-		return "www.amazon.it"
-	case "ES":
-		return "www.amazon.es"
-	case "JP":
-		return "www.amazon.co.jp"
-	case "IN":
-		return "www.amazon.in"
-	default:
-		return "www.amazon.com"
+	if m, ok := marketplaces[strings.ToUpper(region)]; ok {
+		return m.Host
+	}
+	return marketplaces["US"].Host
+}
+
+// NormalizeRegion maps a region or ISO 3166 country code to the region code
+// used by affiliate_links. GB is stored as UK. ok is false for countries
+// without an Amazon marketplace.
+func NormalizeRegion(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "GB" {
+		code = "UK"
 	}
+	_, ok := marketplaces[code]
+	return code, ok
+}
+
+// AffiliateURL returns the canonical tagged product link for asin in region's
+// marketplace.
+func AffiliateURL(region, asin, partnerTag string) string {
+	return canonicalAmazonURL(defaultMarketplace(region), asin, partnerTag)
+}
+
+// RegionCurrency returns the currency prices are listed in for region.
+func RegionCurrency(region string) (string, bool) {
+	m, ok := marketplaces[strings.ToUpper(region)]
+	return m.Currency, ok
 }
 
 func (s *Syncer) allowedByQuality(p Product) bool {
@@ -338,10 +378,19 @@ WHERE id = $1
 		}
 	}
 
+	if _, err := readmodel.RefreshFlashlights(ctx, tx, flashlightID); err != nil {
+		return fmt.Errorf("refresh read model: %w", err)
+	}
 	return tx.Commit()
 }
 
 func (s *Syncer) markListingInactive(ctx context.Context, flashlightID int64, asin string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	const q = `
 UPDATE affiliate_links
 SET is_active = FALSE,
@@ -351,8 +400,13 @@ WHERE flashlight_id = $1
   AND region_code = $2
   AND asin = $3
 `
-	_, err := s.db.ExecContext(ctx, q, flashlightID, s.cfg.Region, asin)
-	return err
+	if _, err := tx.ExecContext(ctx, q, flashlightID, s.cfg.Region, asin); err != nil {
+		return err
+	}
+	if _, err := readmodel.RefreshFlashlights(ctx, tx, flashlightID); err != nil {
+		return fmt.Errorf("refresh read model: %w", err)
+	}
+	return tx.Commit()
 }
 
 func canonicalAmazonURL(marketplace, asin, partnerTag string) string {
@@ -0,0 +1,710 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashlight-ratings-go/internal/amazon"
+	"flashlight-ratings-go/internal/catalog"
+	"flashlight-ratings-go/internal/readmodel"
+)
+
+const (
+	maxAdminBody   = 1 << 20
+	maxAdminReason = 500
+	maxAdminImages = 20
+	maxAdminModes  = 20
+)
+
+var (
+	asinRe = regexp.MustCompile(`^[A-Z0-9]{10}$`)
+
+	errSlugTaken        = errors.New("slug belongs to another flashlight")
+	errMissingMaxLumens = errors.New("max_lumens is required for a light without specs")
+)
+
+// auditSnapshots select the rows an admin write can change, as JSON, keyed by
+// admin_audit_log.entity_type. $1 is the flashlight id; affiliate_link also
+// takes the region code as $2. A NULL result means nothing exists yet.
+var auditSnapshots = map[string]string{
+	"flashlight": `
+SELECT to_jsonb(f) || jsonb_build_object(
+	'specs', (SELECT to_jsonb(s) FROM flashlight_specs s WHERE s.flashlight_id = f.id),
+	'modes', (SELECT jsonb_agg(to_jsonb(m) ORDER BY m.mode_order, m.id) FROM flashlight_modes m WHERE m.flashlight_id = f.id),
+	'media', (SELECT jsonb_agg(to_jsonb(m) ORDER BY m.sort_order, m.id) FROM flashlight_media m WHERE m.flashlight_id = f.id),
+	'affiliate_links', (SELECT jsonb_agg(to_jsonb(a) ORDER BY a.region_code, a.id) FROM affiliate_links a WHERE a.flashlight_id = f.id),
+	'use_cases', (
+		SELECT jsonb_agg(uc.slug ORDER BY uc.slug)
+		FROM flashlight_use_cases fuc
+		JOIN use_cases uc ON uc.id = fuc.use_case_id
+		WHERE fuc.flashlight_id = f.id
+	)
+)
+FROM flashlights f
+WHERE f.id = $1`,
+	"specs":          `SELECT to_jsonb(s) FROM flashlight_specs s WHERE s.flashlight_id = $1`,
+	"modes":          `SELECT jsonb_agg(to_jsonb(m) ORDER BY m.mode_order, m.id) FROM flashlight_modes m WHERE m.flashlight_id = $1`,
+	"media":          `SELECT jsonb_agg(to_jsonb(m) ORDER BY m.sort_order, m.id) FROM flashlight_media m WHERE m.flashlight_id = $1`,
+	"affiliate_link": `SELECT jsonb_agg(to_jsonb(a) ORDER BY a.is_primary DESC, a.id) FROM affiliate_links a WHERE a.flashlight_id = $1 AND a.region_code = $2`,
+}
+
+type adminProductRequest struct {
+	Reason string `json:"reason"`
+	catalog.Product
+}
+
+type adminSpecsRequest struct {
+	Reason string `json:"reason"`
+	catalog.Specs
+}
+
+type adminModesRequest struct {
+	Reason string         `json:"reason"`
+	Modes  []catalog.Mode `json:"modes"`
+}
+
+type adminMediaRequest struct {
+	Reason string          `json:"reason"`
+	Images []catalog.Image `json:"images"`
+}
+
+type adminAffiliateRequest struct {
+	Reason string `json:"reason"`
+	ASIN   string `json:"asin"`
+}
+
+type adminReasonRequest struct {
+	Reason string `json:"reason"`
+}
+
+type adminWriteResponse struct {
+	FlashlightID int64    `json:"flashlight_id"`
+	AuditID      int64    `json:"audit_id"`
+	Action       string   `json:"action"`
+	Warnings     []string `json:"warnings,omitempty"`
+}
+
+type adminValidationError struct {
+	Error    string   `json:"error"`
+	Problems []string `json:"problems"`
+}
+
+// auditEntry is one admin_audit_log row. Action is filled in by adminWrite
+// when left empty: create if nothing existed before, update otherwise.
+type auditEntry struct {
+	Actor        string
+	Action       string
+	EntityType   string
+	FlashlightID int64
+	EntityKey    string
+	Reason       string
+}
+
+type auditRecord struct {
+	ID           int64           `json:"id"`
+	Actor        string          `json:"actor"`
+	Action       string          `json:"action"`
+	EntityType   string          `json:"entity_type"`
+	FlashlightID *int64          `json:"flashlight_id,omitempty"`
+	EntityKey    *string         `json:"entity_key,omitempty"`
+	Reason       string          `json:"reason"`
+	Before       json.RawMessage `json:"before"`
+	After        json.RawMessage `json:"after"`
+	CreatedAt    string          `json:"created_at"`
+}
+
+type auditResponse struct {
+	Items []auditRecord `json:"items"`
+}
+
+// handleAdmin authenticates the caller and routes /admin requests:
+//
+//	POST   /admin/flashlights
+//	PUT    /admin/flashlights/{id}
+//	DELETE /admin/flashlights/{id}
+//	PUT    /admin/flashlights/{id}/specs
+//	PUT    /admin/flashlights/{id}/modes
+//	PUT    /admin/flashlights/{id}/media
+//	PUT    /admin/flashlights/{id}/affiliate-links/{region}
+//	DELETE /admin/flashlights/{id}/affiliate-links/{region}
+//	GET    /admin/audit
+//	GET    /admin/clicks/stats
+func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	actor, ok := s.adminActor(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		writeJSON(w, http.StatusUnauthorized, apiError{Error: "unauthorized"})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/"), "/"), "/")
+	if parts[0] == "audit" && len(parts) == 1 {
+		s.handleAdminAudit(w, r)
+		return
+	}
+	if parts[0] == "clicks" && len(parts) == 2 && parts[1] == "stats" {
+		s.handleClickStats(w, r)
+		return
+	}
+	if parts[0] != "flashlights" {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+		return
+	}
+	if len(parts) == 1 {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+			return
+		}
+		s.adminSaveFlashlight(w, r, actor, 0)
+		return
+	}
+
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || id <= 0 {
+		writeBadRequest(w, r, invalidField("id", parts[1], "must be a positive integer"))
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodPut:
+		s.adminSaveFlashlight(w, r, actor, id)
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		s.adminDeactivateFlashlight(w, r, actor, id)
+	case len(parts) == 3 && parts[2] == "specs" && r.Method == http.MethodPut:
+		s.adminSaveSpecs(w, r, actor, id)
+	case len(parts) == 3 && parts[2] == "modes" && r.Method == http.MethodPut:
+		s.adminReplaceModes(w, r, actor, id)
+	case len(parts) == 3 && parts[2] == "media" && r.Method == http.MethodPut:
+		s.adminReplaceMedia(w, r, actor, id)
+	case len(parts) == 4 && parts[2] == "affiliate-links":
+		region, ok := amazon.NormalizeRegion(parts[3])
+		if !ok {
+			writeBadRequest(w, r, invalidField("region", parts[3], errNotAllowed.Error(), regionCodes...))
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			s.adminSaveAffiliateLink(w, r, actor, id, region)
+		case http.MethodDelete:
+			s.adminDeactivateAffiliateLink(w, r, actor, id, region)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		}
+	case len(parts) <= 3 && (len(parts) == 2 || oneOf(parts[2], "specs", "modes", "media")):
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+	default:
+		writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+	}
+}
+
+// adminActor returns the actor configured for the request's bearer token.
+// Every token is compared so the time taken does not depend on which, if
+// any, matched.
+func (s *Server) adminActor(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", false
+	}
+	actor := ""
+	for t, name := range s.adminTokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			actor = name
+		}
+	}
+	return actor, actor != ""
+}
+
+func (s *Server) adminSaveFlashlight(w http.ResponseWriter, r *http.Request, actor string, id int64) {
+	var req adminProductRequest
+	if !decodeAdminRequest(w, r, &req, &req.Reason) {
+		return
+	}
+	p := req.Product
+	p.Normalize()
+	errs, warnings := p.Check()
+	if !slugRe.MatchString(p.Slug) || !slugRe.MatchString(p.BrandSlug) {
+		errs = append(errs, "slug and brand_slug must be lowercase words joined by hyphens")
+	}
+	if p.ASIN != "" && !asinRe.MatchString(p.ASIN) {
+		errs = append(errs, "asin must be 10 letters or digits")
+	}
+	if p.Specs != (catalog.Specs{}) && p.Specs.MaxLumens <= 0 && id == 0 {
+		errs = append(errs, "specs.max_lumens is required")
+	}
+	errs = append(errs, checkAdminImages(p.Images)...)
+	errs = append(errs, checkAdminModes(p.Modes)...)
+	if len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, adminValidationError{Error: "invalid flashlight", Problems: errs})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	entry := auditEntry{Actor: actor, EntityType: "flashlight", FlashlightID: id, Reason: req.Reason}
+	resp, err := s.adminWrite(ctx, entry, func(tx *sql.Tx) (int64, error) {
+		if err := checkSlugsFree(ctx, tx, id, append([]string{p.Slug}, p.PreviousSlugs...)); err != nil {
+			return 0, err
+		}
+		if id > 0 && p.Specs != (catalog.Specs{}) && p.Specs.MaxLumens <= 0 {
+			if err := requireSpecsRow(ctx, tx, id); err != nil {
+				return 0, err
+			}
+		}
+		return s.catalog.SaveProduct(ctx, tx, id, p)
+	})
+	if err != nil {
+		writeAdminError(w, err, "failed to save flashlight")
+		return
+	}
+	resp.Warnings = warnings
+	status := http.StatusOK
+	if id == 0 {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, resp)
+}
+
+func (s *Server) adminDeactivateFlashlight(w http.ResponseWriter, r *http.Request, actor string, id int64) {
+	var req adminReasonRequest
+	if !decodeAdminRequest(w, r, &req, &req.Reason) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	entry := auditEntry{Actor: actor, Action: "deactivate", EntityType: "flashlight", FlashlightID: id, Reason: req.Reason}
+	resp, err := s.adminWrite(ctx, entry, func(tx *sql.Tx) (int64, error) {
+		res, err := tx.ExecContext(ctx, `UPDATE flashlights SET is_active = FALSE, updated_at = NOW() WHERE id = $1`, id)
+		if err != nil {
+			return 0, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return 0, sql.ErrNoRows
+		}
+		return id, nil
+	})
+	if err != nil {
+		writeAdminError(w, err, "failed to deactivate flashlight")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) adminSaveSpecs(w http.ResponseWriter, r *http.Request, actor string, id int64) {
+	var req adminSpecsRequest
+	if !decodeAdminRequest(w, r, &req, &req.Reason) {
+		return
+	}
+	if req.Specs == (catalog.Specs{}) {
+		writeJSON(w, http.StatusUnprocessableEntity, adminValidationError{Error: "invalid specs", Problems: []string{"no spec fields given"}})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	entry := auditEntry{Actor: actor, EntityType: "specs", FlashlightID: id, Reason: req.Reason}
+	resp, err := s.adminWrite(ctx, entry, func(tx *sql.Tx) (int64, error) {
+		if err := requireFlashlight(ctx, tx, id); err != nil {
+			return 0, err
+		}
+		if req.MaxLumens <= 0 {
+			if err := requireSpecsRow(ctx, tx, id); err != nil {
+				return 0, err
+			}
+		}
+		return id, s.catalog.SaveSpecs(ctx, tx, id, req.Specs)
+	})
+	if err != nil {
+		writeAdminError(w, err, "failed to save specs")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) adminReplaceModes(w http.ResponseWriter, r *http.Request, actor string, id int64) {
+	var req adminModesRequest
+	if !decodeAdminRequest(w, r, &req, &req.Reason) {
+		return
+	}
+	if errs := checkAdminModes(req.Modes); len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, adminValidationError{Error: "invalid modes", Problems: errs})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	entry := auditEntry{Actor: actor, EntityType: "modes", FlashlightID: id, Reason: req.Reason}
+	resp, err := s.adminWrite(ctx, entry, func(tx *sql.Tx) (int64, error) {
+		if err := requireFlashlight(ctx, tx, id); err != nil {
+			return 0, err
+		}
+		return id, s.catalog.ReplaceModes(ctx, tx, id, req.Modes)
+	})
+	if err != nil {
+		writeAdminError(w, err, "failed to save modes")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) adminReplaceMedia(w http.ResponseWriter, r *http.Request, actor string, id int64) {
+	var req adminMediaRequest
+	if !decodeAdminRequest(w, r, &req, &req.Reason) {
+		return
+	}
+	if errs := checkAdminImages(req.Images); len(errs) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, adminValidationError{Error: "invalid media", Problems: errs})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	entry := auditEntry{Actor: actor, EntityType: "media", FlashlightID: id, Reason: req.Reason}
+	resp, err := s.adminWrite(ctx, entry, func(tx *sql.Tx) (int64, error) {
+		p := catalog.Product{Images: req.Images}
+		err := tx.QueryRowContext(ctx, `
+SELECT b.name, f.name
+FROM flashlights f
+JOIN brands b ON b.id = f.brand_id
+WHERE f.id = $1
+`, id).Scan(&p.Brand, &p.Name)
+		if err != nil {
+			return 0, err
+		}
+		_, err = s.catalog.ReplaceMedia(ctx, tx, id, p)
+		return id, err
+	})
+	if err != nil {
+		writeAdminError(w, err, "failed to save media")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) adminSaveAffiliateLink(w http.ResponseWriter, r *http.Request, actor string, id int64, region string) {
+	var req adminAffiliateRequest
+	if !decodeAdminRequest(w, r, &req, &req.Reason) {
+		return
+	}
+	asin := strings.ToUpper(strings.TrimSpace(req.ASIN))
+	if !asinRe.MatchString(asin) {
+		writeJSON(w, http.StatusUnprocessableEntity, adminValidationError{Error: "invalid affiliate link", Problems: []string{"asin must be 10 letters or digits"}})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	entry := auditEntry{Actor: actor, EntityType: "affiliate_link", FlashlightID: id, EntityKey: region, Reason: req.Reason}
+	resp, err := s.adminWrite(ctx, entry, func(tx *sql.Tx) (int64, error) {
+		if err := requireFlashlight(ctx, tx, id); err != nil {
+			return 0, err
+		}
+		return id, s.catalog.SaveAffiliateLink(ctx, tx, id, region, asin)
+	})
+	if err != nil {
+		writeAdminError(w, err, "failed to save affiliate link")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) adminDeactivateAffiliateLink(w http.ResponseWriter, r *http.Request, actor string, id int64, region string) {
+	var req adminReasonRequest
+	if !decodeAdminRequest(w, r, &req, &req.Reason) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	entry := auditEntry{Actor: actor, Action: "deactivate", EntityType: "affiliate_link", FlashlightID: id, EntityKey: region, Reason: req.Reason}
+	resp, err := s.adminWrite(ctx, entry, func(tx *sql.Tx) (int64, error) {
+		res, err := tx.ExecContext(ctx, `
+UPDATE affiliate_links
+SET is_active = FALSE, updated_at = NOW()
+WHERE flashlight_id = $1
+  AND provider = 'amazon'
+  AND region_code = $2
+  AND is_active = TRUE
+`, id, region)
+		if err != nil {
+			return 0, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return 0, sql.ErrNoRows
+		}
+		return id, nil
+	})
+	if err != nil {
+		writeAdminError(w, err, "failed to deactivate affiliate link")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// adminWrite runs fn in a transaction and records the change in
+// admin_audit_log in the same transaction, with the entity's rows before and
+// after. fn returns the id of the flashlight it wrote.
+func (s *Server) adminWrite(ctx context.Context, e auditEntry, fn func(tx *sql.Tx) (int64, error)) (adminWriteResponse, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return adminWriteResponse{}, err
+	}
+	defer tx.Rollback()
+
+	var before []byte
+	if e.FlashlightID > 0 {
+		if before, err = auditSnapshot(ctx, tx, e.EntityType, e.FlashlightID, e.EntityKey); err != nil {
+			return adminWriteResponse{}, err
+		}
+	}
+
+	id, err := fn(tx)
+	if err != nil {
+		return adminWriteResponse{}, err
+	}
+	after, err := auditSnapshot(ctx, tx, e.EntityType, id, e.EntityKey)
+	if err != nil {
+		return adminWriteResponse{}, err
+	}
+	if e.Action == "" {
+		e.Action = "update"
+		if before == nil {
+			e.Action = "create"
+		}
+	}
+
+	resp := adminWriteResponse{FlashlightID: id, Action: e.Action}
+	err = tx.QueryRowContext(ctx, `
+INSERT INTO admin_audit_log (actor, action, entity_type, flashlight_id, entity_key, reason, before_data, after_data)
+VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7::JSONB, $8::JSONB)
+RETURNING id
+`, e.Actor, e.Action, e.EntityType, id, e.EntityKey, e.Reason, jsonOrNull(before), jsonOrNull(after)).Scan(&resp.AuditID)
+	if err != nil {
+		return adminWriteResponse{}, fmt.Errorf("write audit log: %w", err)
+	}
+	if _, err := readmodel.RefreshFlashlights(ctx, tx, id); err != nil {
+		return adminWriteResponse{}, fmt.Errorf("refresh read model: %w", err)
+	}
+	return resp, tx.Commit()
+}
+
+func auditSnapshot(ctx context.Context, tx *sql.Tx, entityType string, id int64, key string) ([]byte, error) {
+	args := []any{id}
+	if entityType == "affiliate_link" {
+		args = append(args, key)
+	}
+	var data []byte
+	err := tx.QueryRowContext(ctx, auditSnapshots[entityType], args...).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func jsonOrNull(b []byte) any {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
+
+// checkSlugsFree fails with errSlugTaken when any of slugs is the current
+// slug of a flashlight other than id.
+func checkSlugsFree(ctx context.Context, tx *sql.Tx, id int64, slugs []string) error {
+	var taken string
+	err := tx.QueryRowContext(ctx, `
+SELECT slug FROM flashlights WHERE slug = ANY($1::TEXT[]) AND id <> $2 LIMIT 1
+`, slugs, id).Scan(&taken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", errSlugTaken, taken)
+}
+
+func requireFlashlight(ctx context.Context, tx *sql.Tx, id int64) error {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM flashlights WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// requireSpecsRow reports a validation problem when a light has no specs yet,
+// since flashlight_specs.max_lumens cannot be left empty.
+func requireSpecsRow(ctx context.Context, tx *sql.Tx, id int64) error {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM flashlight_specs WHERE flashlight_id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errMissingMaxLumens
+	}
+	return nil
+}
+
+func checkAdminModes(modes []catalog.Mode) []string {
+	var errs []string
+	if len(modes) > maxAdminModes {
+		errs = append(errs, fmt.Sprintf("at most %d modes", maxAdminModes))
+	}
+	seen := make(map[string]bool, len(modes))
+	for i, m := range modes {
+		name := strings.ToLower(strings.TrimSpace(m.Name))
+		switch {
+		case name == "":
+			errs = append(errs, fmt.Sprintf("modes[%d]: missing name", i))
+		case seen[name]:
+			errs = append(errs, fmt.Sprintf("modes[%d]: duplicate name %q", i, m.Name))
+		}
+		seen[name] = true
+		if m.OutputLumens < 0 || m.RuntimeMin < 0 || m.Candela < 0 || m.BeamDistanceM < 0 || m.Order < 0 {
+			errs = append(errs, fmt.Sprintf("modes[%d]: values must not be negative", i))
+		}
+	}
+	return errs
+}
+
+func checkAdminImages(images []catalog.Image) []string {
+	var errs []string
+	if len(images) > maxAdminImages {
+		errs = append(errs, fmt.Sprintf("at most %d images", maxAdminImages))
+	}
+	for i, img := range images {
+		u, err := url.Parse(strings.TrimSpace(img.URL))
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs = append(errs, fmt.Sprintf("images[%d]: url must be an absolute http(s) URL", i))
+		}
+	}
+	return errs
+}
+
+// decodeAdminRequest decodes a JSON body into dst, rejecting unknown fields so
+// typos do not silently drop edits, and requires a reason.
+func decodeAdminRequest(w http.ResponseWriter, r *http.Request, dst any, reason *string) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		writeBadRequest(w, r, fmt.Errorf("invalid json body: %w", err))
+		return false
+	}
+	*reason = strings.TrimSpace(*reason)
+	if *reason == "" || len(*reason) > maxAdminReason {
+		writeBadRequest(w, r, invalidField("reason", "", fmt.Sprintf("is required (at most %d characters)", maxAdminReason)))
+		return false
+	}
+	return true
+}
+
+func writeAdminError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+	case errors.Is(err, errSlugTaken):
+		writeJSON(w, http.StatusConflict, apiError{Error: err.Error()})
+	case errors.Is(err, errMissingMaxLumens):
+		writeJSON(w, http.StatusUnprocessableEntity, adminValidationError{Error: "invalid specs", Problems: []string{err.Error()}})
+	default:
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: msg})
+	}
+}
+
+var adminAuditParams = []param{
+	{Name: "flashlight_id", Type: typeInteger, Minimum: &minID},
+	intParam("limit", 50, 1, 200),
+}
+
+// handleAdminAudit lists recent audit entries, newest first, optionally for
+// one flashlight.
+func (s *Server) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	q, ok := readQuery(w, r, adminAuditParams)
+	if !ok {
+		return
+	}
+	var flashlightID int64
+	if id := q.int64("flashlight_id"); id != nil {
+		flashlightID = *id
+	}
+	limit := q.int("limit")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, actor, action, entity_type, flashlight_id, entity_key, reason, before_data, after_data, created_at
+FROM admin_audit_log
+WHERE ($1::BIGINT = 0 OR flashlight_id = $1)
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+`, flashlightID, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch audit log"})
+		return
+	}
+	defer rows.Close()
+
+	resp := auditResponse{Items: []auditRecord{}}
+	for rows.Next() {
+		var (
+			rec           auditRecord
+			fID           sql.NullInt64
+			key           sql.NullString
+			before, after []byte
+			createdAt     time.Time
+		)
+		if err := rows.Scan(&rec.ID, &rec.Actor, &rec.Action, &rec.EntityType, &fID, &key, &rec.Reason, &before, &after, &createdAt); err != nil {
+			writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch audit log"})
+			return
+		}
+		rec.FlashlightID = nullInt(fID)
+		rec.EntityKey = nullString(key)
+		rec.Before = rawOrNull(before)
+		rec.After = rawOrNull(after)
+		rec.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		resp.Items = append(resp.Items, rec)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch audit log"})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func rawOrNull(b []byte) json.RawMessage {
+	if b == nil {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(b)
+}
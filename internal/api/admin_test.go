@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"flashlight-ratings-go/internal/catalog"
+)
+
+const testAdminToken = "0123456789abcdef0123456789abcdef"
+
+func adminRequest(method, path, body, token string) *http.Request {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	s := &Server{adminTokens: map[string]string{testAdminToken: "alice"}}
+
+	for _, path := range []string{"/admin/audit", "/admin/clicks/stats"} {
+		for _, token := range []string{"", "wrong", testAdminToken + "x"} {
+			w := httptest.NewRecorder()
+			s.handleAdmin(w, adminRequest(http.MethodGet, path, "", token))
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("%s with token %q: status = %d, want 401", path, token, w.Code)
+			}
+		}
+	}
+
+	r := adminRequest(http.MethodGet, "/admin/audit", "", testAdminToken)
+	if actor, ok := s.adminActor(r); !ok || actor != "alice" {
+		t.Fatalf("adminActor = %q %v, want alice", actor, ok)
+	}
+
+	empty := &Server{}
+	if _, ok := empty.adminActor(r); ok {
+		t.Fatal("no configured tokens must reject every request")
+	}
+}
+
+func TestAdminRejectsBeforeWriting(t *testing.T) {
+	// No database: every case must be answered before a transaction starts.
+	s := &Server{adminTokens: map[string]string{testAdminToken: "alice"}}
+	cases := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/admin/flashlights", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/admin/clicks/stats?group_by=referrer", "", http.StatusBadRequest},
+		{http.MethodPost, "/admin/clicks/stats", "", http.StatusMethodNotAllowed},
+		{http.MethodPut, "/admin/flashlights/abc", "{}", http.StatusBadRequest},
+		{http.MethodPut, "/admin/flashlights/1/unknown", "{}", http.StatusNotFound},
+		{http.MethodPut, "/admin/flashlights/1/affiliate-links/zz", "{}", http.StatusBadRequest},
+		{http.MethodPut, "/admin/flashlights/1/specs", `{"max_lumens": 1000}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/flashlights/1/specs", `{"reason": "x", "max_lumen": 1000}`, http.StatusBadRequest},
+		{http.MethodPost, "/admin/flashlights", `{"reason": "new light", "name": "E2T"}`, http.StatusUnprocessableEntity},
+		{http.MethodPut, "/admin/flashlights/1/modes", `{"reason": "x", "modes": [{"name": "High"}, {"name": "high"}]}`, http.StatusUnprocessableEntity},
+		{http.MethodPut, "/admin/flashlights/1/media", `{"reason": "x", "images": [{"url": "javascript:alert(1)"}]}`, http.StatusUnprocessableEntity},
+		{http.MethodPut, "/admin/flashlights/1/affiliate-links/de", `{"reason": "x", "asin": "short"}`, http.StatusUnprocessableEntity},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		s.handleAdmin(w, adminRequest(tc.method, tc.path, tc.body, testAdminToken))
+		if w.Code != tc.want {
+			t.Errorf("%s %s: status = %d, want %d (%s)", tc.method, tc.path, w.Code, tc.want, w.Body.String())
+		}
+	}
+}
+
+func TestCheckAdminModes(t *testing.T) {
+	ok := []catalog.Mode{{Name: "Turbo", OutputLumens: 1600}, {Name: "Low", OutputLumens: 30}}
+	if errs := checkAdminModes(ok); len(errs) != 0 {
+		t.Fatalf("unexpected problems: %v", errs)
+	}
+	bad := []catalog.Mode{{Name: " "}, {Name: "Low", RuntimeMin: -1}}
+	if errs := checkAdminModes(bad); len(errs) != 2 {
+		t.Fatalf("problems = %v, want 2", errs)
+	}
+}
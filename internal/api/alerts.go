@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"flashlight-ratings-go/internal/alerts"
+)
+
+type alertRequest struct {
+	FlashlightID int64   `json:"flashlight_id"`
+	Email        string  `json:"email"`
+	TargetPrice  float64 `json:"target_price"`
+	Currency     string  `json:"currency"`
+}
+
+type alertPendingResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type alertResponse struct {
+	ID           int64   `json:"id"`
+	FlashlightID int64   `json:"flashlight_id"`
+	TargetPrice  float64 `json:"target_price"`
+	Currency     string  `json:"currency"`
+	Status       string  `json:"status"`
+}
+
+// handleAlerts subscribes an address to a price drop alert. The alert stays
+// pending until the emailed confirmation link is used, and the response is
+// the same whether or not the address was already subscribed.
+func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	if _, ok := readQuery(w, r, noParams); !ok {
+		return
+	}
+
+	var req alertRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeBadRequest(w, r, fmt.Errorf("invalid json body: %w", err))
+		return
+	}
+	sub := alerts.SubscribeRequest{
+		FlashlightID: req.FlashlightID,
+		Email:        req.Email,
+		TargetPrice:  req.TargetPrice,
+		Currency:     req.Currency,
+	}
+	if err := alerts.ValidateSubscribe(&sub); err != nil {
+		var fe *alerts.FieldError
+		if errors.As(err, &fe) {
+			err = invalidField(fe.Field, fe.Value, fe.Message)
+		}
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := s.alerts.Subscribe(ctx, sub); err != nil {
+		if errors.Is(err, alerts.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, apiError{Error: "flashlight not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to create alert"})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, alertPendingResponse{
+		Status:  "pending_confirmation",
+		Message: "check your inbox for a confirmation link",
+	})
+}
+
+var alertActionParams = []param{{Name: "token", Type: typeString, MaxLen: 128, Required: true}}
+
+// alertAction is one of the changes the emailed links make.
+type alertAction struct {
+	title  string // heading of the page asking to go ahead
+	button string
+	done   string // heading once the change is made
+	// from is the status the alert must be in, any when empty.
+	from string
+	// confirm marks a confirmation token rather than a manage token.
+	confirm bool
+}
+
+var alertActions = map[string]alertAction{
+	"confirm":     {title: "Confirm your price alert", button: "Confirm alert", done: "Your price alert is active", confirm: true},
+	"pause":       {title: "Pause your price alert", button: "Pause alert", done: "Your price alert is paused", from: "active"},
+	"resume":      {title: "Resume your price alert", button: "Resume alert", done: "Your price alert is active again", from: "paused"},
+	"unsubscribe": {title: "Unsubscribe from your price alert", button: "Unsubscribe", done: "You are unsubscribed"},
+}
+
+// handleAlertAction serves the token links sent by email:
+// /alerts/confirm, /alerts/unsubscribe, /alerts/pause and /alerts/resume.
+// Mail scanners and link prefetchers open links nobody clicked, so GET only
+// shows a page whose button POSTs back to the same link, and only POST
+// changes the alert. POST answers with a page too when a browser sent it.
+func (s *Server) handleAlertAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/alerts/")
+	act, ok := alertActions[name]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+		return
+	}
+
+	q, ok := readQuery(w, r, alertActionParams)
+	if !ok {
+		return
+	}
+	token := q.str("token")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method == http.MethodGet {
+		find := s.alerts.Managed
+		if act.confirm {
+			find = s.alerts.Pending
+		}
+		a, err := find(ctx, token)
+		if err == nil && act.from != "" && a.Status != act.from {
+			err = alerts.ErrInvalidState
+		}
+		if err != nil {
+			writeAlertError(w, err)
+			return
+		}
+		writeAlertPage(w, alertPage{
+			Title:  act.title,
+			Text:   fmt.Sprintf("Alert for the %s at %.2f %s or less.", a.Name, a.TargetPrice, a.Currency),
+			Button: act.button,
+			Action: versionPrefix(r.Context()) + r.URL.Path + "?token=" + url.QueryEscape(token),
+		})
+		return
+	}
+
+	var apply func(context.Context, string) (alerts.Alert, error)
+	switch name {
+	case "confirm":
+		apply = s.alerts.Confirm
+	case "unsubscribe":
+		apply = s.alerts.Unsubscribe
+	case "pause":
+		apply = s.alerts.Pause
+	case "resume":
+		apply = s.alerts.Resume
+	}
+	a, err := apply(ctx, token)
+	if err != nil {
+		writeAlertError(w, err)
+		return
+	}
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		writeAlertPage(w, alertPage{Title: act.done, Text: "You can close this page."})
+		return
+	}
+	writeJSON(w, http.StatusOK, alertResponse{
+		ID:           a.ID,
+		FlashlightID: a.FlashlightID,
+		TargetPrice:  a.TargetPrice,
+		Currency:     a.Currency,
+		Status:       a.Status,
+	})
+}
+
+func writeAlertError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, alerts.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, apiError{Error: "alert not found or link expired"})
+	case errors.Is(err, alerts.ErrInvalidState):
+		writeJSON(w, http.StatusConflict, apiError{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to update alert"})
+	}
+}
+
+// alertPage is a page for people following an emailed link. Without Action
+// it has no button.
+type alertPage struct {
+	Title, Text, Button, Action string
+}
+
+var alertPageTmpl = template.Must(template.New("alert").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Text}}</p>
+{{if .Action}}<form method="post" action="{{.Action}}"><button type="submit">{{.Button}}</button></form>
+{{end}}</body>
+</html>
+`))
+
+// writeAlertPage renders p. The link carries the token, so the page is
+// neither cached nor sent on as a referrer.
+func writeAlertPage(w http.ResponseWriter, p alertPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+	_ = alertPageTmpl.Execute(w, p)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAlertsRejectsBadBodies(t *testing.T) {
+	s := &Server{}
+	for body, field := range map[string]string{
+		`{"flashlight_id":1,"email":"rider@example.com","target_price":30,"currrency":"USD"}`: "",
+		`{"flashlight_id":1,"email":"Rider <rider@example.com>","target_price":30}`:           "email",
+		`{"flashlight_id":0,"email":"rider@example.com","target_price":30}`:                   "flashlight_id",
+		`{"flashlight_id":1,"email":"rider@example.com","target_price":30,"currency":"usdd"}`: "currency",
+	} {
+		w := httptest.NewRecorder()
+		s.handleAlerts(w, httptest.NewRequest(http.MethodPost, "/alerts", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" {
+			t.Errorf("%s: status %d, content type %q", body, w.Code, w.Header().Get("Content-Type"))
+			continue
+		}
+		var p problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s: %v", body, err)
+		}
+		if field == "" {
+			if !strings.Contains(p.Detail, `unknown field "currrency"`) {
+				t.Errorf("%s: detail %q", body, p.Detail)
+			}
+			continue
+		}
+		if len(p.Errors) != 1 || p.Errors[0].Field != field {
+			t.Errorf("%s: errors %+v, want one for %s", body, p.Errors, field)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type brandSummary struct {
+	ID          int64              `json:"id"`
+	Name        string             `json:"name"`
+	Slug        string             `json:"slug"`
+	CountryCode *string            `json:"country_code,omitempty"`
+	WebsiteURL  *string            `json:"website_url,omitempty"`
+	ModelCount  int                `json:"model_count"`
+	MinPriceUSD *float64           `json:"min_price_usd,omitempty"`
+	MaxPriceUSD *float64           `json:"max_price_usd,omitempty"`
+	AvgScores   map[string]float64 `json:"avg_scores"`
+	BestModels  []brandBestModel   `json:"best_models"`
+}
+
+type brandBestModel struct {
+	Profile      string  `json:"profile"`
+	FlashlightID int64   `json:"flashlight_id"`
+	Name         string  `json:"name"`
+	Slug         string  `json:"slug"`
+	Score        float64 `json:"score"`
+}
+
+type brandsResponse struct {
+	Items []brandSummary `json:"items"`
+}
+
+type brandDetail struct {
+	brandSummary
+	// Models holds at most maxBrandModels lights in /flashlights order.
+	// ModelsTruncated is set when the brand has more; model_count is the
+	// full count.
+	Models          []flashlightItem `json:"models"`
+	ModelsTruncated bool             `json:"models_truncated"`
+}
+
+const maxBrandModels = 100
+
+func (s *Server) handleBrands(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	if _, ok := readQuery(w, r, noParams); !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := s.brandSummaries(ctx, "")
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch brands"})
+		return
+	}
+	writeJSON(w, http.StatusOK, brandsResponse{Items: items})
+}
+
+func (s *Server) handleBrandBySlug(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	slug := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/brands/")))
+	if !slugRe.MatchString(slug) {
+		writeBadRequest(w, r, invalidField("slug", slug, errBadSlug.Error()))
+		return
+	}
+	if _, ok := readQuery(w, r, regionParams); !ok {
+		return
+	}
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := s.brandSummaries(ctx, slug)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch brand"})
+		return
+	}
+	if len(items) == 0 {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "brand not found"})
+		return
+	}
+
+	models, err := s.listFlashlights(ctx, flashlightFilters{
+		Brands:   []string{slug},
+		Page:     1,
+		PageSize: maxBrandModels,
+		Region:   rg,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch brand models"})
+		return
+	}
+	writeJSON(w, http.StatusOK, brandDetail{
+		brandSummary:    items[0],
+		Models:          models.items,
+		ModelsTruncated: models.next != "",
+	})
+}
+
+// brandSummaries returns every brand with at least one active model, or just
+// the brand with the given slug when slug is set.
+func (s *Server) brandSummaries(ctx context.Context, slug string) ([]brandSummary, error) {
+	query := `
+SELECT
+	b.id,
+	b.name,
+	b.slug,
+	b.country_code,
+	b.website_url,
+	COUNT(f.id),
+	MIN(rm.price_usd),
+	MAX(rm.price_usd)
+FROM brands b
+LEFT JOIN flashlights f ON f.brand_id = b.id AND f.is_active = TRUE
+LEFT JOIN flashlight_read_model rm ON rm.flashlight_id = f.id
+WHERE ($1 = '' OR b.slug = $1)
+GROUP BY b.id
+HAVING $1 <> '' OR COUNT(f.id) > 0
+ORDER BY b.name ASC
+`
+	rows, err := s.db.QueryContext(ctx, query, slug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]brandSummary, 0)
+	index := make(map[int64]int)
+	for rows.Next() {
+		var (
+			item               brandSummary
+			country, website   sql.NullString
+			minPrice, maxPrice sql.NullFloat64
+		)
+		if err := rows.Scan(
+			&item.ID,
+			&item.Name,
+			&item.Slug,
+			&country,
+			&website,
+			&item.ModelCount,
+			&minPrice,
+			&maxPrice,
+		); err != nil {
+			return nil, err
+		}
+		item.CountryCode = nullString(country)
+		item.WebsiteURL = nullString(website)
+		item.MinPriceUSD = nullFloat(minPrice)
+		item.MaxPriceUSD = nullFloat(maxPrice)
+		item.AvgScores = map[string]float64{}
+		item.BestModels = []brandBestModel{}
+		index[item.ID] = len(out)
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	if err := s.attachBrandScores(ctx, slug, out, index); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// attachBrandScores fills the per-profile average score and best model of
+// each brand from the scoring run the read model was last built from.
+func (s *Server) attachBrandScores(ctx context.Context, slug string, out []brandSummary, index map[int64]int) error {
+	query := `
+WITH brand_scores AS (
+	SELECT
+		f.brand_id,
+		sp.slug AS profile,
+		f.id AS flashlight_id,
+		f.name,
+		f.slug,
+		fs.score
+	FROM flashlight_scores fs
+	JOIN flashlight_read_model rm ON rm.flashlight_id = fs.flashlight_id AND rm.run_id = fs.run_id
+	JOIN scoring_profiles sp ON sp.id = fs.profile_id
+	JOIN flashlights f ON f.id = fs.flashlight_id AND f.is_active = TRUE
+	JOIN brands b ON b.id = f.brand_id
+	WHERE ($1 = '' OR b.slug = $1)
+),
+averages AS (
+	SELECT brand_id, profile, AVG(score) AS avg_score
+	FROM brand_scores
+	GROUP BY brand_id, profile
+),
+best AS (
+	SELECT DISTINCT ON (brand_id, profile)
+		brand_id, profile, flashlight_id, name, slug, score
+	FROM brand_scores
+	ORDER BY brand_id, profile, score DESC, flashlight_id ASC
+)
+SELECT
+	a.brand_id,
+	a.profile,
+	a.avg_score,
+	best.flashlight_id,
+	best.name,
+	best.slug,
+	best.score
+FROM averages a
+JOIN best ON best.brand_id = a.brand_id AND best.profile = a.profile
+ORDER BY a.brand_id, a.profile
+`
+	rows, err := s.db.QueryContext(ctx, query, slug)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			brandID int64
+			avg     float64
+			best    brandBestModel
+		)
+		if err := rows.Scan(&brandID, &best.Profile, &avg, &best.FlashlightID, &best.Name, &best.Slug, &best.Score); err != nil {
+			return err
+		}
+		i, ok := index[brandID]
+		if !ok {
+			continue
+		}
+		out[i].AvgScores[best.Profile] = round1(avg)
+		out[i].BestModels = append(out[i].BestModels, best)
+	}
+	return rows.Err()
+}
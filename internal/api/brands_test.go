@@ -0,0 +1,83 @@
+package api
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// brandFixtures answer the brand detail queries for the brand acme, whose
+// listing query returns models rows out of count active lights.
+func brandFixtures(models, count int) []fixture {
+	rows := make([][]driver.Value, models)
+	for i := range rows {
+		rows[i] = row(fixtureListing, 81.5, int64(7))
+		rows[i][0] = int64(i + 1)
+	}
+	return []fixture{
+		{match: "HAVING $1 <> '' OR COUNT(f.id) > 0", arg: "acme", rows: [][]driver.Value{
+			{int64(1), "Acme", "acme", "US", "https://acme.example", int64(count), 29.95, 89.95},
+		}},
+		{match: "HAVING $1 <> '' OR COUNT(f.id) > 0"},
+		{match: "WITH brand_scores AS", rows: [][]driver.Value{
+			{int64(1), "edc", 72.5, int64(1), "One", "acme-one", 77.25},
+			{int64(1), "tactical", 80.5, int64(2), "Two", "acme-two", 88.0},
+		}},
+		{match: "s.waterproof_rating, rm.price_usd, rm.tactical_score", rows: rows},
+		{match: "SELECT COUNT(*) FROM flashlights f JOIN brands b", rows: [][]driver.Value{{int64(count)}}},
+	}
+}
+
+func getBrand(t *testing.T, fixtures []fixture, target string) (*httptest.ResponseRecorder, brandDetail) {
+	t.Helper()
+	s := &Server{db: openFakeDB(t, fixtures...)}
+	w := httptest.NewRecorder()
+	s.handleBrandBySlug(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var resp brandDetail
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w, resp
+}
+
+func TestBrandDetail(t *testing.T) {
+	w, resp := getBrand(t, brandFixtures(3, 3), "/brands/Acme")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Slug != "acme" || resp.ModelCount != 3 || len(resp.Models) != 3 || resp.ModelsTruncated {
+		t.Fatalf("slug %q, model_count %d, %d models, truncated %v", resp.Slug, resp.ModelCount, len(resp.Models), resp.ModelsTruncated)
+	}
+	if resp.AvgScores["edc"] != 72.5 || resp.AvgScores["tactical"] != 80.5 {
+		t.Fatalf("avg_scores = %v", resp.AvgScores)
+	}
+	if len(resp.BestModels) != 2 || resp.BestModels[1].Profile != "tactical" || resp.BestModels[1].Slug != "acme-two" {
+		t.Fatalf("best_models = %+v", resp.BestModels)
+	}
+
+	if w, _ := getBrand(t, brandFixtures(0, 0), "/brands/unknown"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown brand: status %d", w.Code)
+	}
+	if w, _ := getBrand(t, nil, "/brands/no_such"); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid slug: status %d", w.Code)
+	}
+}
+
+func TestBrandDetailReportsTruncatedModels(t *testing.T) {
+	w, resp := getBrand(t, brandFixtures(maxBrandModels, maxBrandModels+20), "/brands/acme")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	if len(resp.Models) != maxBrandModels || !resp.ModelsTruncated || resp.ModelCount != maxBrandModels+20 {
+		t.Fatalf("%d models, truncated %v, model_count %d", len(resp.Models), resp.ModelsTruncated, resp.ModelCount)
+	}
+
+	_, resp = getBrand(t, brandFixtures(maxBrandModels, maxBrandModels), "/brands/acme")
+	if resp.ModelsTruncated {
+		t.Fatal("a brand with exactly maxBrandModels lights reported as truncated")
+	}
+}
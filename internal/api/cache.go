@@ -0,0 +1,268 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// cacheControl lets browsers and CDNs reuse a response for a minute and
+	// serve it stale for five more while they revalidate with If-None-Match.
+	cacheControl = "public, max-age=60, stale-while-revalidate=300"
+
+	// stampTTL is how long a loaded data stamp is trusted, which bounds how
+	// late a newly published run shows up.
+	stampTTL = 2 * time.Second
+
+	maxCacheBytes = 64 << 20
+)
+
+// dataStamp identifies the published data: the latest completed scoring run
+// and data_versions.version, which every publishing transaction bumps: read
+// model refreshes and the velocity rollup.
+type dataStamp struct {
+	RunID   int64
+	Version int64
+}
+
+// responseCache keeps GET responses for the current dataStamp in memory. It
+// is emptied as soon as the stamp changes, so it never serves data older
+// than the last published run.
+type responseCache struct {
+	load func(ctx context.Context) (dataStamp, error)
+
+	mu       sync.Mutex
+	stamp    dataStamp
+	loadedAt time.Time
+	entries  map[string]cachedResponse
+	size     int
+}
+
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func newResponseCache(load func(ctx context.Context) (dataStamp, error)) *responseCache {
+	return &responseCache{load: load, entries: make(map[string]cachedResponse)}
+}
+
+// currentStamp returns the data stamp, reloading it at most every stampTTL,
+// and clears the cache when it has moved on.
+func (c *responseCache) currentStamp(ctx context.Context) (dataStamp, error) {
+	c.mu.Lock()
+	if !c.loadedAt.IsZero() && time.Since(c.loadedAt) < stampTTL {
+		st := c.stamp
+		c.mu.Unlock()
+		return st, nil
+	}
+	c.mu.Unlock()
+
+	st, err := c.load(ctx)
+	if err != nil {
+		return dataStamp{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if st != c.stamp {
+		c.stamp = st
+		c.entries = make(map[string]cachedResponse)
+		c.size = 0
+	}
+	c.loadedAt = time.Now()
+	return st, nil
+}
+
+func (c *responseCache) get(st dataStamp, key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if st != c.stamp {
+		return cachedResponse{}, false
+	}
+	e, ok := c.entries[key]
+	return e, ok
+}
+
+// put stores a response for st. When the cache is full, arbitrary entries
+// are dropped to make room.
+func (c *responseCache) put(st dataStamp, key string, e cachedResponse) {
+	if len(e.body) > maxCacheBytes/16 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if st != c.stamp {
+		return
+	}
+	if old, ok := c.entries[key]; ok {
+		c.size -= len(old.body)
+	}
+	for k, old := range c.entries {
+		if c.size+len(e.body) <= maxCacheBytes {
+			break
+		}
+		delete(c.entries, k)
+		c.size -= len(old.body)
+	}
+	c.entries[key] = e
+	c.size += len(e.body)
+}
+
+// loadDataStamp reads the published run and data version.
+func (s *Server) loadDataStamp(ctx context.Context) (dataStamp, error) {
+	var st dataStamp
+	err := s.db.QueryRowContext(ctx, `
+SELECT
+	COALESCE((
+		SELECT id
+		FROM scoring_runs
+		WHERE status = 'completed'
+		ORDER BY completed_at DESC NULLS LAST, id DESC
+		LIMIT 1
+	), 0),
+	(SELECT version FROM data_versions WHERE id = 1)
+`).Scan(&st.RunID, &st.Version)
+	return st, err
+}
+
+// cached serves GET requests from the response cache and answers
+// If-None-Match with 304. Responses get a strong ETag built from the data
+// stamp and the request, so it is known before the handler runs. If the stamp
+// cannot be loaded the handler runs uncached.
+func (s *Server) cached(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			h(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+		st, err := s.cache.currentStamp(ctx)
+		cancel()
+		if err != nil {
+			h(w, r)
+			return
+		}
+
+		key := cacheKey(r)
+		etag := makeETag(st, key, contentEncoding(r.Context()))
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Cache-Control", cacheControl)
+			w.Header().Add("Vary", strings.Join(countryHeaders, ", "))
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		if e, ok := s.cache.get(st, key); ok {
+			writeCached(w, e, etag, "HIT")
+			return
+		}
+
+		rec := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+		h(rec, r)
+		e := cachedResponse{status: rec.status, header: rec.header, body: rec.body.Bytes()}
+		if rec.status != http.StatusOK {
+			writeCached(w, e, "", "")
+			return
+		}
+		s.cache.put(st, key, e)
+		writeCached(w, e, etag, "MISS")
+	}
+}
+
+func writeCached(w http.ResponseWriter, e cachedResponse, etag, state string) {
+	for k, v := range e.header {
+		if k == "Vary" {
+			// Keep what outer middleware (CORS, compression) already varies on.
+			w.Header()[k] = append(w.Header()[k], v...)
+			continue
+		}
+		w.Header()[k] = v
+	}
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("X-Cache", state)
+	}
+	w.WriteHeader(e.status)
+	_, _ = w.Write(e.body)
+}
+
+// cacheKey is the API version, the path, the query with parameters sorted,
+// and the country headers that select a region. The version is part of the
+// key because redirects name paths under it.
+func cacheKey(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(versionPrefix(r.Context()))
+	b.WriteString(r.URL.Path)
+	q := r.URL.Query()
+	names := make([]string, 0, len(q))
+	for name := range q {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range q[name] {
+			b.WriteString("&" + name + "=" + v)
+		}
+	}
+	for _, h := range countryHeaders {
+		b.WriteString("|" + strings.TrimSpace(r.Header.Get(h)))
+	}
+	return b.String()
+}
+
+// makeETag names the data stamp, the request and the negotiated content
+// encoding: a gzip or brotli body is a different representation from the
+// identity one and must not share its validator.
+func makeETag(st dataStamp, key, encoding string) string {
+	sum := sha256.Sum256([]byte(key))
+	tag := "r" + strconv.FormatInt(st.RunID, 10) + "-v" + strconv.FormatInt(st.Version, 10) + "-" + hex.EncodeToString(sum[:8])
+	if encoding != "" {
+		tag += "-" + encoding
+	}
+	return `"` + tag + `"`
+}
+
+// etagMatches applies the weak comparison If-None-Match calls for.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header { return r.header }
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCachedServesHitsAndNotModified(t *testing.T) {
+	st := dataStamp{RunID: 7, Version: 100}
+	s := &Server{cache: newResponseCache(func(context.Context) (dataStamp, error) { return st, nil })}
+	calls := 0
+	h := s.cached(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeJSON(w, http.StatusOK, map[string]int{"calls": calls})
+	})
+
+	get := func(url, inm string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		if inm != "" {
+			r.Header.Set("If-None-Match", inm)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	first := get("/brands?b=2&a=1", "")
+	etag := first.Header().Get("ETag")
+	if first.Header().Get("X-Cache") != "MISS" || etag == "" || first.Header().Get("Cache-Control") != cacheControl {
+		t.Fatalf("first response headers: %v", first.Header())
+	}
+	second := get("/brands?a=1&b=2", "")
+	if second.Header().Get("X-Cache") != "HIT" || second.Body.String() != first.Body.String() || calls != 1 {
+		t.Fatalf("second: cache=%q calls=%d", second.Header().Get("X-Cache"), calls)
+	}
+	if second.Header().Get("ETag") != etag {
+		t.Fatal("query order changed the ETag")
+	}
+
+	if w := get("/brands?a=1&b=2", `"other", W/`+etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("If-None-Match: status %d", w.Code)
+	}
+	if w := get("/brands?a=1", ""); w.Header().Get("ETag") == etag || calls != 2 {
+		t.Fatal("different query shared an entry")
+	}
+
+	// A newly published run invalidates the cache and every ETag.
+	st = dataStamp{RunID: 8, Version: 101}
+	s.cache.loadedAt = time.Time{}
+	w := get("/brands?a=1&b=2", etag)
+	if w.Code != http.StatusOK || w.Header().Get("X-Cache") != "MISS" || w.Header().Get("ETag") == etag {
+		t.Fatalf("after publish: status %d cache %q", w.Code, w.Header().Get("X-Cache"))
+	}
+}
+
+func TestCachedSkipsErrorsAndMissingStamp(t *testing.T) {
+	s := &Server{cache: newResponseCache(func(context.Context) (dataStamp, error) { return dataStamp{RunID: 1}, nil })}
+	calls := 0
+	notFound := s.cached(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeJSON(w, http.StatusNotFound, apiError{Error: "flashlight not found"})
+	})
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		notFound(w, httptest.NewRequest(http.MethodGet, "/flashlights/9", nil))
+		if w.Code != http.StatusNotFound || w.Header().Get("ETag") != "" {
+			t.Fatalf("404 response: status %d etag %q", w.Code, w.Header().Get("ETag"))
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("404 was cached: calls=%d", calls)
+	}
+
+	broken := &Server{cache: newResponseCache(func(context.Context) (dataStamp, error) { return dataStamp{}, errors.New("db down") })}
+	w := httptest.NewRecorder()
+	broken.cached(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	})(w, httptest.NewRequest(http.MethodGet, "/brands", nil))
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != "" {
+		t.Fatalf("without a stamp: status %d etag %q", w.Code, w.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,297 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashlight-ratings-go/internal/telemetry"
+)
+
+const maxClickStatsRange = 366 * 24 * time.Hour
+
+// clickSources are the page keys a /go link may give as src. A click without
+// one is recorded as "unknown", so free text never reaches the table.
+var clickSources = []string{
+	"home", "rankings", "best-flashlights", "find-yours", "compare", "versus",
+	"flashlight", "brand", "use-case", "search", "trending", "guide",
+}
+
+var maxClickPosition = 10000.0
+
+// goParams are the query parameters of /go/{id}: the page and list position
+// the click came from, and the region whose link to follow.
+var goParams = []param{
+	enumParam("src", "", clickSources...),
+	{Name: "pos", Type: typeInteger, Minimum: &minID, Maximum: &maxClickPosition},
+	regionParam,
+}
+
+type affiliateLink struct {
+	ID       int64
+	Provider string
+	Region   string
+	URL      string
+}
+
+type clickStatsResponse struct {
+	From        string          `json:"from"`
+	To          string          `json:"to"`
+	GroupBy     string          `json:"group_by"`
+	IncludeBots bool            `json:"include_bots"`
+	Total       int64           `json:"total"`
+	Items       []clickStatItem `json:"items"`
+}
+
+type clickStatItem struct {
+	Key          string   `json:"key"`
+	Label        string   `json:"label"`
+	Clicks       int64    `json:"clicks"`
+	AvgPosition  *float64 `json:"avg_position,omitempty"`
+	FlashlightID *int64   `json:"flashlight_id,omitempty"`
+}
+
+// handleGo records an outbound click and redirects to the light's active
+// primary affiliate link. Recording is best effort: a failed insert is logged
+// and the visitor is still redirected.
+func (s *Server) handleGo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+	q, verr := goQuery(r.URL.Query())
+	if err := verr.err(); err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	idPart := strings.TrimPrefix(r.URL.Path, "/go/")
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id <= 0 {
+		writeBadRequest(w, r, invalidField("id", idPart, "must be a positive integer"))
+		return
+	}
+
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	link, err := s.primaryAffiliateLink(ctx, id, rg)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, apiError{Error: "no active affiliate link"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch affiliate link"})
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		source := q.str("src")
+		if source == "" {
+			source = "unknown"
+		}
+		if err := s.recordClick(ctx, id, link, source, q.int64("pos"), userAgentClass(r.UserAgent())); err != nil {
+			telemetry.Logger(ctx).Warn("record affiliate click", "flashlight_id", id, "err", err)
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	http.Redirect(w, r, link.URL, http.StatusFound)
+}
+
+// goQuery validates the goParams in v and drops everything else. Mail and
+// social platforms append their own tracking parameters (utm_*, fbclid,
+// gclid) to outbound links, and those clicks must still redirect.
+func goQuery(v url.Values) (query, *validationError) {
+	known := url.Values{}
+	for _, p := range goParams {
+		if vs, ok := v[p.Name]; ok {
+			known[p.Name] = vs
+		}
+	}
+	return parseQuery(known, goParams)
+}
+
+// primaryAffiliateLink picks the light's active Amazon link for rg, falling
+// back to US.
+func (s *Server) primaryAffiliateLink(ctx context.Context, id int64, rg region) (affiliateLink, error) {
+	var link affiliateLink
+	err := s.db.QueryRowContext(ctx, `
+SELECT a.id, a.provider, a.region_code, a.affiliate_url
+FROM affiliate_links a
+JOIN flashlights f ON f.id = a.flashlight_id
+WHERE a.flashlight_id = $1
+  AND a.provider = 'amazon'
+  AND a.region_code IN ($2, 'US')
+  AND a.is_active = TRUE
+  AND f.is_active = TRUE
+ORDER BY (a.region_code = $2) DESC, a.is_primary DESC, a.updated_at DESC, a.id DESC
+LIMIT 1
+`, id, rg.Code).Scan(&link.ID, &link.Provider, &link.Region, &link.URL)
+	return link, err
+}
+
+func (s *Server) recordClick(ctx context.Context, id int64, link affiliateLink, source string, position *int64, uaClass string) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO affiliate_click_events (flashlight_id, affiliate_link_id, provider, region_code, source_page, position, user_agent_class)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`, id, link.ID, link.Provider, link.Region, source, position, uaClass)
+	return err
+}
+
+// userAgentClass reduces a user agent to a device class; the raw string is
+// never stored.
+func userAgentClass(ua string) string {
+	ua = strings.ToLower(ua)
+	switch {
+	case ua == "":
+		return "other"
+	case strings.Contains(ua, "bot"), strings.Contains(ua, "crawl"), strings.Contains(ua, "spider"),
+		strings.Contains(ua, "slurp"), strings.Contains(ua, "preview"), strings.Contains(ua, "curl"),
+		strings.Contains(ua, "wget"), strings.Contains(ua, "python-requests"), strings.Contains(ua, "go-http-client"):
+		return "bot"
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"):
+		return "tablet"
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "android"):
+		return "mobile"
+	case strings.Contains(ua, "mozilla"):
+		return "desktop"
+	default:
+		return "other"
+	}
+}
+
+var clickStatsParams = []param{
+	enumParam("group_by", "flashlight", "flashlight", "source", "day", "region", "device"),
+	timeParam("from"),
+	timeParam("to"),
+	boolParam("include_bots"),
+	intParam("limit", 50, 1, 500),
+}
+
+// handleClickStats serves GET /admin/clicks/stats, affiliate click counts for
+// editors, once handleAdmin has checked the token. group_by is one of
+// flashlight, source, day, region or device; bot traffic is excluded unless
+// include_bots=true.
+func (s *Server) handleClickStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	q, ok := readQuery(w, r, clickStatsParams)
+	if !ok {
+		return
+	}
+	groupBy := q.str("group_by")
+	to := time.Now().UTC()
+	if t, ok := q.time("to"); ok {
+		to = t
+	}
+	from := to.AddDate(0, 0, -30)
+	if t, ok := q.time("from"); ok {
+		from = t
+	}
+	if !from.Before(to) || to.Sub(from) > maxClickStatsRange {
+		writeBadRequest(w, r, invalidField("from", r.URL.Query().Get("from"), "must be before to and the range at most 366 days"))
+		return
+	}
+	includeBots := q.boolean("include_bots") != nil && *q.boolean("include_bots")
+	limit := q.int("limit")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	resp, err := s.clickStats(ctx, groupBy, from, to, includeBots, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch click stats"})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) clickStats(ctx context.Context, groupBy string, from, to time.Time, includeBots bool, limit int) (clickStatsResponse, error) {
+	resp := clickStatsResponse{
+		From:        from.Format(time.RFC3339),
+		To:          to.Format(time.RFC3339),
+		GroupBy:     groupBy,
+		IncludeBots: includeBots,
+		Items:       []clickStatItem{},
+	}
+
+	var keyExpr, labelExpr, idExpr, joins, orderBy string
+	switch groupBy {
+	case "flashlight":
+		keyExpr, labelExpr, idExpr = "f.slug", "b.name || ' ' || f.name", "f.id"
+		joins = "JOIN flashlights f ON f.id = c.flashlight_id JOIN brands b ON b.id = f.brand_id"
+		orderBy = "clicks DESC, f.id ASC"
+	case "source":
+		keyExpr, labelExpr, idExpr = "c.source_page", "c.source_page", "NULL::BIGINT"
+		orderBy = "clicks DESC, 1 ASC"
+	case "day":
+		keyExpr = "to_char(date_trunc('day', c.clicked_at AT TIME ZONE 'UTC'), 'YYYY-MM-DD')"
+		labelExpr, idExpr = keyExpr, "NULL::BIGINT"
+		orderBy = "1 ASC"
+	case "region":
+		keyExpr, labelExpr, idExpr = "c.region_code", "c.region_code", "NULL::BIGINT"
+		orderBy = "clicks DESC, 1 ASC"
+	case "device":
+		keyExpr, labelExpr, idExpr = "c.user_agent_class", "c.user_agent_class", "NULL::BIGINT"
+		orderBy = "clicks DESC, 1 ASC"
+	}
+
+	query := fmt.Sprintf(`
+SELECT
+	%[1]s AS key,
+	%[2]s AS label,
+	%[3]s AS flashlight_id,
+	COUNT(*) AS clicks,
+	AVG(c.position)::FLOAT8 AS avg_position,
+	SUM(COUNT(*)) OVER ()::BIGINT AS total
+FROM affiliate_click_events c
+%[4]s
+WHERE c.clicked_at >= $1
+  AND c.clicked_at < $2
+  AND ($3 OR c.user_agent_class <> 'bot')
+GROUP BY 1, 2, 3
+ORDER BY %[5]s
+LIMIT %[6]d
+`, keyExpr, labelExpr, idExpr, joins, orderBy, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, from, to, includeBots)
+	if err != nil {
+		return resp, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item     clickStatItem
+			id       sql.NullInt64
+			position sql.NullFloat64
+		)
+		if err := rows.Scan(&item.Key, &item.Label, &id, &item.Clicks, &position, &resp.Total); err != nil {
+			return resp, err
+		}
+		item.FlashlightID = nullInt(id)
+		if position.Valid {
+			item.AvgPosition = ptrFloat(round1(position.Float64))
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	return resp, rows.Err()
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestUserAgentClass(t *testing.T) {
+	cases := map[string]string{
+		"": "other",
+		"Mozilla/5.0 (compatible; Googlebot/2.1)":                           "bot",
+		"Slackbot-LinkExpanding 1.0":                                        "bot",
+		"curl/8.4.0":                                                        "bot",
+		"Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X)":                     "tablet",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E": "mobile",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) Mobile Safari/537.36":     "mobile",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0":            "desktop",
+		"SomeLibrary/1.0":                                                   "other",
+	}
+	for ua, want := range cases {
+		if got := userAgentClass(ua); got != want {
+			t.Errorf("userAgentClass(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
+
+func TestGoParams(t *testing.T) {
+	v, _ := url.ParseQuery("src=Rankings&pos=3&region=gb&utm_source=mail&fbclid=x1&gclid=y2")
+	q, verr := goQuery(v)
+	if err := verr.err(); err != nil {
+		t.Fatalf("valid click rejected: %v", err)
+	}
+	if q.str("src") != "rankings" || q.int64("pos") == nil || *q.int64("pos") != 3 || q.str("region") != "UK" {
+		t.Fatalf("parsed src %q, pos %v, region %q", q.str("src"), q.int64("pos"), q.str("region"))
+	}
+
+	for _, raw := range []string{"src=a+b", "src=%3Cscript%3E", "src=user@example.com", "pos=0", "pos=-2", "pos=x", "pos=99999", "pos=1&pos=2"} {
+		v, _ := url.ParseQuery(raw)
+		if _, verr := goQuery(v); verr.err() == nil {
+			t.Errorf("%s: accepted", raw)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"flashlight-ratings-go/pkg/client"
+)
+
+// TestClientRequestsAreValid runs every pkg/client call against the real
+// routes. Without a database they cannot succeed, but none may be rejected
+// as a bad request, which would mean the client and the parameter
+// declarations have drifted apart.
+func TestClientRequestsAreValid(t *testing.T) {
+	srv := httptest.NewServer(contractServer(t))
+	defer srv.Close()
+	c, err := client.New(client.Config{BaseURL: srv.URL, Region: "DE", MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	budget, throw, usbc := 50.0, int64(200), true
+	profile := client.IntelligenceRunRequest{IntendedUse: "camping", BudgetUSD: 120, BatteryPreference: "18650", SizeConstraint: "compact"}
+	calls := map[string]func() error{
+		"List": func() error {
+			_, err := c.List(ctx, client.ListOptions{
+				Brands: []string{"acme"}, IPRatings: []string{"IPX8"}, MinIPRating: "IPX7", SwitchTypes: []string{"tail"},
+				Min: map[string]float64{"lumens": 500}, Max: map[string]float64{"price": 80},
+				Flags: map[string]bool{"usb_c_rechargeable": true}, SortBy: "price", Order: "asc",
+				Page: 2, PageSize: 10, Facets: true,
+			})
+			return err
+		},
+		"Get":     func() error { _, err := c.Get(ctx, 1); return err },
+		"Compare": func() error { _, err := c.Compare(ctx, 1, 2); return err },
+		"Rankings": func() error {
+			_, err := c.Rankings(ctx, client.RankingsOptions{UseCase: "edc", Page: 1, PageSize: 50})
+			return err
+		},
+		"Finder": func() error {
+			_, err := c.Finder(ctx, client.FinderOptions{FinderFilters: client.FinderFilters{Budget: &budget, USBC: &usbc, MinThrow: &throw}, Limit: 5})
+			return err
+		},
+		"Recommendations": func() error { _, err := c.Recommendations(ctx, profile); return err },
+		"CreateRun":       func() error { _, err := c.CreateRun(ctx, profile); return err },
+		"GetRun":          func() error { _, err := c.GetRun(ctx, 1); return err },
+	}
+	for name, call := range calls {
+		var apiErr *client.Error
+		if err := call(); !errors.As(err, &apiErr) || apiErr.StatusCode == http.StatusBadRequest {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	"flashlight-ratings-go/internal/scoring"
+	"flashlight-ratings-go/pkg/client"
+)
+
+const maxCompareIDs = 20
+
+type (
+	compareResponse  = client.CompareResponse
+	compareAttribute = client.CompareAttribute
+	compareValue     = client.CompareValue
+	compareAxes      = client.CompareAxes
+	compareSummary   = client.CompareSummary
+)
+
+type compareSpec struct {
+	key, label, unit string
+	higherIsBetter   bool
+	value            func(d flashlightDetail) *float64
+}
+
+var compareSpecs = []compareSpec{
+	{"max_lumens", "Max output", "lm", true, func(d flashlightDetail) *float64 { return intValue(d.MaxLumens) }},
+	{"sustained_lumens", "Sustained output", "lm", true, func(d flashlightDetail) *float64 { return intValue(d.SustainedLumens) }},
+	{"max_candela", "Peak intensity", "cd", true, func(d flashlightDetail) *float64 { return intValue(d.MaxCandela) }},
+	{"beam_distance_m", "Beam distance", "m", true, func(d flashlightDetail) *float64 { return intValue(d.BeamDistanceM) }},
+	{"runtime_high_min", "Runtime on high", "min", true, func(d flashlightDetail) *float64 { return intValue(d.RuntimeHighMin) }},
+	{"runtime_medium_min", "Runtime on medium", "min", true, func(d flashlightDetail) *float64 { return intValue(d.RuntimeMediumMin) }},
+	{"weight_g", "Weight", "g", false, func(d flashlightDetail) *float64 { return d.WeightG }},
+	{"length_mm", "Length", "mm", false, func(d flashlightDetail) *float64 { return d.LengthMM }},
+	{"price_usd", "Price", "USD", false, func(d flashlightDetail) *float64 { return d.PriceUSD }},
+	{"waterproof_level", "Water resistance (IP second digit)", "", true, func(d flashlightDetail) *float64 {
+		if d.Waterproof == nil {
+			return nil
+		}
+		level := ipWaterLevel(*d.Waterproof)
+		if level < 0 {
+			return nil
+		}
+		return ptrFloat(float64(level))
+	}},
+	{"impact_resistance_m", "Impact resistance", "m", true, func(d flashlightDetail) *float64 { return d.ImpactResistance }},
+	{"cri", "Color rendering", "CRI", true, func(d flashlightDetail) *float64 { return intValue(d.CRI) }},
+	{"amazon_average_rating", "Amazon rating", "", true, func(d flashlightDetail) *float64 { return d.AmazonAverageRating }},
+	{"tactical_score", "Tactical score", "", true, func(d flashlightDetail) *float64 { return d.TacticalScore }},
+	{"edc_score", "EDC score", "", true, func(d flashlightDetail) *float64 { return d.EDCScore }},
+	{"value_score", "Value score", "", true, func(d flashlightDetail) *float64 { return d.ValueScore }},
+	{"throw_score", "Throw score", "", true, func(d flashlightDetail) *float64 { return d.ThrowScore }},
+	{"flood_score", "Flood score", "", true, func(d flashlightDetail) *float64 { return d.FloodScore }},
+}
+
+// compareFlashlights loads the detail view of each id in request order. Ids
+// that do not exist are returned separately instead of failing the request.
+func (s *Server) compareFlashlights(ctx context.Context, ids []int64, rg region) ([]flashlightDetail, []int64, error) {
+	found, err := s.getFlashlightsByID(ctx, ids, rg)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[int64]flashlightDetail, len(found))
+	for _, item := range found {
+		byID[item.ID] = item
+	}
+
+	items := make([]flashlightDetail, 0, len(ids))
+	var missing []int64
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		item, ok := byID[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, missing, nil
+}
+
+func buildComparison(items []flashlightDetail) compareResponse {
+	resp := compareResponse{
+		Items:      items,
+		Attributes: make([]compareAttribute, 0, len(compareSpecs)),
+		Axes:       make([]compareAxes, 0, len(items)),
+		Summary:    make([]compareSummary, 0, len(items)),
+	}
+	wins := make(map[int64][]string, len(items))
+
+	for _, spec := range compareSpecs {
+		attr := compareAttribute{
+			Key:       spec.key,
+			Label:     spec.label,
+			Unit:      spec.unit,
+			Better:    "lower",
+			WinnerIDs: []int64{},
+			Values:    make([]compareValue, 0, len(items)),
+		}
+		if spec.higherIsBetter {
+			attr.Better = "higher"
+		}
+		known := 0
+		for _, item := range items {
+			v := spec.value(item)
+			attr.Values = append(attr.Values, compareValue{FlashlightID: item.ID, Value: v})
+			if v == nil {
+				continue
+			}
+			known++
+			if attr.Best == nil ||
+				(spec.higherIsBetter && *v > *attr.Best) ||
+				(!spec.higherIsBetter && *v < *attr.Best) {
+				attr.Best = ptrFloat(*v)
+			}
+		}
+		if known == 0 {
+			continue
+		}
+		for i := range attr.Values {
+			v := attr.Values[i].Value
+			if v == nil {
+				continue
+			}
+			if *attr.Best != 0 {
+				attr.Values[i].DeltaPct = ptrFloat(round1((*v - *attr.Best) / math.Abs(*attr.Best) * 100))
+			}
+			// A single known value is not a win.
+			if known > 1 && *v == *attr.Best {
+				attr.WinnerIDs = append(attr.WinnerIDs, attr.Values[i].FlashlightID)
+			}
+		}
+		if len(attr.WinnerIDs) == 1 {
+			wins[attr.WinnerIDs[0]] = append(wins[attr.WinnerIDs[0]], spec.key)
+		}
+		resp.Attributes = append(resp.Attributes, attr)
+	}
+
+	for _, item := range items {
+		resp.Axes = append(resp.Axes, detailAxes(item))
+		w := wins[item.ID]
+		if w == nil {
+			w = []string{}
+		}
+		resp.Summary = append(resp.Summary, compareSummary{
+			FlashlightID: item.ID,
+			Wins:         w,
+			Text:         summaryText(item, w),
+		})
+	}
+	return resp
+}
+
+func detailAxes(d flashlightDetail) compareAxes {
+	c := similarCandidate{
+		candela: valOr(intValue(d.MaxCandela), 0),
+		beam:    valOr(intValue(d.BeamDistanceM), 0),
+	}
+	portability := 0.0
+	if size := scoring.SizeAxis(valOr(d.WeightG, 0), valOr(d.LengthMM, 0)); size > 0 {
+		portability = 100 - size
+	}
+	return compareAxes{
+		FlashlightID: d.ID,
+		Output:       round1(scoring.LumensAxis(valOr(intValue(d.MaxLumens), 0))),
+		Throw:        round1(throwAxis(c)),
+		Runtime:      round1(scoring.RuntimeHighAxis(valOr(intValue(d.RuntimeHighMin), 0))),
+		Portability:  round1(portability),
+		Value:        round1(scoring.PriceAxis(valOr(d.PriceUSD, 0))),
+		Durability:   round1(scoring.DurabilityAxis(valOrString(d.Waterproof, ""), valOr(d.ImpactResistance, 0))),
+	}
+}
+
+func summaryText(d flashlightDetail, wins []string) string {
+	name := d.Brand + " " + d.Name
+	if len(wins) == 0 {
+		return name + " does not lead on any compared attribute."
+	}
+	labels := make([]string, 0, len(wins))
+	for _, key := range wins {
+		for _, spec := range compareSpecs {
+			if spec.key == key {
+				labels = append(labels, strings.ToLower(spec.label))
+				break
+			}
+		}
+	}
+	return name + " leads on " + joinList(labels) + "."
+}
+
+func joinList(parts []string) string {
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return parts[0]
+	case 2:
+		return parts[0] + " and " + parts[1]
+	default:
+		return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+	}
+}
+
+func intValue(v *int64) *float64 {
+	if v == nil {
+		return nil
+	}
+	return ptrFloat(float64(*v))
+}
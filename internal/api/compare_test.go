@@ -0,0 +1,40 @@
+package api
+
+import "testing"
+
+func TestBuildComparisonWinnersAndDeltas(t *testing.T) {
+	lumensA, lumensB := int64(3000), int64(1500)
+	weightA, weightB := 150.0, 100.0
+	priceA := 90.0
+	a := flashlightDetail{FlashlightItem: flashlightItem{ID: 1, Brand: "Fenix", Name: "PD36R Pro", MaxLumens: &lumensA, PriceUSD: &priceA}, WeightG: &weightA}
+	b := flashlightDetail{FlashlightItem: flashlightItem{ID: 2, Brand: "Olight", Name: "Warrior 3S", MaxLumens: &lumensB}, WeightG: &weightB}
+
+	resp := buildComparison([]flashlightDetail{a, b})
+	attrs := map[string]compareAttribute{}
+	for _, attr := range resp.Attributes {
+		attrs[attr.Key] = attr
+	}
+
+	lumens := attrs["max_lumens"]
+	if len(lumens.WinnerIDs) != 1 || lumens.WinnerIDs[0] != 1 || *lumens.Best != 3000 {
+		t.Fatalf("unexpected lumens winner %+v", lumens)
+	}
+	if d := lumens.Values[1].DeltaPct; d == nil || *d != -50 {
+		t.Fatalf("expected -50%% lumens delta, got %v", d)
+	}
+	if weight := attrs["weight_g"]; len(weight.WinnerIDs) != 1 || weight.WinnerIDs[0] != 2 {
+		t.Fatalf("lighter light should win weight, got %+v", weight)
+	}
+	if price, ok := attrs["price_usd"]; !ok || len(price.WinnerIDs) != 0 {
+		t.Fatalf("a single known price must not produce a winner, got %+v", price)
+	}
+	if _, ok := attrs["cri"]; ok {
+		t.Fatalf("attributes with no data should be omitted")
+	}
+	if resp.Summary[0].Text != "Fenix PD36R Pro leads on max output." {
+		t.Fatalf("unexpected summary %q", resp.Summary[0].Text)
+	}
+	if resp.Axes[1].Portability <= resp.Axes[0].Portability {
+		t.Fatalf("lighter light should score higher on portability, got %+v", resp.Axes)
+	}
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// minCompressSize is the smallest body worth compressing. Smaller bodies are
+// sent as they are.
+const minCompressSize = 1024
+
+var (
+	gzipWriters = sync.Pool{New: func() any {
+		zw, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return zw
+	}}
+	// Level 5 keeps brotli close to gzip's CPU cost on dynamic responses
+	// while still compressing JSON noticeably better.
+	brotliWriters = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, 5)
+	}}
+)
+
+// compressibleTypes are the media types worth compressing; images and other
+// already-compressed bodies are left alone.
+var compressibleTypes = map[string]bool{
+	"application/json":         true,
+	"application/problem+json": true,
+	"application/xml":          true,
+	"text/csv":                 true,
+	"text/html":                true,
+	"text/plain":               true,
+}
+
+type contentEncodingKey struct{}
+
+// contentEncoding returns the response encoding compress negotiated for the
+// request, or "" for identity. cached folds it into ETags so each encoding
+// of a resource has its own validator.
+func contentEncoding(ctx context.Context) string {
+	enc, _ := ctx.Value(contentEncodingKey{}).(string)
+	return enc
+}
+
+// compress encodes responses with brotli or gzip, whichever the client
+// accepts and prefers, brotli winning ties. Only successful responses of a
+// compressible type and at least minCompressSize bytes are encoded.
+func compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		enc := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if enc == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), contentEncodingKey{}, enc))
+		cw := &compressWriter{ResponseWriter: w, enc: enc}
+		next.ServeHTTP(cw, r)
+		// Not deferred: after a panic the buffered body must not be sent
+		// ahead of the recovered error response.
+		cw.close()
+	})
+}
+
+// negotiateEncoding picks "br", "gzip" or "" from an Accept-Encoding header.
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+	q := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		weight := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				weight = f
+			}
+		}
+		if name == "x-gzip" {
+			name = "gzip"
+		}
+		q[name] = weight
+	}
+	weight := func(enc string) float64 {
+		if w, ok := q[enc]; ok {
+			return w
+		}
+		return q["*"]
+	}
+	br, gz := weight("br"), weight("gzip")
+	switch {
+	case br > 0 && br >= gz:
+		return "br"
+	case gz > 0:
+		return "gzip"
+	}
+	return ""
+}
+
+// compressWriter buffers the start of a body until it can tell whether the
+// response is worth compressing, then either streams it through the encoder
+// or passes it through unchanged.
+type compressWriter struct {
+	http.ResponseWriter
+	enc     string
+	status  int
+	buf     []byte
+	decided bool
+	zw      io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+	w.status = status
+	if status != http.StatusOK {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.decided {
+		if w.zw != nil {
+			return w.zw.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= minCompressSize {
+		if err := w.decide(w.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && compressibleTypes[mediaType]
+}
+
+// decide sends the header, with encoding headers when compressing, and
+// flushes whatever was buffered.
+func (w *compressWriter) decide(encode bool) error {
+	w.decided = true
+	if encode {
+		h := w.Header()
+		h.Set("Content-Encoding", w.enc)
+		h.Del("Content-Length")
+		switch w.enc {
+		case "br":
+			bw := brotliWriters.Get().(*brotli.Writer)
+			bw.Reset(w.ResponseWriter)
+			w.zw = bw
+		default:
+			gw := gzipWriters.Get().(*gzip.Writer)
+			gw.Reset(w.ResponseWriter)
+			w.zw = gw
+		}
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.zw != nil {
+		_, err = w.zw.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+// close flushes a short body as is or finishes the encoded stream.
+func (w *compressWriter) close() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.zw == nil {
+		return
+	}
+	_ = w.zw.Close()
+	switch zw := w.zw.(type) {
+	case *brotli.Writer:
+		zw.Reset(io.Discard)
+		brotliWriters.Put(zw)
+	case *gzip.Writer:
+		zw.Reset(io.Discard)
+		gzipWriters.Put(zw)
+	}
+	w.zw = nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
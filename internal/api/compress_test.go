@@ -0,0 +1,110 @@
+package api
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := map[string]string{
+		"":                     "",
+		"identity":             "",
+		"gzip, deflate":        "gzip",
+		"gzip, deflate, br":    "br",
+		"br;q=0.5, gzip":       "gzip",
+		"br;q=0, gzip;q=0":     "",
+		"*":                    "br",
+		"*;q=0.1, gzip;q=0.8":  "gzip",
+		"GZIP;q=1.0, BR;q=1.0": "br",
+		"x-gzip":               "gzip",
+	}
+	for header, want := range cases {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompressWithCachedETags(t *testing.T) {
+	st := dataStamp{RunID: 3, Version: 9}
+	s := &Server{cache: newResponseCache(func(context.Context) (dataStamp, error) { return st, nil })}
+	body := `{"items":"` + strings.Repeat("flashlight ", 300) + `"}`
+	h := compress(s.cached(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Vary", "CF-IPCountry")
+		_, _ = io.WriteString(w, body)
+	}))
+
+	get := func(acceptEncoding, inm string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/brands", nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		if inm != "" {
+			r.Header.Set("If-None-Match", inm)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	plain := get("", "")
+	gz := get("gzip", "")
+	br := get("gzip, br", "")
+
+	if plain.Header().Get("Content-Encoding") != "" || plain.Body.String() != body {
+		t.Fatal("identity response was encoded")
+	}
+	if gz.Header().Get("Content-Encoding") != "gzip" || br.Header().Get("Content-Encoding") != "br" {
+		t.Fatalf("encodings: gzip=%q br=%q", gz.Header().Get("Content-Encoding"), br.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(gz.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(zr); string(got) != body {
+		t.Fatal("gzip body does not round-trip")
+	}
+	if got, _ := io.ReadAll(brotli.NewReader(br.Body)); string(got) != body {
+		t.Fatal("brotli body does not round-trip")
+	}
+
+	etags := map[string]bool{plain.Header().Get("ETag"): true, gz.Header().Get("ETag"): true, br.Header().Get("ETag"): true}
+	if len(etags) != 3 || !strings.HasSuffix(gz.Header().Get("ETag"), `-gzip"`) {
+		t.Fatalf("ETags not distinct per encoding: %v", etags)
+	}
+	if vary := strings.Join(gz.Header().Values("Vary"), ", "); !strings.Contains(vary, "Accept-Encoding") || !strings.Contains(vary, "CF-IPCountry") {
+		t.Fatalf("Vary: %v", gz.Header().Values("Vary"))
+	}
+
+	if w := get("gzip", gz.Header().Get("ETag")); w.Code != http.StatusNotModified {
+		t.Fatalf("revalidating gzip: status %d", w.Code)
+	}
+	if w := get("br", gz.Header().Get("ETag")); w.Code != http.StatusOK {
+		t.Fatalf("gzip ETag validated a brotli request: status %d", w.Code)
+	}
+}
+
+func TestCompressSkipsSmallAndErrorResponses(t *testing.T) {
+	h := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			writeJSON(w, http.StatusNotFound, apiError{Error: strings.Repeat("x", 2*minCompressSize)})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}))
+	for _, path := range []string{"/small", "/missing"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Header().Get("Content-Encoding") != "" || !strings.HasPrefix(w.Body.String(), "{") {
+			t.Fatalf("%s was encoded", path)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// cursor is a position in a keyset-paginated list. It names the row a page
+// starts after (or, with Before, ends before) by its sort key and id, so
+// pages do not shift when rows are added or removed ahead of it. Run pins
+// the scoring run the list was read from: later pages keep ranking by that
+// run's scores even after a new run is published. Clients get cursors
+// encoded and must treat them as opaque.
+type cursor struct {
+	// Sort is the ordering the cursor was made for; a cursor is rejected
+	// when the request asks for another one.
+	Sort string `json:"s"`
+	// Key is the row's sort value, nil when it is NULL. Sort values are
+	// NUMERIC or integer columns of at most 15 digits, which float64 and
+	// its shortest JSON form carry exactly.
+	Key    *float64 `json:"k,omitempty"`
+	ID     int64    `json:"i"`
+	Run    int64    `json:"r,omitempty"`
+	Rank   int      `json:"n,omitempty"`
+	Before bool     `json:"b,omitempty"`
+}
+
+// maxCursorLen bounds the cursor parameter; encoded cursors are well under.
+const maxCursorLen = 256
+
+var errBadCursor = errors.New("must be a cursor returned by this endpoint")
+
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(raw string) (cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor{}, errBadCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 || c.Run < 0 || c.Sort == "" {
+		return cursor{}, errBadCursor
+	}
+	return c, nil
+}
+
+func checkCursor(v string) (string, error) {
+	_, err := decodeCursor(v)
+	return v, err
+}
+
+var cursorParam = param{Name: "cursor", Type: typeString, MaxLen: maxCursorLen, check: checkCursor}
+
+// pageCursor reads the cursor parameter for a list sorted by sort, adding
+// any problem with it to verr. A cursor replaces page, so combining the two
+// is an error.
+func pageCursor(q query, verr *validationError, sort string) *cursor {
+	if !q.has("cursor") {
+		return nil
+	}
+	raw := q.str("cursor")
+	if q.has("page") {
+		verr.add("cursor", raw, "cannot be combined with page")
+		return nil
+	}
+	c, _ := decodeCursor(raw) // checked by cursorParam
+	if c.Sort != sort {
+		verr.add("cursor", raw, "was made for another sort order; start again without it")
+		return nil
+	}
+	return &c
+}
+
+// keysetCondition selects the rows after c, or before it when c.Before, in
+// a list ordered by key (descending when desc, NULLs last) and then by id
+// ascending. Placeholders are numbered from argn.
+func keysetCondition(key, id string, desc bool, c cursor, argn int) (string, []any) {
+	cmp := ">"
+	if desc != c.Before {
+		cmp = "<"
+	}
+	idCmp := ">"
+	if c.Before {
+		idCmp = "<"
+	}
+	switch {
+	case c.Key == nil && !c.Before:
+		return fmt.Sprintf("(%s IS NULL AND %s > $%d)", key, id, argn), []any{c.ID}
+	case c.Key == nil:
+		return fmt.Sprintf("(%s IS NOT NULL OR %s < $%d)", key, id, argn), []any{c.ID}
+	case !c.Before:
+		return fmt.Sprintf("(%[1]s %[3]s $%[5]d::NUMERIC OR (%[1]s = $%[5]d::NUMERIC AND %[2]s %[4]s $%[6]d) OR %[1]s IS NULL)",
+			key, id, cmp, idCmp, argn, argn+1), []any{*c.Key, c.ID}
+	default:
+		return fmt.Sprintf("(%[1]s %[3]s $%[5]d::NUMERIC OR (%[1]s = $%[5]d::NUMERIC AND %[2]s %[4]s $%[6]d))",
+			key, id, cmp, idCmp, argn, argn+1), []any{*c.Key, c.ID}
+	}
+}
+
+// keysetOrder is the ORDER BY for keysetCondition: the list order, or its
+// reverse for a page that ends before c, which the caller flips back.
+func keysetOrder(key, id string, desc bool, c *cursor) string {
+	reverse := c != nil && c.Before
+	dir, nulls, idDir := "ASC", "NULLS LAST", "ASC"
+	if desc != reverse {
+		dir = "DESC"
+	}
+	if reverse {
+		nulls, idDir = "NULLS FIRST", "DESC"
+	}
+	return fmt.Sprintf("%s %s %s, %s %s", key, dir, nulls, id, idDir)
+}
+
+// keysetPage trims rows fetched for a cursor page, which asks for one row
+// more than size to learn whether another page follows, and puts a
+// backwards page back in list order. It reports whether rows remain past
+// the page in the direction it was read.
+func keysetPage[T any](rows []T, size int, c *cursor) ([]T, bool) {
+	more := len(rows) > size
+	if more {
+		rows = rows[:size]
+	}
+	if c != nil && c.Before {
+		slices.Reverse(rows)
+	}
+	return rows, more
+}
+
+// keysetRow is what a cursor records about a row.
+type keysetRow struct {
+	key  *float64
+	id   int64
+	rank int
+}
+
+// listPage is one page of a keyset-paginated list.
+type listPage[T any] struct {
+	items []T
+	// total is nil for pages read by cursor, which skip the count.
+	total      *int
+	next, prev string
+}
+
+// pageCursors returns the cursors to the pages after and before rows, which
+// are in list order. base carries the sort and run the page was read with.
+func pageCursors(base cursor, rows []keysetRow, hasNext, hasPrev bool) (next, prev string) {
+	if len(rows) == 0 {
+		return "", ""
+	}
+	if hasNext {
+		c, last := base, rows[len(rows)-1]
+		c.Key, c.ID, c.Rank = last.key, last.id, last.rank
+		next = c.encode()
+	}
+	if hasPrev {
+		c, first := base, rows[0]
+		c.Key, c.ID, c.Rank, c.Before = first.key, first.id, first.rank, true
+		prev = c.encode()
+	}
+	return next, prev
+}
+
+// latestRunSQL selects the latest completed scoring run, whose scores the
+// read model holds, or 0 before the first run.
+const latestRunSQL = `COALESCE((
+	SELECT id
+	FROM scoring_runs
+	WHERE status = 'completed'
+	ORDER BY completed_at DESC NULLS LAST, id DESC
+	LIMIT 1
+), 0)`
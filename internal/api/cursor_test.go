@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	key := 87.25
+	for _, c := range []cursor{
+		{Sort: "tactical_score desc", Key: &key, ID: 42, Run: 7, Rank: 20},
+		{Sort: "price asc", ID: 3, Before: true},
+	} {
+		got, err := decodeCursor(c.encode())
+		if err != nil {
+			t.Fatalf("decode %+v: %v", c, err)
+		}
+		if got.Sort != c.Sort || got.ID != c.ID || got.Run != c.Run || got.Rank != c.Rank || got.Before != c.Before ||
+			(got.Key == nil) != (c.Key == nil) || (c.Key != nil && *got.Key != *c.Key) {
+			t.Fatalf("round trip %+v, got %+v", c, got)
+		}
+	}
+
+	for _, raw := range []string{"", "not base64!", cursor{Sort: "edc"}.encode(), cursor{ID: 1}.encode(), cursor{Sort: "edc", ID: 1, Run: -1}.encode()} {
+		if _, err := decodeCursor(raw); err == nil {
+			t.Errorf("decodeCursor(%q): no error", raw)
+		}
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	params := []param{intParam("page", 1, 1, 100), cursorParam}
+	raw := cursor{Sort: "edc", ID: 5}.encode()
+	other := cursor{Sort: "tactical", ID: 5}.encode()
+
+	for query, want := range map[string]string{
+		"cursor=" + raw:             "",
+		"cursor=" + raw + "&page=2": "cannot be combined with page",
+		"cursor=" + other:           "was made for another sort order; start again without it",
+		"cursor=abc":                "must be a cursor returned by this endpoint",
+	} {
+		v, _ := url.ParseQuery(query)
+		q, verr := parseQuery(v, params)
+		c := pageCursor(q, verr, "edc")
+		var got string
+		if len(verr.Errors) > 0 {
+			got = verr.Errors[0].Message
+		}
+		if got != want {
+			t.Errorf("%s: error %q, want %q", query, got, want)
+		}
+		if want == "" && (c == nil || c.ID != 5) {
+			t.Errorf("%s: cursor %+v", query, c)
+		}
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	key := 9.5
+	for _, tc := range []struct {
+		c           cursor
+		desc        bool
+		cond, order string
+	}{
+		{
+			cursor{Key: &key, ID: 4}, true,
+			"(k < $3::NUMERIC OR (k = $3::NUMERIC AND id > $4) OR k IS NULL)",
+			"k DESC NULLS LAST, id ASC",
+		},
+		{
+			cursor{Key: &key, ID: 4, Before: true}, true,
+			"(k > $3::NUMERIC OR (k = $3::NUMERIC AND id < $4))",
+			"k ASC NULLS FIRST, id DESC",
+		},
+		{
+			cursor{ID: 4}, false,
+			"(k IS NULL AND id > $3)",
+			"k ASC NULLS LAST, id ASC",
+		},
+		{
+			cursor{ID: 4, Before: true}, false,
+			"(k IS NOT NULL OR id < $3)",
+			"k DESC NULLS FIRST, id DESC",
+		},
+	} {
+		cond, _ := keysetCondition("k", "id", tc.desc, tc.c, 3)
+		if cond != tc.cond {
+			t.Errorf("%+v: condition %s, want %s", tc.c, cond, tc.cond)
+		}
+		if order := keysetOrder("k", "id", tc.desc, &tc.c); order != tc.order {
+			t.Errorf("%+v: order %s, want %s", tc.c, order, tc.order)
+		}
+	}
+}
+
+func TestKeysetPage(t *testing.T) {
+	rows, more := keysetPage([]int{1, 2, 3}, 2, &cursor{})
+	if !more || !slices.Equal(rows, []int{1, 2}) {
+		t.Fatalf("forward: %v %v", rows, more)
+	}
+	// A backwards page is read in reverse and flipped back to list order.
+	rows, more = keysetPage([]int{5, 4}, 2, &cursor{Before: true})
+	if more || !slices.Equal(rows, []int{4, 5}) {
+		t.Fatalf("backward: %v %v", rows, more)
+	}
+
+	next, prev := pageCursors(cursor{Sort: "edc", Run: 3}, []keysetRow{{id: 4, rank: 11}, {id: 5, rank: 12}}, true, true)
+	n, _ := decodeCursor(next)
+	p, _ := decodeCursor(prev)
+	if n.ID != 5 || n.Rank != 12 || n.Before || n.Run != 3 || p.ID != 4 || p.Rank != 11 || !p.Before {
+		t.Fatalf("next %+v, prev %+v", n, p)
+	}
+	if next, prev := pageCursors(cursor{Sort: "edc"}, nil, true, true); next != "" || prev != "" {
+		t.Fatalf("empty page has cursors %q %q", next, prev)
+	}
+}
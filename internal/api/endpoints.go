@@ -0,0 +1,120 @@
+package api
+
+import (
+	"net/http"
+)
+
+// endpoint describes one public route. The exported fields are what
+// /parameters publishes; the rest feed the OpenAPI document.
+type endpoint struct {
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Parameters []param `json:"parameters"`
+	Body       []param `json:"body,omitempty"`
+
+	// op is the OpenAPI operationId and summary a one-line description.
+	op      string
+	summary string
+	// request is the JSON body type when Body does not describe it.
+	request any
+	// status is the success status, 200 when zero, and response a value of
+	// the type written with it; nil means no body.
+	status   int
+	response any
+	// html marks a success response that is, or for browsers may be, an
+	// HTML page.
+	html bool
+	// redirect, when set, is the body of a 301 to the canonical path.
+	redirect any
+	// errors lists statuses beyond the common ones that the handler answers
+	// with an apiError.
+	errors []int
+}
+
+// endpoints lists every public route with the query parameters, and for
+// POST routes the JSON body fields, it accepts. Handlers pass the same
+// slices to readQuery, so the list cannot drift from what is enforced.
+// Paths are relative to apiVersion.
+var endpoints = []endpoint{
+	{Method: http.MethodGet, Path: "/flashlights", Parameters: flashlightsParams,
+		op: "listFlashlights", summary: "Filter, sort and page the catalog.", response: paginatedFlashlightsResponse{}},
+	{Method: http.MethodGet, Path: "/flashlights/{id}", Parameters: regionParams,
+		op: "getFlashlight", summary: "One flashlight with specs, modes and offers.", response: flashlightDetail{}},
+	{Method: http.MethodGet, Path: "/flashlights/{id}/similar", Parameters: similarParams,
+		op: "similarFlashlights", summary: "Lights closest in specs, optionally only cheaper ones.", response: similarResponse{}},
+	{Method: http.MethodGet, Path: "/flashlights/{id}/prices", Parameters: priceParams,
+		op: "priceHistory", summary: "Price candles and trailing lows per source.", response: priceHistoryResponse{}},
+	{Method: http.MethodGet, Path: "/flashlights/by-slug/{slug}", Parameters: regionParams,
+		op: "getFlashlightBySlug", summary: "One flashlight by slug; old slugs redirect.", response: flashlightDetail{}, redirect: slugRedirectResponse{}},
+	{Method: http.MethodGet, Path: "/search", Parameters: searchParams,
+		op: "search", summary: "Full-text search over brand, name, model and LED.", response: searchResponse{}},
+	{Method: http.MethodGet, Path: "/search/suggest", Parameters: suggestParams,
+		op: "searchSuggest", summary: "Typeahead suggestions.", response: searchSuggestResponse{}},
+	{Method: http.MethodGet, Path: "/compare", Parameters: compareParams,
+		op: "compare", summary: "Side-by-side comparison of up to 20 lights.", response: compareResponse{}},
+	{Method: http.MethodGet, Path: "/versus/{slugA}/{slugB}", Parameters: regionParams,
+		op: "versus", summary: "Head-to-head comparison; non-canonical pairs redirect.", response: versusResponse{}, redirect: versusRedirectResponse{}},
+	{Method: http.MethodGet, Path: "/versus/pairs", Parameters: versusPairsParams,
+		op: "versusPairs", summary: "Popular head-to-head pairs.", response: versusPairsResponse{}},
+	{Method: http.MethodGet, Path: "/brands", Parameters: noParams,
+		op: "listBrands", summary: "Brands with model counts and score ranges.", response: brandsResponse{}},
+	{Method: http.MethodGet, Path: "/brands/{slug}", Parameters: regionParams,
+		op: "getBrand", summary: "One brand and its models.", response: brandDetail{}},
+	{Method: http.MethodGet, Path: "/use-cases", Parameters: noParams,
+		op: "listUseCases", summary: "Use cases with their scoring profiles.", response: useCasesResponse{}},
+	{Method: http.MethodGet, Path: "/use-cases/{slug}/flashlights", Parameters: useCaseFlashlightsParams,
+		op: "useCaseFlashlights", summary: "Lights ranked for one use case.", response: useCaseFlashlightsResponse{}},
+	{Method: http.MethodGet, Path: "/trending", Parameters: trendingParams,
+		op: "trending", summary: "Lights gathering ratings fastest.", response: trendingResponse{}},
+	{Method: http.MethodGet, Path: "/rankings", Parameters: rankingsParams,
+		op: "rankings", summary: "Lights ranked by a score from the latest scoring run.", response: rankingsResponse{}},
+	{Method: http.MethodGet, Path: "/finder", Parameters: finderParams,
+		op: "finder", summary: "Best lights under a budget and spec floor.", response: finderResponse{}},
+	{Method: http.MethodPost, Path: "/intelligence/recommendations", Parameters: regionParams, Body: intelligenceFields,
+		op: "intelligenceRecommendations", summary: "Recommendations for a buyer profile, not stored.", response: intelligenceResponse{}},
+	{Method: http.MethodPost, Path: "/intelligence/runs", Parameters: regionParams, Body: intelligenceFields,
+		op: "createIntelligenceRun", summary: "Recommendations for a buyer profile, stored as a run.", status: http.StatusCreated, response: intelligenceRunResponse{}},
+	{Method: http.MethodGet, Path: "/intelligence/runs/{id}", Parameters: noParams,
+		op: "getIntelligenceRun", summary: "A stored recommendation run.", response: intelligenceRunResponse{}},
+	{Method: http.MethodPost, Path: "/alerts", Parameters: noParams, request: alertRequest{},
+		op: "subscribeAlert", summary: "Subscribe to a price drop alert; confirmed by email.", status: http.StatusAccepted, response: alertPendingResponse{}, errors: []int{http.StatusNotFound}},
+	{Method: http.MethodGet, Path: "/alerts/{action}", Parameters: alertActionParams,
+		op: "alertActionPage", summary: "Page for an email link, whose button POSTs the change; changes nothing.", html: true, errors: []int{http.StatusConflict}},
+	{Method: http.MethodPost, Path: "/alerts/{action}", Parameters: alertActionParams,
+		op: "alertAction", summary: "Confirm, pause, resume or unsubscribe an alert.", response: alertResponse{}, html: true, errors: []int{http.StatusConflict}},
+	{Method: http.MethodGet, Path: "/go/{id}", Parameters: goParams,
+		op: "goToOffer", summary: "Record a click and redirect to the affiliate link.", status: http.StatusFound},
+	{Method: http.MethodGet, Path: "/status", Parameters: noParams,
+		op: "status", summary: "Freshness of scoring, Amazon sync and prices.", response: statusResponse{}},
+	{Method: http.MethodGet, Path: "/parameters", Parameters: noParams,
+		op: "parameters", summary: "Query parameters and allowed values per endpoint.", response: parametersResponse{}},
+	{Method: http.MethodGet, Path: "/openapi.json", Parameters: noParams,
+		op: "openAPI", summary: "This document.", response: map[string]any{}},
+}
+
+// pathParams declares the placeholders used in endpoint paths.
+var pathParams = map[string]param{
+	"id":     {Name: "id", Type: typeInteger, Minimum: &minID},
+	"slug":   {Name: "slug", Type: typeString, MaxLen: 200},
+	"slugA":  {Name: "slugA", Type: typeString, MaxLen: 200},
+	"slugB":  {Name: "slugB", Type: typeString, MaxLen: 200},
+	"action": {Name: "action", Type: typeString, Allowed: []string{"confirm", "unsubscribe", "pause", "resume"}},
+}
+
+type parametersResponse struct {
+	Endpoints []endpoint `json:"endpoints"`
+}
+
+// handleParameters publishes endpoints so clients can build forms and
+// validate input without hard-coding allowed values.
+func (s *Server) handleParameters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+	if _, ok := readQuery(w, r, noParams); !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	writeJSON(w, http.StatusOK, parametersResponse{Endpoints: endpoints})
+}
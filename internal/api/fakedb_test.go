@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixture answers every query containing match with rows, ignoring
+// differences in whitespace. When arg is set the query's first argument must
+// also equal it. Each row holds the values the handler scans, in order.
+type fixture struct {
+	match string
+	arg   any
+	rows  [][]driver.Value
+}
+
+// openFakeDB returns a database whose queries are answered from fixtures,
+// first match wins. A query no fixture matches fails, so new SQL shows up as
+// a 500 rather than an empty success. Statements run through Exec always
+// succeed.
+func openFakeDB(t *testing.T, fixtures ...fixture) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(fakeConnector{fixtures})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type fakeConnector struct{ fixtures []fixture }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fakedb: open through openFakeDB")
+}
+
+type fakeConn fakeConnector
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakedb: prepared statements are not supported")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+// CheckNamedValue passes arguments such as []int64 through unconverted.
+func (c fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	query = compactSQL(query)
+	for _, f := range c.fixtures {
+		if !strings.Contains(query, compactSQL(f.match)) {
+			continue
+		}
+		if f.arg != nil && (len(args) == 0 || !reflect.DeepEqual(args[0].Value, f.arg)) {
+			continue
+		}
+		return &fakeRows{rows: f.rows}, nil
+	}
+	return nil, fmt.Errorf("fakedb: no fixture for %s", query)
+}
+
+func compactSQL(q string) string {
+	return strings.Join(strings.Fields(q), " ")
+}
+
+func (c fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	rows [][]driver.Value
+	next int
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	cols := make([]string, len(r.rows[0]))
+	for i := range cols {
+		cols[i] = fmt.Sprintf("c%d", i)
+	}
+	return cols
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next == len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+// row flattens values and slices of values into one row.
+func row(parts ...any) []driver.Value {
+	var out []driver.Value
+	for _, p := range parts {
+		if vs, ok := p.([]driver.Value); ok {
+			out = append(out, vs...)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+var (
+	fixtureTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	// fixtureOffer is what region.offerColumns selects: link, link region,
+	// price and currency.
+	fixtureOffer = []driver.Value{"https://www.amazon.com/dp/B0TEST0001?tag=fr-20", "US", 49.95, "USD"}
+	// fixtureScores are the tactical, edc, value, throw and flood scores.
+	fixtureScores = []driver.Value{81.5, 77.25, 64.0, 70.5, 55.75}
+)
+
+// fixtureDetail is the getFlashlightsByID row of light id.
+func fixtureDetail(id int64, slug string) []driver.Value {
+	return row(
+		id, "Acme", "One "+slug, slug, "A1", int64(2024), 59.95, "A bright light.", "https://img.example/1.jpg",
+		"https://www.amazon.com/dp/B0TEST0001?tag=fr-20", "B0TEST0001", "US", 49.95, "USD",
+		// max and sustained lumens, candela, beam, five runtimes, stepdown
+		int64(3000), int64(900), int64(40000), int64(400), int64(1200), int64(300), int64(120), int64(3), int64(150), int64(180),
+		"spot", "usb-c", true, "IPX8",
+		// weight, length, head and body diameter, impact resistance
+		90.5, 120.0, 30.0, 25.4, 1.5,
+		"aluminium",
+		true, true, true, true, true, true, true, false, true, // charging and feature flags
+		"tail", "XHP70.3", int64(70), int64(5000), int64(6500),
+		49.95, fixtureTime, int64(1250), 4.6, fixtureTime,
+		fixtureScores,
+		[]byte(`["18650"]`), []byte(`["https://img.example/1.jpg"]`),
+		[]byte(`[{"name":"High","output_lumens":1000,"runtime_min":120,"candela":10000,"beam_distance_m":200}]`),
+		[]byte(`["edc"]`),
+	)
+}
+
+// fixtureCandidate is the specCandidates row of light id.
+func fixtureCandidate(id int64, slug string, lumens int64, price float64) []driver.Value {
+	return row(id, "Acme", "One "+slug, slug, "https://img.example/1.jpg", fixtureOffer,
+		lumens, int64(40000), int64(400), 90.5, 120.0, price, []byte(`["18650"]`))
+}
+
+// fixtureListing holds the columns listFlashlights, use case and trending
+// rows share: id through the five scores.
+var fixtureListing = row(
+	int64(1), "Acme", "One", "acme-one", "A1", "A bright light.", "https://img.example/1.jpg", fixtureOffer,
+	int64(3000), int64(40000), int64(400), int64(120), "IPX8", 49.95, fixtureScores,
+)
+
+// contractFixtures answer the queries behind every documented operation with
+// plausible rows. More specific matches come first.
+func contractFixtures() []fixture {
+	return []fixture{
+		{match: "(SELECT version FROM data_versions WHERE id = 1)", rows: [][]driver.Value{{int64(7), int64(42)}}},
+
+		{match: "FROM flashlight_slug_history h", arg: "acme-one", rows: [][]driver.Value{{int64(1), "acme-one"}}},
+		{match: "FROM flashlight_slug_history h", arg: "acme-two", rows: [][]driver.Value{{int64(2), "acme-two"}}},
+		{match: "FROM flashlight_slug_history h", arg: "acme-old", rows: [][]driver.Value{{int64(1), "acme-one"}}},
+		{match: "WHERE f.id = ANY($1)", arg: []int64{1}, rows: [][]driver.Value{fixtureDetail(1, "acme-one")}},
+		{match: "WHERE f.id = ANY($1)", arg: []int64{2}, rows: [][]driver.Value{fixtureDetail(2, "acme-two")}},
+		{match: "WHERE f.id = ANY($1)", arg: []int64{1, 2}, rows: [][]driver.Value{fixtureDetail(2, "acme-two"), fixtureDetail(1, "acme-one")}},
+		{match: "WITH batteries AS", rows: [][]driver.Value{
+			fixtureCandidate(1, "acme-one", 3000, 49.95),
+			fixtureCandidate(2, "acme-two", 2800, 39.95),
+		}},
+
+		{match: "SELECT EXISTS (SELECT 1 FROM flashlights WHERE id = $1)", rows: [][]driver.Value{{true}}},
+		{match: "date_trunc($4, p.captured_at AT TIME ZONE 'UTC') AS bucket", rows: [][]driver.Value{
+			{"amazon", "USD", fixtureTime, 52.0, 55.0, 45.0, 49.95, 50.0, int64(4)},
+		}},
+		{match: "MAX(p.captured_at) AS current_at", rows: [][]driver.Value{
+			{"amazon", "USD", 49.95, fixtureTime, 45.0, 45.0, 39.95},
+		}},
+
+		{match: "AS text_rank", rows: [][]driver.Value{
+			{int64(1), "Acme", "One", "acme-one", "A1", "XHP70.3", "https://img.example/1.jpg", 49.95, "Acme <mark>One</mark>", 0.42},
+		}},
+		{match: "d.brand || ' ' || d.name AS label", rows: [][]driver.Value{{int64(1), "acme-one", "Acme One"}}},
+
+		{match: "HAVING $1 <> '' OR COUNT(f.id) > 0", rows: [][]driver.Value{
+			{int64(1), "Acme", "acme", "US", "https://acme.example", int64(3), 29.95, 89.95},
+		}},
+		{match: "WITH brand_scores AS", rows: [][]driver.Value{{int64(1), "edc", 72.35, int64(1), "One", "acme-one", 77.25}}},
+
+		{match: "FROM use_cases u", rows: [][]driver.Value{{int64(1), "Everyday carry", "edc", int64(3)}}},
+		{match: "FROM flashlight_use_cases fuc JOIN flashlights f", rows: [][]driver.Value{row(fixtureListing, 77.25, 0.9)}},
+
+		{match: "SELECT MAX(metric_date) FROM flashlight_review_velocity_daily", rows: [][]driver.Value{{fixtureTime}}},
+		{match: "FROM flashlight_review_velocity_daily v", rows: [][]driver.Value{
+			row(fixtureListing, int64(1250), int64(4), int64(31), int64(140), 3.2),
+		}},
+
+		{match: "JOIN pinned_run pr ON TRUE", rows: [][]driver.Value{
+			row(81.5, "tactical", int64(1), "Acme", "One", "acme-one", "https://img.example/1.jpg", fixtureOffer, int64(7)),
+		}},
+		{match: "SELECT COUNT(*) FROM flashlights f JOIN selected_profile sp", rows: [][]driver.Value{{int64(1)}}},
+
+		{match: "AS value, ", rows: [][]driver.Value{{"18650", nil, int64(1)}}},
+		{match: "s.waterproof_rating, rm.price_usd, rm.tactical_score", rows: [][]driver.Value{
+			row(fixtureListing, 81.5, int64(7)),
+		}},
+		{match: "SELECT COUNT(*) FROM flashlights f JOIN brands b", rows: [][]driver.Value{{int64(1)}}},
+
+		{match: "AS finder_score", rows: [][]driver.Value{
+			row(int64(1), "Acme", "One", fixtureOffer, 49.95, int64(400), 81.5, 70.5, 64.0, 73.1),
+		}},
+		{match: "WITH battery_choice AS", rows: [][]driver.Value{
+			row(int64(1), "Acme", "One", "edc", "https://img.example/1.jpg", fixtureOffer, 49.95,
+				int64(3000), int64(40000), int64(400), int64(120), int64(300), 90.5, 120.0, "IPX8", "18650", fixtureScores),
+		}},
+		{match: "INSERT INTO intelligence_runs", rows: [][]driver.Value{{int64(9), fixtureTime}}},
+		{match: "FROM intelligence_runs WHERE id = $1", rows: [][]driver.Value{
+			{int64(9), fixtureTime, "edc", 80.0, "any", "any", "v1", []byte(`[]`)},
+		}},
+
+		{match: "WHERE f.id = $1 AND f.is_active = TRUE", rows: [][]driver.Value{{"Acme One"}}},
+		{match: "confirm_sent_at > NOW() - INTERVAL '1 hour'", rows: [][]driver.Value{{int64(0)}}},
+		{match: "INSERT INTO price_drop_alerts", rows: [][]driver.Value{{int64(3)}}},
+		{match: "FROM price_drop_alerts a", rows: [][]driver.Value{{int64(3), int64(1), 39.95, "USD", "pending", "Acme One"}}},
+		{match: "RETURNING id, flashlight_id, target_price::FLOAT8, currency_code, status", rows: [][]driver.Value{
+			{int64(3), int64(1), 39.95, "USD", "active"},
+		}},
+
+		{match: "SELECT a.id, a.provider, a.region_code, a.affiliate_url", rows: [][]driver.Value{
+			{int64(5), "amazon", "US", "https://www.amazon.com/dp/B0TEST0001?tag=fr-20"},
+		}},
+		{match: "(SELECT MAX(captured_at) FROM amazon_product_snapshots)", rows: [][]driver.Value{
+			{fixtureTime, int64(7), fixtureTime, fixtureTime, int64(120), int64(2)},
+		}},
+	}
+}
@@ -0,0 +1,456 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"flashlight-ratings-go/pkg/client"
+)
+
+type flashlightFilters struct {
+	BatteryTypes  []string
+	Brands        []string
+	IPRatings     []string
+	MinIPRating   string
+	LEDModel      string
+	SwitchTypes   []string
+	BeamPatterns  []string
+	RechargeTypes []string
+	Ranges        map[string]numberRange
+	Flags         map[string]bool
+	SortBy        string
+	Order         string
+	Page          int
+	PageSize      int
+	Cursor        *cursor
+	WithFacets    bool
+	Region        region
+}
+
+type numberRange struct {
+	Min *float64
+	Max *float64
+}
+
+// rangeFilter maps a min_<param>/max_<param> query pair onto a spec column.
+type rangeFilter struct {
+	param  string
+	column string
+}
+
+// flagFilter maps a boolean query parameter onto a spec column.
+type flagFilter struct {
+	param  string
+	column string
+}
+
+var flashlightRangeFilters = []rangeFilter{
+	{param: "price", column: "rm.price_usd"},
+	{param: "lumens", column: "s.max_lumens"},
+	{param: "candela", column: "s.max_candela"},
+	{param: "beam_distance", column: "s.beam_distance_m"},
+	{param: "runtime_low", column: "s.runtime_low_min"},
+	{param: "runtime_medium", column: "s.runtime_medium_min"},
+	{param: "runtime_high", column: "s.runtime_high_min"},
+	{param: "runtime_turbo", column: "s.runtime_turbo_min"},
+	{param: "runtime_500", column: "s.runtime_500_min"},
+	{param: "weight", column: "s.weight_g"},
+	{param: "length", column: "s.length_mm"},
+	{param: "head_diameter", column: "s.head_diameter_mm"},
+}
+
+var flashlightFlagFilters = []flagFilter{
+	{param: "usb_c_rechargeable", column: "s.usb_c_rechargeable"},
+	{param: "has_strobe", column: "s.has_strobe"},
+	{param: "has_memory_mode", column: "s.has_memory_mode"},
+	{param: "has_lockout", column: "s.has_lockout"},
+	{param: "has_moonlight_mode", column: "s.has_moonlight_mode"},
+	{param: "has_magnetic_tailcap", column: "s.has_magnetic_tailcap"},
+	{param: "has_pocket_clip", column: "s.has_pocket_clip"},
+}
+
+var (
+	ipRatingRe    = regexp.MustCompile(`^IP[0-6X][0-9X]$`)
+	switchTypes   = []string{"tail", "side", "dual", "rotary", "twist"}
+	beamPatterns  = []string{"flood", "throw", "hybrid"}
+	rechargeTypes = []string{"usb-c", "magnetic", "none"}
+	maxListValues = 20
+)
+
+// sortFields are the sort_by values /flashlights accepts; sortColumn maps
+// them to columns.
+var sortFields = []string{"tactical_score", "edc_score", "value_score", "throw_score", "flood_score", "price", "max_lumens", "max_candela", "runtime_high_min"}
+
+// flashlightsParams are the query parameters /flashlights accepts.
+var flashlightsParams = func() []param {
+	ipRating := listParam("ip_rating", strings.ToUpper)
+	ipRating.check = checkIPRating
+	params := []param{
+		listParam("battery_type", strings.ToUpper),
+		listParam("brand", strings.ToLower),
+		ipRating,
+		{Name: "min_ip_rating", Type: typeString, norm: strings.ToUpper, check: checkMinIPRating},
+		stringParam("led_model", 64),
+		listParam("switch_type", strings.ToLower, switchTypes...),
+		listParam("beam_pattern", strings.ToLower, beamPatterns...),
+		listParam("recharge_type", strings.ToLower, rechargeTypes...),
+		enumParam("sort_by", "tactical_score", sortFields...),
+		enumParam("order", "desc", "asc", "desc"),
+		intParam("page", 1, 1, 100000),
+		intParam("page_size", 20, 1, 100),
+		cursorParam,
+		boolParam("facets"),
+		regionParam,
+	}
+	for _, rf := range flashlightRangeFilters {
+		params = append(params, numberParam("min_"+rf.param, 0), numberParam("max_"+rf.param, 0))
+	}
+	for _, ff := range flashlightFlagFilters {
+		params = append(params, boolParam(ff.param))
+	}
+	return params
+}()
+
+func checkIPRating(v string) (string, error) {
+	if !ipRatingRe.MatchString(v) {
+		return "", errors.New("must be an IP code such as IPX8 or IP68")
+	}
+	return v, nil
+}
+
+// checkMinIPRating also needs a water ingress digit to compare against.
+func checkMinIPRating(v string) (string, error) {
+	if !ipRatingRe.MatchString(v) || ipWaterLevel(v) < 0 {
+		return "", errors.New("must be an IP code with a water rating such as IPX7")
+	}
+	return v, nil
+}
+
+func parseFlashlightFilters(v url.Values) (flashlightFilters, error) {
+	q, verr := parseQuery(v, flashlightsParams)
+	f := flashlightFilters{
+		BatteryTypes:  q.list("battery_type"),
+		Brands:        q.list("brand"),
+		IPRatings:     q.list("ip_rating"),
+		MinIPRating:   q.str("min_ip_rating"),
+		LEDModel:      q.str("led_model"),
+		SwitchTypes:   q.list("switch_type"),
+		BeamPatterns:  q.list("beam_pattern"),
+		RechargeTypes: q.list("recharge_type"),
+		SortBy:        q.str("sort_by"),
+		Order:         q.str("order"),
+		Page:          q.int("page"),
+		PageSize:      q.int("page_size"),
+		Ranges:        map[string]numberRange{},
+		Flags:         map[string]bool{},
+	}
+	if b := q.boolean("facets"); b != nil {
+		f.WithFacets = *b
+	}
+	f.Cursor = pageCursor(q, verr, flashlightsSort(f))
+
+	for _, rf := range flashlightRangeFilters {
+		r := numberRange{Min: q.float("min_" + rf.param), Max: q.float("max_" + rf.param)}
+		if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+			verr.add("min_"+rf.param, v.Get("min_"+rf.param), "must not exceed max_"+rf.param)
+			continue
+		}
+		if r.Min != nil || r.Max != nil {
+			f.Ranges[rf.param] = r
+		}
+	}
+	for _, ff := range flashlightFlagFilters {
+		if b := q.boolean(ff.param); b != nil {
+			f.Flags[ff.param] = *b
+		}
+	}
+
+	if err := verr.err(); err != nil {
+		return flashlightFilters{}, err
+	}
+	return f, nil
+}
+
+func buildFlashlightWhere(f flashlightFilters) (string, []any) {
+	clauses := []string{"f.is_active = TRUE"}
+	args := make([]any, 0, 8)
+	argn := 1
+
+	in := func(column string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		clauses = append(clauses, fmt.Sprintf("%s IN (%s)", column, makePlaceholders(argn, len(values))))
+		for _, v := range values {
+			args = append(args, v)
+		}
+		argn += len(values)
+	}
+
+	if len(f.BatteryTypes) > 0 {
+		clauses = append(clauses, fmt.Sprintf(`
+EXISTS (
+	SELECT 1
+	FROM flashlight_battery_compatibility fbc
+	JOIN battery_types bt ON bt.id = fbc.battery_type_id
+	WHERE fbc.flashlight_id = f.id
+	  AND bt.code IN (%s)
+)`, makePlaceholders(argn, len(f.BatteryTypes))))
+		for _, v := range f.BatteryTypes {
+			args = append(args, v)
+		}
+		argn += len(f.BatteryTypes)
+	}
+	in("b.slug", f.Brands)
+
+	for _, rf := range flashlightRangeFilters {
+		r, ok := f.Ranges[rf.param]
+		if !ok {
+			continue
+		}
+		if r.Min != nil {
+			clauses = append(clauses, fmt.Sprintf("%s >= $%d", rf.column, argn))
+			args = append(args, *r.Min)
+			argn++
+		}
+		if r.Max != nil {
+			clauses = append(clauses, fmt.Sprintf("%s <= $%d", rf.column, argn))
+			args = append(args, *r.Max)
+			argn++
+		}
+	}
+
+	for _, ff := range flashlightFlagFilters {
+		v, ok := f.Flags[ff.param]
+		if !ok {
+			continue
+		}
+		clauses = append(clauses, fmt.Sprintf("%s = $%d", ff.column, argn))
+		args = append(args, v)
+		argn++
+	}
+
+	in("s.waterproof_rating", f.IPRatings)
+	if f.MinIPRating != "" {
+		// The second IP digit is the water ingress level; "X" means untested.
+		clauses = append(clauses, fmt.Sprintf(`
+(CASE
+	WHEN substring(s.waterproof_rating FROM 4 FOR 1) ~ '^[0-9]$'
+	THEN substring(s.waterproof_rating FROM 4 FOR 1)::INTEGER
+END) >= $%d`, argn))
+		args = append(args, ipWaterLevel(f.MinIPRating))
+		argn++
+	}
+
+	if f.LEDModel != "" {
+		clauses = append(clauses, fmt.Sprintf(`s.led_model ILIKE '%%' || $%d || '%%' ESCAPE '\'`, argn))
+		args = append(args, escapeLike(f.LEDModel))
+		argn++
+	}
+	in("s.switch_type", f.SwitchTypes)
+	in("s.beam_pattern", f.BeamPatterns)
+	in("s.recharge_type", f.RechargeTypes)
+
+	return "WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// likeEscaper quotes the LIKE wildcards in user input, for patterns declared
+// with ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// flashlightFilterFrom is the join set every filter clause is written against.
+// Listing counts and facet counts share it so their semantics cannot drift.
+const flashlightFilterFrom = `
+FROM flashlights f
+JOIN brands b ON b.id = f.brand_id
+LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
+LEFT JOIN flashlight_read_model rm ON rm.flashlight_id = f.id
+`
+
+type facetValue = client.FacetValue
+
+// facetDef describes one facet: the filter it owns (cleared before counting),
+// the value it groups by, and how its buckets are ordered.
+type facetDef struct {
+	name    string
+	join    string
+	value   string
+	label   string
+	orderBy string
+	clear   func(*flashlightFilters)
+}
+
+// priceBuckets are [min, max) ranges in USD; a nil max is open-ended.
+var priceBuckets = []struct {
+	value string
+	min   float64
+	max   *float64
+}{
+	{value: "under-25", min: 0, max: ptrFloat(25)},
+	{value: "25-50", min: 25, max: ptrFloat(50)},
+	{value: "50-100", min: 50, max: ptrFloat(100)},
+	{value: "100-200", min: 100, max: ptrFloat(200)},
+	{value: "200-plus", min: 200},
+}
+
+var flashlightFacets = []facetDef{
+	{
+		name: "battery_type",
+		join: `
+JOIN flashlight_battery_compatibility ffbc ON ffbc.flashlight_id = f.id
+JOIN battery_types fbt ON fbt.id = ffbc.battery_type_id`,
+		value: "fbt.code",
+		clear: func(f *flashlightFilters) { f.BatteryTypes = nil },
+	},
+	{
+		name:  "ip_rating",
+		value: "s.waterproof_rating",
+		clear: func(f *flashlightFilters) { f.IPRatings, f.MinIPRating = nil, "" },
+	},
+	{
+		name:  "switch_type",
+		value: "s.switch_type",
+		clear: func(f *flashlightFilters) { f.SwitchTypes = nil },
+	},
+	{
+		name:  "brand",
+		value: "b.slug",
+		label: "b.name::TEXT",
+		clear: func(f *flashlightFilters) { f.Brands = nil },
+	},
+	{
+		name:  "beam_pattern",
+		value: "s.beam_pattern",
+		clear: func(f *flashlightFilters) { f.BeamPatterns = nil },
+	},
+	{
+		name:  "recharge_type",
+		value: "s.recharge_type",
+		clear: func(f *flashlightFilters) { f.RechargeTypes = nil },
+	},
+	{
+		name:    "price_bucket",
+		value:   priceBucketExpr(),
+		orderBy: "MIN(rm.price_usd) ASC",
+		clear:   func(f *flashlightFilters) { delete(f.Ranges, "price") },
+	},
+}
+
+// flashlightFacetCounts counts each facet with every filter applied except
+// the facet's own, so a sidebar can show how many results each choice adds.
+func (s *Server) flashlightFacetCounts(ctx context.Context, f flashlightFilters) (map[string][]facetValue, error) {
+	out := make(map[string][]facetValue, len(flashlightFacets))
+	for _, def := range flashlightFacets {
+		scoped := f.withoutFacet(def)
+		where, args := buildFlashlightWhere(scoped)
+
+		label := def.label
+		if label == "" {
+			label = "NULL::TEXT"
+		}
+		orderBy := def.orderBy
+		if orderBy == "" {
+			orderBy = "COUNT(DISTINCT f.id) DESC, 1 ASC"
+		}
+		query := fmt.Sprintf(`
+SELECT %s AS value, %s AS label, COUNT(DISTINCT f.id)
+%s%s
+%s AND %s IS NOT NULL
+GROUP BY 1, 2
+ORDER BY %s
+`, def.value, label, flashlightFilterFrom, def.join, where, def.value, orderBy)
+
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("facet %s: %w", def.name, err)
+		}
+		values := make([]facetValue, 0, 8)
+		for rows.Next() {
+			var (
+				fv    facetValue
+				label sql.NullString
+			)
+			if err := rows.Scan(&fv.Value, &label, &fv.Count); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("facet %s: %w", def.name, err)
+			}
+			fv.Label = label.String
+			if def.name == "price_bucket" {
+				for _, b := range priceBuckets {
+					if b.value == fv.Value {
+						fv.Min = ptrFloat(b.min)
+						fv.Max = b.max
+					}
+				}
+			}
+			values = append(values, fv)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("facet %s: %w", def.name, err)
+		}
+		out[def.name] = values
+	}
+	return out, nil
+}
+
+// withoutFacet returns a copy of f with the facet's own filter removed.
+func (f flashlightFilters) withoutFacet(def facetDef) flashlightFilters {
+	cp := f
+	cp.Ranges = make(map[string]numberRange, len(f.Ranges))
+	for k, v := range f.Ranges {
+		cp.Ranges[k] = v
+	}
+	def.clear(&cp)
+	return cp
+}
+
+func priceBucketExpr() string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for _, pb := range priceBuckets {
+		if pb.max == nil {
+			fmt.Fprintf(&b, " WHEN rm.price_usd >= %g THEN '%s'", pb.min, pb.value)
+			continue
+		}
+		fmt.Fprintf(&b, " WHEN rm.price_usd < %g THEN '%s'", *pb.max, pb.value)
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+func ptrFloat(v float64) *float64 {
+	return &v
+}
+
+// ipWaterLevel returns the water ingress digit of an IP rating, or -1 when
+// the rating does not declare one (e.g. IP5X).
+func ipWaterLevel(rating string) int {
+	if len(rating) != 4 {
+		return -1
+	}
+	d := rating[3]
+	if d < '0' || d > '9' {
+		return -1
+	}
+	return int(d - '0')
+}
+
+func oneOf(v string, allowed ...string) bool {
+	for _, a := range allowed {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
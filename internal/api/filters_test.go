@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseFlashlightFilters(t *testing.T) {
+	q, _ := url.ParseQuery("min_lumens=1000&max_weight=150&has_strobe=true&usb_c_rechargeable=false&ip_rating=ipx7,IPX8&min_ip_rating=IPX7&switch_type=Tail,side&led_model=SFT40")
+	f, err := parseFlashlightFilters(q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := f.Ranges["lumens"]; r.Min == nil || *r.Min != 1000 || r.Max != nil {
+		t.Fatalf("expected min_lumens=1000, got %+v", r)
+	}
+	if r := f.Ranges["weight"]; r.Max == nil || *r.Max != 150 {
+		t.Fatalf("expected max_weight=150, got %+v", r)
+	}
+	if v, ok := f.Flags["has_strobe"]; !ok || !v {
+		t.Fatalf("expected has_strobe=true")
+	}
+	if v, ok := f.Flags["usb_c_rechargeable"]; !ok || v {
+		t.Fatalf("expected usb_c_rechargeable=false")
+	}
+	if strings.Join(f.IPRatings, ",") != "IPX7,IPX8" {
+		t.Fatalf("unexpected ip ratings %v", f.IPRatings)
+	}
+	if strings.Join(f.SwitchTypes, ",") != "tail,side" {
+		t.Fatalf("unexpected switch types %v", f.SwitchTypes)
+	}
+	if f.Page != 1 || f.PageSize != 20 {
+		t.Fatalf("expected default paging, got page=%d size=%d", f.Page, f.PageSize)
+	}
+}
+
+func TestParseFlashlightFiltersRejectsInvalid(t *testing.T) {
+	for _, raw := range []string{
+		"min_lumens=abc",
+		"min_price=50&max_price=20",
+		"has_lockout=maybe",
+		"ip_rating=IPX9X",
+		"min_ip_rating=IP6X",
+		"switch_type=button",
+	} {
+		q, _ := url.ParseQuery(raw)
+		if _, err := parseFlashlightFilters(q); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestBuildFlashlightWherePlaceholders(t *testing.T) {
+	q, _ := url.ParseQuery("battery_type=21700&min_price=20&max_price=80&has_pocket_clip=true&ip_rating=IPX8,IP68&switch_type=side")
+	f, err := parseFlashlightFilters(q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	where, args := buildFlashlightWhere(f)
+	if len(args) != 7 {
+		t.Fatalf("expected 7 args, got %d: %v", len(args), args)
+	}
+	for _, ph := range []string{"$1", "$2", "$3", "$4", "$5", "$6", "$7"} {
+		if !strings.Contains(where, ph) {
+			t.Fatalf("expected placeholder %s in %s", ph, where)
+		}
+	}
+	if strings.Contains(where, "$8") {
+		t.Fatalf("unexpected extra placeholder in %s", where)
+	}
+}
+
+func TestBuildFlashlightWhereEscapesLEDModel(t *testing.T) {
+	where, args := buildFlashlightWhere(flashlightFilters{LEDModel: `50%_\x`})
+	if !strings.Contains(where, `ESCAPE '\'`) {
+		t.Fatalf("expected an ESCAPE clause in %s", where)
+	}
+	if got := args[len(args)-1]; got != `50\%\_\\x` {
+		t.Fatalf("led_model pattern %q", got)
+	}
+}
+
+func TestWithoutFacetClearsOnlyOwnFilter(t *testing.T) {
+	q, _ := url.ParseQuery("brand=fenix,olight&min_price=20&max_price=80&switch_type=side")
+	f, err := parseFlashlightFilters(q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, def := range flashlightFacets {
+		scoped := f.withoutFacet(def)
+		switch def.name {
+		case "brand":
+			if len(scoped.Brands) != 0 || len(scoped.SwitchTypes) != 1 {
+				t.Fatalf("brand facet should clear only brands, got %+v", scoped)
+			}
+		case "price_bucket":
+			if _, ok := scoped.Ranges["price"]; ok {
+				t.Fatalf("price facet should clear the price range")
+			}
+		}
+	}
+	if _, ok := f.Ranges["price"]; !ok || len(f.Brands) != 2 {
+		t.Fatalf("original filters must not be mutated, got %+v", f)
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"flashlight-ratings-go/internal/telemetry"
+)
+
+// schemaVersion is the highest migration in db/migrations this build needs.
+// Bump it together with each new migration.
+const schemaVersion = 18
+
+// probePaths are hit by load balancers and supervisors every few seconds.
+// They skip rate limiting and are logged at debug level.
+var probePaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// freshnessSLA holds the limits /status checks published data against.
+type freshnessSLA struct {
+	scoringMaxAge   time.Duration
+	syncMaxAge      time.Duration
+	priceStaleAfter time.Duration
+	maxStaleShare   float64
+}
+
+type readyResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+type statusResponse struct {
+	Status     string        `json:"status"`
+	CheckedAt  time.Time     `json:"checked_at"`
+	Problems   []string      `json:"problems,omitempty"`
+	Scoring    scoringStatus `json:"scoring"`
+	AmazonSync syncStatus    `json:"amazon_sync"`
+	Prices     priceStatus   `json:"prices"`
+}
+
+type scoringStatus struct {
+	RunID         *int64     `json:"run_id,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	AgeSeconds    *int64     `json:"age_seconds,omitempty"`
+	MaxAgeSeconds int64      `json:"max_age_seconds"`
+	OK            bool       `json:"ok"`
+}
+
+type syncStatus struct {
+	LastSyncAt    *time.Time `json:"last_sync_at,omitempty"`
+	AgeSeconds    *int64     `json:"age_seconds,omitempty"`
+	MaxAgeSeconds int64      `json:"max_age_seconds"`
+	OK            bool       `json:"ok"`
+}
+
+type priceStatus struct {
+	Listings          int     `json:"listings"`
+	Stale             int     `json:"stale"`
+	StaleShare        float64 `json:"stale_share"`
+	StaleAfterSeconds int64   `json:"stale_after_seconds"`
+	MaxStaleShare     float64 `json:"max_stale_share"`
+	OK                bool    `json:"ok"`
+}
+
+// freshness is what /status reads from the database. Times are compared with
+// the database clock, not the API host's.
+type freshness struct {
+	now            time.Time
+	runID          *int64
+	runCompletedAt *time.Time
+	lastSyncAt     *time.Time
+	listings       int
+	stalePrices    int
+}
+
+// handleHealthz reports that the process is up. It does not touch the
+// database, so a database outage does not get the API restarted.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz reports whether the API can serve traffic: it is not shutting
+// down, the database answers and its schema is at least schemaVersion. It
+// returns 503 otherwise so proxies take the instance out of rotation.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	if s.draining.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, readyResponse{Status: "draining", Checks: map[string]string{}})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	resp := readyResponse{Status: "ready", Checks: map[string]string{}}
+	if err := s.db.PingContext(ctx); err != nil {
+		telemetry.Logger(ctx).Warn("readiness: database unreachable", "err", err)
+		resp.Status = "unavailable"
+		resp.Checks["database"] = "unreachable"
+		resp.Checks["migrations"] = "unknown"
+		writeJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	resp.Checks["database"] = "ok"
+
+	var version int
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	switch {
+	case err != nil:
+		telemetry.Logger(ctx).Warn("readiness: schema version check failed", "err", err)
+		resp.Checks["migrations"] = "schema_migrations unreadable"
+	case version < schemaVersion:
+		resp.Checks["migrations"] = fmt.Sprintf("schema at version %d, need %d", version, schemaVersion)
+	default:
+		resp.Checks["migrations"] = "ok"
+	}
+	if resp.Checks["migrations"] != "ok" {
+		resp.Status = "unavailable"
+		writeJSON(w, http.StatusServiceUnavailable, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleStatus reports how fresh the published data is: the latest completed
+// scoring run, the last Amazon sync and the share of Amazon listings whose
+// price is older than the staleness window. Status is "degraded" when any SLA
+// is breached. The response is 200 either way; the API itself is serving.
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+	if _, ok := readQuery(w, r, noParams); !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	f, err := s.loadFreshness(ctx)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to load status"})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, s.sla.evaluate(f))
+}
+
+func (s *Server) loadFreshness(ctx context.Context) (freshness, error) {
+	var (
+		f           freshness
+		runID       sql.NullInt64
+		completedAt sql.NullTime
+		lastSync    sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx, `
+WITH latest_run AS (
+	SELECT id, completed_at
+	FROM scoring_runs
+	WHERE status = 'completed'
+	ORDER BY completed_at DESC NULLS LAST, id DESC
+	LIMIT 1
+)
+SELECT
+	NOW(),
+	(SELECT id FROM latest_run),
+	(SELECT completed_at FROM latest_run),
+	(SELECT MAX(captured_at) FROM amazon_product_snapshots),
+	COUNT(*),
+	COUNT(*) FILTER (
+		WHERE rm.price_usd_captured_at IS NULL
+		   OR rm.price_usd_captured_at < NOW() - make_interval(secs => $1)
+	)
+FROM flashlights f
+JOIN flashlight_read_model rm ON rm.flashlight_id = f.id
+WHERE f.is_active = TRUE
+  AND rm.offers <> '{}'::JSONB
+`, s.sla.priceStaleAfter.Seconds()).Scan(&f.now, &runID, &completedAt, &lastSync, &f.listings, &f.stalePrices)
+	if err != nil {
+		return f, err
+	}
+	if runID.Valid {
+		f.runID = &runID.Int64
+	}
+	if completedAt.Valid {
+		f.runCompletedAt = &completedAt.Time
+	}
+	if lastSync.Valid {
+		f.lastSyncAt = &lastSync.Time
+	}
+	return f, nil
+}
+
+// evaluate checks f against the SLA. Missing data counts as a breach: a site
+// that has never scored or synced is not fresh.
+func (sla freshnessSLA) evaluate(f freshness) statusResponse {
+	resp := statusResponse{
+		Status:    "ok",
+		CheckedAt: f.now.UTC(),
+		Scoring: scoringStatus{
+			RunID:         f.runID,
+			CompletedAt:   f.runCompletedAt,
+			MaxAgeSeconds: int64(sla.scoringMaxAge.Seconds()),
+		},
+		AmazonSync: syncStatus{
+			LastSyncAt:    f.lastSyncAt,
+			MaxAgeSeconds: int64(sla.syncMaxAge.Seconds()),
+		},
+		Prices: priceStatus{
+			Listings:          f.listings,
+			Stale:             f.stalePrices,
+			StaleAfterSeconds: int64(sla.priceStaleAfter.Seconds()),
+			MaxStaleShare:     sla.maxStaleShare,
+		},
+	}
+
+	switch {
+	case f.runCompletedAt == nil:
+		resp.Problems = append(resp.Problems, "no completed scoring run")
+	default:
+		age := f.now.Sub(*f.runCompletedAt)
+		resp.Scoring.AgeSeconds = ageSeconds(age)
+		resp.Scoring.OK = age <= sla.scoringMaxAge
+		if !resp.Scoring.OK {
+			resp.Problems = append(resp.Problems, fmt.Sprintf("latest scoring run is %s old", age.Round(time.Minute)))
+		}
+	}
+
+	switch {
+	case f.lastSyncAt == nil:
+		resp.Problems = append(resp.Problems, "no Amazon sync recorded")
+	default:
+		age := f.now.Sub(*f.lastSyncAt)
+		resp.AmazonSync.AgeSeconds = ageSeconds(age)
+		resp.AmazonSync.OK = age <= sla.syncMaxAge
+		if !resp.AmazonSync.OK {
+			resp.Problems = append(resp.Problems, fmt.Sprintf("last Amazon sync is %s old", age.Round(time.Minute)))
+		}
+	}
+
+	if f.listings > 0 {
+		resp.Prices.StaleShare = float64(f.stalePrices) / float64(f.listings)
+	}
+	resp.Prices.OK = resp.Prices.StaleShare <= sla.maxStaleShare
+	if !resp.Prices.OK {
+		resp.Problems = append(resp.Problems, fmt.Sprintf("%d of %d listings have stale prices", f.stalePrices, f.listings))
+	}
+
+	if len(resp.Problems) > 0 {
+		resp.Status = "degraded"
+	}
+	return resp
+}
+
+func ageSeconds(d time.Duration) *int64 {
+	secs := int64(max(d, 0).Seconds())
+	return &secs
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFreshnessEvaluate(t *testing.T) {
+	sla := freshnessSLA{
+		scoringMaxAge:   3 * time.Hour,
+		syncMaxAge:      3 * time.Hour,
+		priceStaleAfter: 24 * time.Hour,
+		maxStaleShare:   0.05,
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+	runID := int64(42)
+
+	fresh := freshness{now: now, runID: &runID, runCompletedAt: at(time.Hour), lastSyncAt: at(30 * time.Minute), listings: 100, stalePrices: 5}
+	got := sla.evaluate(fresh)
+	if got.Status != "ok" || len(got.Problems) != 0 || !got.Scoring.OK || !got.AmazonSync.OK || !got.Prices.OK {
+		t.Fatalf("fresh data: %+v", got)
+	}
+	if *got.Scoring.AgeSeconds != 3600 || got.Prices.StaleShare != 0.05 {
+		t.Fatalf("fresh data ages: scoring %d, stale share %v", *got.Scoring.AgeSeconds, got.Prices.StaleShare)
+	}
+
+	stale := fresh
+	stale.lastSyncAt = at(4 * time.Hour)
+	stale.stalePrices = 6
+	got = sla.evaluate(stale)
+	if got.Status != "degraded" || len(got.Problems) != 2 || got.AmazonSync.OK || got.Prices.OK || !got.Scoring.OK {
+		t.Fatalf("stale sync and prices: %+v", got)
+	}
+
+	got = sla.evaluate(freshness{now: now})
+	if got.Status != "degraded" || len(got.Problems) != 2 || got.Scoring.AgeSeconds != nil || !got.Prices.OK {
+		t.Fatalf("empty database: %+v", got)
+	}
+}
+
+func TestHealthzSkipsRateLimit(t *testing.T) {
+	s := &Server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealthz)
+
+	// buckets is nil, so anything reaching the limiter would panic.
+	w := httptest.NewRecorder()
+	s.rateLimit(mux)(mux).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "{\"status\":\"ok\"}\n" {
+		t.Fatalf("healthz: %d %q", w.Code, w.Body.String())
+	}
+}
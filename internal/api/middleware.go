@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"flashlight-ratings-go/internal/telemetry"
+)
+
+// middleware wraps a handler with behaviour shared by every route.
+type middleware func(http.Handler) http.Handler
+
+// chain wraps h in mws so that the first middleware sees the request first.
+func chain(h http.Handler, mws ...middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// recoverPanics turns a panicking handler into a 500 with the usual error
+// body and logs the stack. If the response had already started it cannot be
+// replaced, so the connection is aborted rather than left looking complete.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			telemetry.Logger(r.Context()).Error("panic serving request",
+				"panic", v, "path", r.URL.Path, "stack", string(debug.Stack()))
+			if sw.status != 0 {
+				panic(http.ErrAbortHandler)
+			}
+			writeJSON(sw, http.StatusInternalServerError, apiError{Error: "internal server error"})
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+const (
+	corsAllowMethods = "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowHeaders = "Accept, Authorization, Content-Type, If-None-Match, X-API-Key, X-Request-ID"
+	// corsExposeHeaders lets browser clients read caching, tracing and rate
+	// limit headers.
+	corsExposeHeaders = "ETag, Retry-After, X-Cache, X-Request-ID, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, X-RateLimit-Quota-Limit, X-RateLimit-Quota-Remaining, X-RateLimit-Quota-Reset, Deprecation, Sunset, Link"
+)
+
+// corsPolicy answers cross-origin requests from allowed origins. An entry of
+// "*" allows any origin. Credentials are never allowed: the API
+// authenticates with headers, not cookies.
+type corsPolicy struct {
+	any     bool
+	origins map[string]bool
+}
+
+func newCORSPolicy(origins []string) corsPolicy {
+	p := corsPolicy{origins: make(map[string]bool)}
+	for _, o := range origins {
+		o = strings.TrimRight(strings.TrimSpace(o), "/")
+		switch o {
+		case "":
+		case "*":
+			p.any = true
+		default:
+			p.origins[strings.ToLower(o)] = true
+		}
+	}
+	return p
+}
+
+func (p corsPolicy) enabled() bool {
+	return p.any || len(p.origins) > 0
+}
+
+func (p corsPolicy) allows(origin string) bool {
+	return p.any || p.origins[strings.ToLower(origin)]
+}
+
+// wrap sets the CORS response headers for allowed origins and answers
+// preflight requests itself, so they are neither rate limited nor routed.
+// With an empty allowlist it is a no-op and browsers apply same-origin rules.
+func (p corsPolicy) wrap(next http.Handler) http.Handler {
+	if !p.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if !p.any {
+			w.Header().Add("Vary", "Origin")
+		}
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if origin == "" || !p.allows(origin) {
+			if preflight {
+				writeJSON(w, http.StatusForbidden, apiError{Error: "origin not allowed"})
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if p.any {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if !preflight {
+			w.Header().Set("Access-Control-Expose-Headers", corsExposeHeaders)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method, Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+		w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+		w.Header().Set("Access-Control-Max-Age", "600")
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { order = append(order, "handler") }),
+		mark("outer"), mark("inner"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := strings.Join(order, ","); got != "outer,inner,handler" {
+		t.Fatalf("order %s", got)
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	h := recoverPanics(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/brands", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "{\"error\":\"internal server error\"}\n" {
+		t.Fatalf("recovered response: %d %q", w.Code, w.Body.String())
+	}
+
+	// Once the response has started the connection is aborted instead.
+	h = recoverPanics(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("late boom")
+	}))
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatalf("late panic: recovered %v, want http.ErrAbortHandler", v)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/brands", nil))
+}
+
+func TestCORS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := newCORSPolicy([]string{"https://flashlightratings.com/", " https://staging.flashlightratings.com"}).wrap(ok)
+
+	do := func(method, origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/brands", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if method == http.MethodOptions {
+			r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(http.MethodGet, "https://flashlightratings.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://flashlightratings.com" || w.Header().Get("Vary") != "Origin" {
+		t.Fatalf("allowed origin headers: %v", w.Header())
+	}
+	if w := do(http.MethodGet, "https://evil.example"); w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("other origin: %d %v", w.Code, w.Header())
+	}
+
+	w = do(http.MethodOptions, "https://staging.flashlightratings.com")
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") == "" || !strings.Contains(w.Header().Get("Access-Control-Allow-Headers"), "X-API-Key") {
+		t.Fatalf("preflight: %d %v", w.Code, w.Header())
+	}
+	if w := do(http.MethodOptions, "https://evil.example"); w.Code != http.StatusForbidden {
+		t.Fatalf("disallowed preflight: %d", w.Code)
+	}
+
+	if h := newCORSPolicy(nil).wrap(ok); h == nil {
+		t.Fatal("nil handler")
+	} else {
+		r := httptest.NewRequest(http.MethodGet, "/brands", nil)
+		r.Header.Set("Origin", "https://flashlightratings.com")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if len(w.Header()) != 0 {
+			t.Fatalf("empty allowlist set headers: %v", w.Header())
+		}
+	}
+}
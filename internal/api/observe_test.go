@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"flashlight-ratings-go/internal/telemetry"
+)
+
+func TestObserveRequestID(t *testing.T) {
+	s := &Server{}
+	mux := http.NewServeMux()
+	var seen string
+	mux.HandleFunc("/brands/", func(w http.ResponseWriter, r *http.Request) {
+		seen = telemetry.RequestID(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h := s.observe(mux)(mux)
+
+	r := httptest.NewRequest(http.MethodGet, "/brands/acme", nil)
+	r.Header.Set("X-Request-ID", "edge-42.a")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get("X-Request-ID"); got != "edge-42.a" || seen != got {
+		t.Fatalf("request id header %q, context %q", got, seen)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/brands/acme", nil)
+	r.Header.Set("X-Request-ID", "bad id\nwith newline")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get("X-Request-ID"); len(got) != 24 || got != seen {
+		t.Fatalf("generated request id %q, context %q", got, seen)
+	}
+
+	// Latency is recorded under the route pattern, not the raw path.
+	if !telemetry.HTTPRequestDuration.DeleteLabelValues("/brands/", http.MethodGet, "418") {
+		t.Fatal("no latency series for route /brands/")
+	}
+}
@@ -0,0 +1,342 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// openAPISpec is the encoded OpenAPI document, built once on first use.
+var openAPISpec = sync.OnceValue(func() []byte {
+	b, err := json.Marshal(openAPIDocument())
+	if err != nil {
+		panic("openapi: " + err.Error())
+	}
+	return b
+})
+
+// handleOpenAPI serves the OpenAPI 3 description of the API.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+	if _, ok := readQuery(w, r, noParams); !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", cacheControl)
+	_, _ = w.Write(openAPISpec())
+}
+
+var placeholderRe = regexp.MustCompile(`\{([A-Za-z]+)\}`)
+
+// openAPIDocument describes endpoints. Schemas are derived from the Go types
+// handlers encode, following the same json tags, so they change with them.
+func openAPIDocument() map[string]any {
+	b := newSchemaBuilder()
+	paths := map[string]any{}
+	for _, ep := range endpoints {
+		item, ok := paths[ep.Path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[ep.Path] = item
+		}
+		item[strings.ToLower(ep.Method)] = b.operation(ep)
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Flashlight Ratings API",
+			"version":     strings.TrimPrefix(apiVersion, "/"),
+			"description": "Unversioned paths are deprecated aliases of these and answer with Deprecation and Link headers.",
+		},
+		"servers": []any{map[string]any{"url": apiVersion}},
+		"paths":   paths,
+		// The key is optional: requests without one are rate limited per
+		// client address.
+		"security": []any{map[string]any{}, map[string]any{"apiKey": []any{}}},
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+func (b *schemaBuilder) operation(ep endpoint) map[string]any {
+	op := map[string]any{"operationId": ep.op, "summary": ep.summary}
+
+	params := []any{}
+	for _, m := range placeholderRe.FindAllStringSubmatch(ep.Path, -1) {
+		p, ok := pathParams[m[1]]
+		if !ok {
+			panic("openapi: undeclared path parameter " + m[1])
+		}
+		params = append(params, p.openAPI("path"))
+	}
+	for _, p := range ep.Parameters {
+		params = append(params, p.openAPI("query"))
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	switch {
+	case len(ep.Body) > 0:
+		props := map[string]any{}
+		for _, p := range ep.Body {
+			props[p.Name] = p.schema()
+		}
+		op["requestBody"] = map[string]any{
+			"content": jsonContent("application/json", map[string]any{"type": "object", "properties": props}),
+		}
+	case ep.request != nil:
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  jsonContent("application/json", b.schema(reflect.TypeOf(ep.request))),
+		}
+	}
+
+	status := ep.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	responses := map[string]any{}
+	location := map[string]any{"Location": map[string]any{"schema": map[string]any{"type": "string"}}}
+	content := map[string]any{}
+	if ep.response != nil {
+		content = jsonContent("application/json", b.schema(reflect.TypeOf(ep.response)))
+	}
+	if ep.html {
+		content["text/html"] = map[string]any{"schema": map[string]any{"type": "string"}}
+	}
+	if len(content) > 0 {
+		responses[strconv.Itoa(status)] = map[string]any{"description": http.StatusText(status), "content": content}
+	} else {
+		responses[strconv.Itoa(status)] = map[string]any{"description": http.StatusText(status), "headers": location}
+	}
+	if ep.redirect != nil {
+		responses[strconv.Itoa(http.StatusMovedPermanently)] = map[string]any{
+			"description": "Redirect to the canonical path.",
+			"headers":     location,
+			"content":     jsonContent("application/json", b.schema(reflect.TypeOf(ep.redirect))),
+		}
+	}
+	responses[strconv.Itoa(http.StatusBadRequest)] = map[string]any{
+		"description": "Invalid parameters.",
+		"content":     jsonContent("application/problem+json", b.schema(reflect.TypeOf(problem{}))),
+	}
+	errorStatuses := append([]int{http.StatusTooManyRequests, http.StatusInternalServerError}, ep.errors...)
+	if strings.Contains(ep.Path, "{") {
+		errorStatuses = append(errorStatuses, http.StatusNotFound)
+	}
+	for _, st := range errorStatuses {
+		responses[strconv.Itoa(st)] = map[string]any{
+			"description": http.StatusText(st),
+			"content":     jsonContent("application/json", b.schema(reflect.TypeOf(apiError{}))),
+		}
+	}
+	op["responses"] = responses
+	return op
+}
+
+func jsonContent(mediaType string, schema map[string]any) map[string]any {
+	return map[string]any{mediaType: map[string]any{"schema": schema}}
+}
+
+// openAPI is p as an OpenAPI parameter object. Lists are comma-separated.
+func (p param) openAPI(in string) map[string]any {
+	out := map[string]any{"name": p.Name, "in": in, "schema": p.schema()}
+	if in == "path" || p.Required {
+		out["required"] = true
+	}
+	if p.Type == typeList {
+		out["style"] = "form"
+		out["explode"] = false
+	}
+	return out
+}
+
+func (p param) schema() map[string]any {
+	s := map[string]any{}
+	switch p.Type {
+	case typeInteger, typeNumber, typeBoolean:
+		s["type"] = p.Type
+	case typeDateTime:
+		s["type"] = "string"
+		s["description"] = "RFC 3339 timestamp or YYYY-MM-DD date (midnight UTC)."
+	case typeList:
+		s["type"] = "array"
+		s["items"] = param{Type: typeString, Allowed: p.Allowed}.schema()
+		if p.MaxItems > 0 {
+			s["maxItems"] = p.MaxItems
+		}
+		return s
+	default:
+		s["type"] = "string"
+	}
+	if p.Minimum != nil {
+		s["minimum"] = *p.Minimum
+	}
+	if p.Maximum != nil {
+		s["maximum"] = *p.Maximum
+	}
+	if len(p.Allowed) > 0 {
+		s["enum"] = p.Allowed
+	}
+	if p.MaxLen > 0 {
+		s["maxLength"] = p.MaxLen
+	}
+	if p.Default != "" {
+		s["default"] = p.Default
+		if p.Type == typeInteger || p.Type == typeNumber {
+			n, _ := strconv.ParseFloat(p.Default, 64)
+			s["default"] = n
+		}
+	}
+	return s
+}
+
+// schemaBuilder turns Go types into OpenAPI schemas. Named struct types
+// become components, referenced by their name with the first letter upper
+// cased.
+type schemaBuilder struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{components: map[string]any{}, names: map[reflect.Type]string{}}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := b.schema(t.Elem())
+		if _, ok := s["$ref"]; ok {
+			return map[string]any{"allOf": []any{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		return b.ref(t)
+	}
+	panic("openapi: unsupported type " + t.String())
+}
+
+func (b *schemaBuilder) ref(t reflect.Type) map[string]any {
+	name, ok := b.names[t]
+	if !ok {
+		r, size := utf8.DecodeRuneInString(t.Name())
+		name = string(unicode.ToUpper(r)) + t.Name()[size:]
+		if _, taken := b.components[name]; taken {
+			panic("openapi: two types named " + name)
+		}
+		b.names[t] = name
+		b.components[name] = nil // reserved while the fields are walked
+		b.components[name] = b.object(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// object follows encoding/json: embedded structs without a name tag are
+// flattened into their parent, the shallowest field of a name wins, and
+// fields without omitempty are always present, so they are required.
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	fields := map[string]objectField{}
+	b.fields(t, 0, fields)
+	props := map[string]any{}
+	var required []string
+	for name, f := range fields {
+		props[name] = f.schema
+		if f.required {
+			required = append(required, name)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+type objectField struct {
+	schema   map[string]any
+	required bool
+	depth    int
+}
+
+func (b *schemaBuilder) fields(t reflect.Type, depth int, out map[string]objectField) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.fields(ft, depth+1, out)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if prev, ok := out[name]; ok && prev.depth <= depth {
+			continue
+		}
+		out[name] = objectField{schema: b.schema(f.Type), required: !strings.Contains(opts, "omitempty"), depth: depth}
+	}
+}
@@ -0,0 +1,411 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"flashlight-ratings-go/internal/alerts"
+	"flashlight-ratings-go/internal/apikeys"
+)
+
+// contractServer serves real routes against a database that refuses
+// connections, so every handler runs up to its first query and fails there.
+func contractServer(t *testing.T) http.Handler {
+	t.Helper()
+	db, err := sql.Open("pgx", "postgres://contract@127.0.0.1:1/none?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s := &Server{
+		db:        db,
+		alerts:    alerts.NewSubscriptions(db, alerts.LogSender{}, "http://example.test/v1"),
+		cache:     newResponseCache(func(context.Context) (dataStamp, error) { return dataStamp{}, errors.New("no database") }),
+		buckets:   apikeys.NewBuckets(),
+		anonRate:  1000,
+		anonBurst: 1000,
+	}
+	return s.Routes()
+}
+
+func loadSpec(t *testing.T, h http.Handler) map[string]any {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("openapi.json: status %d", w.Code)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// TestHandlersMatchSpec sends each documented operation a valid request and
+// one with an unknown parameter. Every answer must use a documented status,
+// and its body must match the schema documented for it.
+func TestHandlersMatchSpec(t *testing.T) {
+	h := contractServer(t)
+	doc := loadSpec(t, h)
+	paths := doc["paths"].(map[string]any)
+	if len(paths) != len(uniquePaths()) {
+		t.Fatalf("spec has %d paths, endpoints %d", len(paths), len(uniquePaths()))
+	}
+
+	for path, item := range paths {
+		for method, raw := range item.(map[string]any) {
+			op := raw.(map[string]any)
+			name := strings.ToUpper(method) + " " + path
+
+			target, body := exampleRequest(doc, path, op)
+			w := serve(h, strings.ToUpper(method), target, body)
+			if w.Code == http.StatusBadRequest {
+				t.Errorf("%s: valid request rejected: %s", name, w.Body.String())
+			}
+			checkResponse(t, doc, name, op, w)
+
+			bogus := target + "&bogus=1"
+			if !strings.Contains(target, "?") {
+				bogus = target + "?bogus=1"
+			}
+			if path == "/go/{id}" {
+				continue // affiliate links carry arbitrary tracking parameters
+			}
+			w = serve(h, strings.ToUpper(method), bogus, body)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s: unknown parameter: status %d", name, w.Code)
+			}
+			checkResponse(t, doc, name, op, w)
+		}
+	}
+}
+
+// TestHandlersSucceedAgainstFixtures sends each documented operation a
+// valid request against fixture data. Every one must answer with its
+// success status and a body matching the schema documented for it.
+func TestHandlersSucceedAgainstFixtures(t *testing.T) {
+	db := openFakeDB(t, contractFixtures()...)
+	s := &Server{
+		db:        db,
+		alerts:    alerts.NewSubscriptions(db, alerts.LogSender{}, "http://example.test/v1"),
+		buckets:   apikeys.NewBuckets(),
+		anonRate:  1000,
+		anonBurst: 1000,
+	}
+	s.cache = newResponseCache(s.loadDataStamp)
+	h := s.Routes()
+	doc := loadSpec(t, h)
+	paths := doc["paths"].(map[string]any)
+
+	for _, ep := range endpoints {
+		name := ep.Method + " " + ep.Path
+		op := paths[ep.Path].(map[string]any)[strings.ToLower(ep.Method)].(map[string]any)
+		target, body := exampleRequest(doc, ep.Path, op)
+		w := serve(h, ep.Method, target, body)
+		want := ep.status
+		if want == 0 {
+			want = http.StatusOK
+		}
+		if w.Code != want {
+			t.Errorf("%s: status %d, want %d: %s", name, w.Code, want, w.Body.String())
+			continue
+		}
+		checkResponse(t, doc, name, op, w)
+	}
+
+	// Retired slugs and pairs in the wrong order redirect to the canonical path.
+	for path, target := range map[string]string{
+		"/flashlights/by-slug/{slug}": "/v1/flashlights/by-slug/acme-old",
+		"/versus/{slugA}/{slugB}":     "/v1/versus/acme-two/acme-one",
+	} {
+		w := serve(h, http.MethodGet, target, nil)
+		if w.Code != http.StatusMovedPermanently {
+			t.Errorf("GET %s: status %d, want 301: %s", target, w.Code, w.Body.String())
+			continue
+		}
+		checkResponse(t, doc, "GET "+path, paths[path].(map[string]any)["get"].(map[string]any), w)
+	}
+}
+
+func uniquePaths() map[string]bool {
+	out := map[string]bool{}
+	for _, ep := range endpoints {
+		out[ep.Path] = true
+	}
+	return out
+}
+
+func serve(h http.Handler, method, target string, body []byte) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, bytes.NewReader(body))
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func checkResponse(t *testing.T, doc map[string]any, name string, op map[string]any, w *httptest.ResponseRecorder) {
+	t.Helper()
+	resp, ok := op["responses"].(map[string]any)[strconv.Itoa(w.Code)].(map[string]any)
+	if !ok {
+		t.Errorf("%s: status %d is not documented: %s", name, w.Code, w.Body.String())
+		return
+	}
+	content, _ := resp["content"].(map[string]any)
+	if content == nil {
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	media, ok := content[mediaType].(map[string]any)
+	if !ok {
+		t.Errorf("%s: status %d content type %q is not documented", name, w.Code, mediaType)
+		return
+	}
+	if !strings.HasSuffix(mediaType, "json") {
+		return
+	}
+	var v any
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	if err := validate(doc, media["schema"].(map[string]any), v, "body"); err != nil {
+		t.Errorf("%s: status %d: %v", name, w.Code, err)
+	}
+}
+
+// exampleRequest fills path and query parameters with valid values, and the
+// body with one built from its schema.
+func exampleRequest(doc map[string]any, path string, op map[string]any) (string, []byte) {
+	target := apiVersion + path
+	q := url.Values{}
+	params, _ := op["parameters"].([]any)
+	for _, raw := range params {
+		p := raw.(map[string]any)
+		if p["name"] == "cursor" {
+			continue // replaces page, which the example sets
+		}
+		v := exampleValue(doc, p["schema"].(map[string]any), p["name"].(string))
+		s := fmt.Sprint(v)
+		if items, ok := v.([]any); ok {
+			s = fmt.Sprint(items[0])
+		}
+		if p["in"] == "path" {
+			target = strings.Replace(target, "{"+p["name"].(string)+"}", s, 1)
+			continue
+		}
+		q.Set(p["name"].(string), s)
+	}
+	if len(q) > 0 {
+		target += "?" + q.Encode()
+	}
+
+	rb, ok := op["requestBody"].(map[string]any)
+	if !ok {
+		return target, nil
+	}
+	schema := rb["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	body, _ := json.Marshal(exampleValue(doc, schema, ""))
+	return target, body
+}
+
+// exampleNames are values for fields whose validation goes beyond their
+// schema.
+var exampleNames = map[string]any{
+	"email":    "rider@example.com",
+	"currency": "USD",
+	"slug":     "acme-one",
+	"slugA":    "acme-one",
+	"slugB":    "acme-two",
+	"q":        "acme",
+	"ids":      "1,2",
+	"token":    "tok",
+	"from":     "2026-01-01",
+	"to":       "2026-02-01",
+
+	"ip_rating":     "IPX8",
+	"min_ip_rating": "IPX7",
+}
+
+func exampleValue(doc map[string]any, schema map[string]any, name string) any {
+	if v, ok := exampleNames[name]; ok {
+		return v
+	}
+	schema = resolve(doc, schema)
+	if v, ok := schema["default"]; ok {
+		return v
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		return enum[0]
+	}
+	switch schema["type"] {
+	case "object":
+		out := map[string]any{}
+		props, _ := schema["properties"].(map[string]any)
+		for k, ps := range props {
+			out[k] = exampleValue(doc, ps.(map[string]any), k)
+		}
+		return out
+	case "array":
+		return []any{exampleValue(doc, schema["items"].(map[string]any), name)}
+	case "integer", "number":
+		if min, ok := schema["minimum"].(float64); ok {
+			return math.Max(min, 1)
+		}
+		return 1
+	case "boolean":
+		return true
+	}
+	if _, ok := schema["description"]; ok {
+		return "2026-01-01" // date-time parameters
+	}
+	return "x"
+}
+
+func resolve(doc, schema map[string]any) map[string]any {
+	for {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		schema = doc["components"].(map[string]any)["schemas"].(map[string]any)[name].(map[string]any)
+	}
+}
+
+// validate checks v against the subset of JSON Schema the generator emits.
+// Objects may not carry properties their schema does not list, so fields
+// added to a response type without reaching the spec are caught.
+func validate(doc, schema map[string]any, v any, at string) error {
+	schema = resolve(doc, schema)
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, s := range all {
+			if v == nil && schema["nullable"] == true {
+				return nil
+			}
+			if err := validate(doc, s.(map[string]any), v, at); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if v == nil {
+		if schema["nullable"] == true || schema["type"] == nil {
+			return nil
+		}
+		return fmt.Errorf("%s: null where %v expected", at, schema["type"])
+	}
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: %T, want object", at, v)
+		}
+		required, _ := schema["required"].([]any)
+		for _, r := range required {
+			if _, ok := obj[r.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %s", at, r)
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		extra, _ := schema["additionalProperties"].(map[string]any)
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ps, ok := props[k].(map[string]any)
+			if !ok {
+				ps = extra
+			}
+			if ps == nil {
+				return fmt.Errorf("%s: undocumented property %s", at, k)
+			}
+			if err := validate(doc, ps, obj[k], at+"."+k); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: %T, want array", at, v)
+		}
+		for i, item := range arr {
+			if err := validate(doc, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: %T, want string", at, v)
+		}
+		if enum, ok := schema["enum"].([]any); ok {
+			for _, e := range enum {
+				if e == s {
+					return nil
+				}
+			}
+			return fmt.Errorf("%s: %q not in %v", at, s, enum)
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: %v, want integer", at, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: %T, want number", at, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: %T, want boolean", at, v)
+		}
+	}
+	return nil
+}
+
+func TestLegacyPathsAreDeprecatedAliases(t *testing.T) {
+	h := contractServer(t)
+
+	w := serve(h, http.MethodGet, "/parameters", nil)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Deprecation"), "@") || w.Header().Get("Link") != `</v1/parameters>; rel="successor-version"` {
+		t.Fatalf("legacy: %d %v", w.Code, w.Header())
+	}
+	w = serve(h, http.MethodGet, "/v1/parameters", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "" {
+		t.Fatalf("v1: %d %v", w.Code, w.Header())
+	}
+	if w := serve(h, http.MethodGet, "/v1", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("/v1: %d", w.Code)
+	}
+	if w := serve(h, http.MethodGet, "/v10/parameters", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("/v10: %d", w.Code)
+	}
+	if w := serve(h, http.MethodGet, "/healthz", nil); w.Header().Get("Deprecation") != "" {
+		t.Fatal("probe marked deprecated")
+	}
+
+	w = serve(h, http.MethodGet, "/v1/rankings?use_case=nope", nil)
+	var p problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil || p.Instance != "/v1/rankings" {
+		t.Fatalf("problem instance %q, %v", p.Instance, err)
+	}
+}
@@ -0,0 +1,413 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashlight-ratings-go/internal/amazon"
+	"flashlight-ratings-go/pkg/client"
+)
+
+// param declares one query parameter an endpoint accepts. The exported
+// fields are what /parameters publishes; norm and check refine validation.
+type param struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Default  string   `json:"default,omitempty"`
+	Minimum  *float64 `json:"minimum,omitempty"`
+	Maximum  *float64 `json:"maximum,omitempty"`
+	Allowed  []string `json:"allowed,omitempty"`
+	MaxItems int      `json:"max_items,omitempty"`
+	MaxLen   int      `json:"max_length,omitempty"`
+	Required bool     `json:"required,omitempty"`
+
+	// norm canonicalises a value (or each list item) before it is checked.
+	norm func(string) string
+	// check validates a normalised value and may rewrite it.
+	check func(string) (string, error)
+}
+
+const (
+	typeString   = "string"
+	typeInteger  = "integer"
+	typeNumber   = "number"
+	typeBoolean  = "boolean"
+	typeDateTime = "date-time"
+	// typeList is a comma-separated list of strings.
+	typeList = "list"
+)
+
+func intParam(name string, def, min, max int) param {
+	lo, hi := float64(min), float64(max)
+	return param{Name: name, Type: typeInteger, Default: strconv.Itoa(def), Minimum: &lo, Maximum: &hi}
+}
+
+func numberParam(name string, min float64) param {
+	return param{Name: name, Type: typeNumber, Minimum: &min}
+}
+
+func boolParam(name string) param {
+	return param{Name: name, Type: typeBoolean}
+}
+
+func stringParam(name string, maxLen int) param {
+	return param{Name: name, Type: typeString, MaxLen: maxLen}
+}
+
+func timeParam(name string) param {
+	return param{Name: name, Type: typeDateTime}
+}
+
+// enumParam accepts one of allowed, case-insensitively.
+func enumParam(name, def string, allowed ...string) param {
+	return param{Name: name, Type: typeString, Default: def, Allowed: allowed, norm: strings.ToLower}
+}
+
+// listParam accepts up to maxListValues comma-separated values, each one of
+// allowed when allowed is given.
+func listParam(name string, norm func(string) string, allowed ...string) param {
+	return param{Name: name, Type: typeList, Allowed: allowed, MaxItems: maxListValues, norm: norm}
+}
+
+// regionCodes are the Amazon marketplaces a region parameter may name.
+var regionCodes = []string{"US", "CA", "UK", "DE", "FR", "IT", "ES", "JP", "IN"}
+
+// regionParam is accepted by every endpoint that returns offers. GB is
+// accepted as an alias for UK.
+var regionParam = param{
+	Name:    "region",
+	Type:    typeString,
+	Allowed: regionCodes,
+	check: func(v string) (string, error) {
+		code, ok := amazon.NormalizeRegion(v)
+		if !ok {
+			return "", errNotAllowed
+		}
+		return code, nil
+	},
+}
+
+// noParams is for endpoints that take no query parameters at all.
+var noParams = []param{}
+
+// Bounds shared by parameters declared without a constructor.
+var zero, minID = 0.0, 1.0
+
+var errNotAllowed = errors.New("must be one of the allowed values")
+
+type fieldError = client.FieldError
+
+// validationError collects every problem with a request so clients can fix
+// them in one round trip.
+type validationError struct {
+	Errors []fieldError
+}
+
+func (e *validationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *validationError) add(field, value, message string, allowed ...string) {
+	e.Errors = append(e.Errors, fieldError{Field: field, Value: value, Message: message, Allowed: allowed})
+}
+
+// err returns e, or nil when nothing was invalid.
+func (e *validationError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// invalidField is a validationError for a single field.
+func invalidField(field, value, message string, allowed ...string) error {
+	e := &validationError{}
+	e.add(field, value, message, allowed...)
+	return e
+}
+
+// query holds validated, normalised query parameters. Accessors return the
+// declared default for parameters that were absent or invalid.
+type query struct {
+	params map[string]param
+	values map[string]string
+	lists  map[string][]string
+}
+
+// parseQuery checks v against params. Unknown and repeated parameters and
+// values that do not fit their declared type, range or allowed values are
+// all reported in the returned error, as are missing required parameters.
+// The error is never nil; call err on it. Empty values count as absent.
+func parseQuery(v url.Values, params []param) (query, *validationError) {
+	q := query{
+		params: make(map[string]param, len(params)),
+		values: map[string]string{},
+		lists:  map[string][]string{},
+	}
+	for _, p := range params {
+		q.params[p.Name] = p
+	}
+	verr := &validationError{}
+
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p, ok := q.params[name]
+		if !ok {
+			verr.add(name, "", "unknown parameter", paramNames(params)...)
+			continue
+		}
+		if len(v[name]) > 1 {
+			verr.add(name, "", "must be given at most once")
+			continue
+		}
+		raw := strings.TrimSpace(v[name][0])
+		if raw == "" {
+			continue
+		}
+		if p.Type == typeList {
+			if items, ok := p.parseList(raw, verr); ok {
+				q.lists[name] = items
+			}
+			continue
+		}
+		if value, ok := p.parseValue(raw, verr); ok {
+			q.values[name] = value
+		}
+	}
+	for _, p := range params {
+		// A repeated parameter has already been reported.
+		if p.Required && len(v[p.Name]) <= 1 && strings.TrimSpace(v.Get(p.Name)) == "" {
+			verr.add(p.Name, "", "is required")
+		}
+	}
+	return q, verr
+}
+
+// readQuery parses r's query against params. When it does not fit, it
+// answers 400 and returns false.
+func readQuery(w http.ResponseWriter, r *http.Request, params []param) (query, bool) {
+	q, verr := parseQuery(r.URL.Query(), params)
+	if err := verr.err(); err != nil {
+		writeBadRequest(w, r, err)
+		return q, false
+	}
+	return q, true
+}
+
+func (p param) parseValue(raw string, verr *validationError) (string, bool) {
+	fail := func(message string, allowed ...string) (string, bool) {
+		verr.add(p.Name, raw, message, allowed...)
+		return "", false
+	}
+	v := raw
+	if p.norm != nil {
+		v = p.norm(v)
+	}
+
+	switch p.Type {
+	case typeInteger:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fail("must be an integer")
+		}
+		if !p.inRange(float64(n)) {
+			return fail(p.rangeMessage())
+		}
+	case typeNumber:
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return fail("must be a number")
+		}
+		if !p.inRange(n) {
+			return fail(p.rangeMessage())
+		}
+	case typeBoolean:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fail("must be true or false")
+		}
+		v = strconv.FormatBool(b)
+	case typeDateTime:
+		if _, err := parseTimeParam(v); err != nil {
+			return fail("must be an RFC 3339 timestamp or YYYY-MM-DD date")
+		}
+	default:
+		if p.MaxLen > 0 && len(v) > p.MaxLen {
+			return fail(fmt.Sprintf("must be at most %d characters", p.MaxLen))
+		}
+	}
+
+	if p.check != nil {
+		checked, err := p.check(v)
+		if err != nil {
+			return fail(err.Error(), p.Allowed...)
+		}
+		return checked, true
+	}
+	if len(p.Allowed) > 0 {
+		for _, a := range p.Allowed {
+			if strings.EqualFold(v, a) {
+				return a, true
+			}
+		}
+		return fail("must be one of the allowed values", p.Allowed...)
+	}
+	return v, true
+}
+
+func (p param) parseList(raw string, verr *validationError) ([]string, bool) {
+	parts := strings.Split(raw, ",")
+	if p.MaxItems > 0 && len(parts) > p.MaxItems {
+		verr.add(p.Name, raw, fmt.Sprintf("must have at most %d values", p.MaxItems))
+		return nil, false
+	}
+	item := p
+	item.Type = typeString
+	out := make([]string, 0, len(parts))
+	seen := make(map[string]bool, len(parts))
+	ok := true
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, valid := item.parseValue(part, verr)
+		if !valid {
+			ok = false
+			continue
+		}
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out, ok
+}
+
+func (p param) inRange(n float64) bool {
+	return (p.Minimum == nil || n >= *p.Minimum) && (p.Maximum == nil || n <= *p.Maximum)
+}
+
+func (p param) rangeMessage() string {
+	switch {
+	case p.Minimum != nil && p.Maximum != nil:
+		return fmt.Sprintf("must be between %g and %g", *p.Minimum, *p.Maximum)
+	case p.Minimum != nil:
+		return fmt.Sprintf("must be at least %g", *p.Minimum)
+	default:
+		return fmt.Sprintf("must be at most %g", *p.Maximum)
+	}
+}
+
+func paramNames(params []param) []string {
+	names := make([]string, len(params))
+	for i, p := range params {
+		names[i] = p.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (q query) has(name string) bool {
+	_, ok := q.values[name]
+	return ok
+}
+
+// str returns the normalised value of name, or its default.
+func (q query) str(name string) string {
+	if v, ok := q.values[name]; ok {
+		return v
+	}
+	return q.params[name].Default
+}
+
+func (q query) int(name string) int {
+	n, _ := strconv.Atoi(q.str(name))
+	return n
+}
+
+func (q query) int64(name string) *int64 {
+	if !q.has(name) {
+		return nil
+	}
+	n, _ := strconv.ParseInt(q.values[name], 10, 64)
+	return &n
+}
+
+func (q query) float(name string) *float64 {
+	if !q.has(name) {
+		return nil
+	}
+	n, _ := strconv.ParseFloat(q.values[name], 64)
+	return &n
+}
+
+func (q query) boolean(name string) *bool {
+	if !q.has(name) {
+		return nil
+	}
+	b := q.values[name] == "true"
+	return &b
+}
+
+func (q query) time(name string) (time.Time, bool) {
+	if !q.has(name) {
+		return time.Time{}, false
+	}
+	t, _ := parseTimeParam(q.values[name])
+	return t, true
+}
+
+func (q query) list(name string) []string {
+	return q.lists[name]
+}
+
+// problem is an RFC 7807 problem details body.
+type problem = client.Problem
+
+// writeBadRequest answers err with a 400 application/problem+json response,
+// listing field errors when err is a validationError.
+func writeBadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	p := problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(http.StatusBadRequest),
+		Status:   http.StatusBadRequest,
+		Detail:   err.Error(),
+		Instance: versionPrefix(r.Context()) + r.URL.Path,
+	}
+	var verr *validationError
+	if errors.As(err, &verr) {
+		p.Errors = verr.Errors
+		if len(verr.Errors) == 1 {
+			p.Detail = "invalid parameter " + verr.Errors[0].Field + ": " + verr.Errors[0].Message
+		} else {
+			p.Detail = fmt.Sprintf("%d invalid parameters", len(verr.Errors))
+		}
+	}
+	writeProblem(w, p)
+}
+
+func writeProblem(w http.ResponseWriter, p problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(true)
+	_ = enc.Encode(p)
+}
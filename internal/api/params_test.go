@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"flashlight-ratings-go/internal/apikeys"
+)
+
+func TestParseQuery(t *testing.T) {
+	params := []param{
+		intParam("page", 1, 1, 100),
+		enumParam("order", "desc", "asc", "desc"),
+		listParam("switch_type", strings.ToLower, "tail", "side"),
+		boolParam("facets"),
+		regionParam,
+	}
+
+	v, _ := url.ParseQuery("page=3&order=ASC&switch_type=Tail,side,tail&facets=1&region=gb")
+	q, verr := parseQuery(v, params)
+	if err := verr.err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.int("page") != 3 || q.str("order") != "asc" || q.str("region") != "UK" || !*q.boolean("facets") {
+		t.Fatalf("unexpected values %+v", q.values)
+	}
+	if got := strings.Join(q.list("switch_type"), ","); got != "tail,side" {
+		t.Fatalf("switch_type = %s", got)
+	}
+
+	q, _ = parseQuery(url.Values{"page": {""}}, params)
+	if q.int("page") != 1 || q.str("order") != "desc" || q.boolean("facets") != nil {
+		t.Fatalf("defaults not applied: %+v", q.values)
+	}
+
+	v, _ = url.ParseQuery("page=abc&page_size=5&order=up&switch_type=tail,twisty&facets=maybe&region=XX&order=asc")
+	_, verr = parseQuery(v, params)
+	got := map[string]fieldError{}
+	for _, fe := range verr.Errors {
+		got[fe.Field] = fe
+	}
+	for field, msg := range map[string]string{
+		"page":        "must be an integer",
+		"page_size":   "unknown parameter",
+		"order":       "must be given at most once",
+		"switch_type": "must be one of the allowed values",
+		"facets":      "must be true or false",
+		"region":      "must be one of the allowed values",
+	} {
+		if got[field].Message != msg {
+			t.Errorf("%s: message %q, want %q", field, got[field].Message, msg)
+		}
+	}
+	if len(verr.Errors) != 6 {
+		t.Fatalf("errors: %+v", verr.Errors)
+	}
+	if a := got["page_size"].Allowed; strings.Join(a, ",") != "facets,order,page,region,switch_type" {
+		t.Fatalf("unknown parameter lists %v", a)
+	}
+	if a := got["region"].Allowed; len(a) != len(regionCodes) {
+		t.Fatalf("region lists %v", a)
+	}
+
+	_, verr = parseQuery(url.Values{"page": {"101"}}, params)
+	if verr.err() == nil || verr.Errors[0].Message != "must be between 1 and 100" {
+		t.Fatalf("range: %+v", verr.Errors)
+	}
+}
+
+func TestWriteBadRequest(t *testing.T) {
+	w := httptest.NewRecorder()
+	verr := &validationError{}
+	verr.add("sort_by", "speed", "must be one of the allowed values", "price", "tactical_score")
+	writeBadRequest(w, httptest.NewRequest(http.MethodGet, "/flashlights?sort_by=speed", nil), verr)
+
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var p problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != "about:blank" || p.Title != "Bad Request" || p.Status != http.StatusBadRequest || p.Instance != "/flashlights" {
+		t.Fatalf("problem %+v", p)
+	}
+	if p.Detail != "invalid parameter sort_by: must be one of the allowed values" || len(p.Errors) != 1 || len(p.Errors[0].Allowed) != 2 {
+		t.Fatalf("problem %+v", p)
+	}
+
+	w = httptest.NewRecorder()
+	writeBadRequest(w, httptest.NewRequest(http.MethodPost, "/alerts", nil), errors.New("invalid json body"))
+	var plain problem
+	if err := json.Unmarshal(w.Body.Bytes(), &plain); err != nil || plain.Detail != "invalid json body" || len(plain.Errors) != 0 {
+		t.Fatalf("plain error problem %+v, %v", plain, err)
+	}
+}
+
+func TestDecodeIntelligenceRequest(t *testing.T) {
+	decode := func(body string) (intelligenceRunRequest, error) {
+		return decodeIntelligenceRequest(httptest.NewRequest(http.MethodPost, "/intelligence/runs", strings.NewReader(body)))
+	}
+
+	req, err := decode(`{"intended_use": "Camping", "battery_preference": "18650"}`)
+	if err != nil || req.IntendedUse != "camping" || req.BudgetUSD != 80 || req.SizeConstraint != "any" {
+		t.Fatalf("unexpected result %+v, %v", req, err)
+	}
+	for _, body := range []string{
+		`{"intended_use": "spelunking"}`,
+		`{"budget_usd": -5}`,
+		`{"size_constraint": "huge"}`,
+		`{"intendeduse": "edc"}`,
+		`not json`,
+	} {
+		if _, err := decode(body); err == nil {
+			t.Errorf("expected error for %s", body)
+		}
+	}
+}
+
+// TestEndpointsRejectUnknownParameters checks every route /parameters
+// publishes answers an unknown parameter with problem+json before touching
+// the database.
+func TestEndpointsRejectUnknownParameters(t *testing.T) {
+	s := &Server{
+		cache:     newResponseCache(func(context.Context) (dataStamp, error) { return dataStamp{}, errors.New("no database") }),
+		buckets:   apikeys.NewBuckets(),
+		anonRate:  1000,
+		anonBurst: 1000,
+	}
+	h := s.Routes()
+	placeholders := strings.NewReplacer("{id}", "1", "{action}", "confirm", "{slug}", "acme-one", "{slugA}", "acme-one", "{slugB}", "acme-two")
+
+	for _, ep := range endpoints {
+		if ep.Path == "/go/{id}" {
+			continue // affiliate links carry arbitrary tracking parameters
+		}
+		r := httptest.NewRequest(ep.Method, placeholders.Replace(ep.Path)+"?bogus=1", strings.NewReader("{}"))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" {
+			t.Errorf("%s %s: status %d, content type %q", ep.Method, ep.Path, w.Code, w.Header().Get("Content-Type"))
+			continue
+		}
+		var p problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil || !slices.ContainsFunc(p.Errors, func(e fieldError) bool { return e.Field == "bogus" }) {
+			t.Errorf("%s %s: problem %s", ep.Method, ep.Path, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// priceIntervals maps the interval parameter to date_trunc fields, along with
+// the widest range each interval may cover so a single request stays small.
+var priceIntervals = map[string]time.Duration{
+	"hour":  31 * 24 * time.Hour,
+	"day":   3 * 366 * 24 * time.Hour,
+	"week":  10 * 366 * 24 * time.Hour,
+	"month": 10 * 366 * 24 * time.Hour,
+}
+
+var priceLowWindows = []int{30, 90, 365}
+
+type priceHistoryResponse struct {
+	FlashlightID int64         `json:"flashlight_id"`
+	From         string        `json:"from"`
+	To           string        `json:"to"`
+	Interval     string        `json:"interval"`
+	Series       []priceSeries `json:"series"`
+}
+
+type priceSeries struct {
+	Source    string       `json:"source"`
+	Currency  string       `json:"currency"`
+	Current   *float64     `json:"current,omitempty"`
+	CurrentAt *string      `json:"current_at,omitempty"`
+	Lows      []priceLow   `json:"lows"`
+	Points    []pricePoint `json:"points"`
+}
+
+// priceLow is the lowest price seen in the trailing window, and how far the
+// current price sits above it.
+type priceLow struct {
+	Window          string   `json:"window"`
+	Price           *float64 `json:"price,omitempty"`
+	CurrentVsLowPct *float64 `json:"current_vs_low_pct,omitempty"`
+}
+
+type pricePoint struct {
+	Bucket  string  `json:"bucket"`
+	Open    float64 `json:"open"`
+	High    float64 `json:"high"`
+	Low     float64 `json:"low"`
+	Close   float64 `json:"close"`
+	Median  float64 `json:"median"`
+	Samples int     `json:"samples"`
+}
+
+type priceHistoryQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+}
+
+// priceParams are the query parameters /flashlights/{id}/prices accepts.
+var priceParams = []param{
+	enumParam("interval", "day", "hour", "day", "week", "month"),
+	timeParam("from"),
+	timeParam("to"),
+}
+
+func (s *Server) handleFlashlightPrices(w http.ResponseWriter, r *http.Request, id int64) {
+	q, err := parsePriceHistoryQuery(r.URL.Query(), time.Now().UTC())
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	resp, err := s.priceHistory(ctx, id, q)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, apiError{Error: "flashlight not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch price history"})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func parsePriceHistoryQuery(v url.Values, now time.Time) (priceHistoryQuery, error) {
+	params, verr := parseQuery(v, priceParams)
+	if err := verr.err(); err != nil {
+		return priceHistoryQuery{}, err
+	}
+	q := priceHistoryQuery{To: now, Interval: params.str("interval")}
+	if to, ok := params.time("to"); ok {
+		q.To = to
+	}
+	q.From = q.To.AddDate(0, 0, -90)
+	if from, ok := params.time("from"); ok {
+		q.From = from
+	}
+	if !q.From.Before(q.To) {
+		return q, invalidField("from", v.Get("from"), "must be before to")
+	}
+	if q.To.Sub(q.From) > priceIntervals[q.Interval] {
+		return q, invalidField("from", v.Get("from"), fmt.Sprintf("range is too large for interval %s", q.Interval))
+	}
+	return q, nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps or plain YYYY-MM-DD dates, which
+// are read as midnight UTC.
+func parseTimeParam(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 or YYYY-MM-DD")
+	}
+	return t, nil
+}
+
+func (s *Server) priceHistory(ctx context.Context, id int64, q priceHistoryQuery) (priceHistoryResponse, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM flashlights WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return priceHistoryResponse{}, err
+	}
+	if !exists {
+		return priceHistoryResponse{}, sql.ErrNoRows
+	}
+
+	resp := priceHistoryResponse{
+		FlashlightID: id,
+		From:         q.From.Format(time.RFC3339),
+		To:           q.To.Format(time.RFC3339),
+		Interval:     q.Interval,
+		Series:       []priceSeries{},
+	}
+	index := make(map[string]int)
+	seriesFor := func(source, currency string) *priceSeries {
+		key := source + "/" + currency
+		i, ok := index[key]
+		if !ok {
+			i = len(resp.Series)
+			index[key] = i
+			resp.Series = append(resp.Series, priceSeries{
+				Source:   source,
+				Currency: currency,
+				Lows:     []priceLow{},
+				Points:   []pricePoint{},
+			})
+		}
+		return &resp.Series[i]
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT
+	p.source,
+	p.currency_code,
+	date_trunc($4, p.captured_at AT TIME ZONE 'UTC') AS bucket,
+	((array_agg(p.price ORDER BY p.captured_at ASC))[1])::FLOAT8 AS open,
+	MAX(p.price)::FLOAT8 AS high,
+	MIN(p.price)::FLOAT8 AS low,
+	((array_agg(p.price ORDER BY p.captured_at DESC))[1])::FLOAT8 AS close,
+	percentile_cont(0.5) WITHIN GROUP (ORDER BY p.price) AS median,
+	COUNT(*)
+FROM flashlight_price_snapshots p
+WHERE p.flashlight_id = $1
+  AND p.captured_at >= $2
+  AND p.captured_at < $3
+GROUP BY p.source, p.currency_code, bucket
+ORDER BY p.source, p.currency_code, bucket
+`, id, q.From, q.To, q.Interval)
+	if err != nil {
+		return priceHistoryResponse{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			source, currency string
+			bucket           time.Time
+			pt               pricePoint
+		)
+		if err := rows.Scan(&source, &currency, &bucket, &pt.Open, &pt.High, &pt.Low, &pt.Close, &pt.Median, &pt.Samples); err != nil {
+			return priceHistoryResponse{}, err
+		}
+		pt.Bucket = bucket.Format(time.RFC3339)
+		pt.Median = round2(pt.Median)
+		series := seriesFor(source, currency)
+		series.Points = append(series.Points, pt)
+	}
+	if err := rows.Err(); err != nil {
+		return priceHistoryResponse{}, err
+	}
+
+	lowRows, err := s.db.QueryContext(ctx, `
+SELECT
+	p.source,
+	p.currency_code,
+	((array_agg(p.price ORDER BY p.captured_at DESC))[1])::FLOAT8 AS current,
+	MAX(p.captured_at) AS current_at,
+	(MIN(p.price) FILTER (WHERE p.captured_at >= NOW() - INTERVAL '30 days'))::FLOAT8,
+	(MIN(p.price) FILTER (WHERE p.captured_at >= NOW() - INTERVAL '90 days'))::FLOAT8,
+	MIN(p.price)::FLOAT8
+FROM flashlight_price_snapshots p
+WHERE p.flashlight_id = $1
+  AND p.captured_at >= NOW() - INTERVAL '365 days'
+GROUP BY p.source, p.currency_code
+ORDER BY p.source, p.currency_code
+`, id)
+	if err != nil {
+		return priceHistoryResponse{}, err
+	}
+	defer lowRows.Close()
+
+	for lowRows.Next() {
+		var (
+			source, currency string
+			current          float64
+			currentAt        sql.NullTime
+			lows             [3]sql.NullFloat64
+		)
+		if err := lowRows.Scan(&source, &currency, &current, &currentAt, &lows[0], &lows[1], &lows[2]); err != nil {
+			return priceHistoryResponse{}, err
+		}
+		series := seriesFor(source, currency)
+		series.Current = ptrFloat(current)
+		series.CurrentAt = nullTimeString(currentAt)
+		for i, days := range priceLowWindows {
+			low := priceLow{Window: fmt.Sprintf("%dd", days), Price: nullFloat(lows[i])}
+			if low.Price != nil && *low.Price > 0 {
+				low.CurrentVsLowPct = ptrFloat(round1((current - *low.Price) / *low.Price * 100))
+			}
+			series.Lows = append(series.Lows, low)
+		}
+	}
+	if err := lowRows.Err(); err != nil {
+		return priceHistoryResponse{}, err
+	}
+	return resp, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParsePriceHistoryQuery(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	q, err := parsePriceHistoryQuery(url.Values{}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Interval != "day" || !q.To.Equal(now) || !q.From.Equal(now.AddDate(0, 0, -90)) {
+		t.Fatalf("unexpected defaults %+v", q)
+	}
+
+	v, _ := url.ParseQuery("from=2026-01-01&to=2026-02-01T00:00:00Z&interval=WEEK")
+	if q, err = parsePriceHistoryQuery(v, now); err != nil || q.Interval != "week" || q.From.Month() != time.January {
+		t.Fatalf("unexpected result %+v, %v", q, err)
+	}
+
+	for _, raw := range []string{
+		"interval=minute",
+		"from=yesterday",
+		"from=2026-02-01&to=2026-01-01",
+		"from=2025-01-01&interval=hour",
+	} {
+		v, _ := url.ParseQuery(raw)
+		if _, err := parsePriceHistoryQuery(v, now); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashlight-ratings-go/internal/apikeys"
+)
+
+// rateLimit identifies the caller by its X-API-Key header, or by client
+// address when no key is sent, and applies the caller's token bucket and
+// daily quota before passing the request on. Keyed requests are counted per
+// route pattern. Internal keys, used by our own web app, are counted but
+// never limited. Health probes bypass it.
+func (s *Server) rateLimit(mux *http.ServeMux) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if probePaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			secret := strings.TrimSpace(r.Header.Get("X-API-Key"))
+			if secret == "" {
+				d := s.buckets.Take("ip:"+s.clientIP(r), s.anonRate, s.anonBurst)
+				setRateLimitHeaders(w, d)
+				if !d.Allowed {
+					writeTooManyRequests(w, d.RetryAfter, "rate limit exceeded")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+			defer cancel()
+
+			key, err := s.keys.Lookup(ctx, secret)
+			if err != nil {
+				if errors.Is(err, apikeys.ErrNotFound) {
+					writeJSON(w, http.StatusUnauthorized, apiError{Error: "invalid api key"})
+					return
+				}
+				writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to check api key"})
+				return
+			}
+
+			endpoint := routePattern(mux, r)
+			if !key.Internal {
+				if key.RatePerMinute > 0 {
+					d := s.buckets.Take(fmt.Sprintf("key:%d", key.ID), key.RatePerMinute, key.Burst)
+					setRateLimitHeaders(w, d)
+					if !d.Allowed {
+						s.usage.Record(key.ID, endpoint, true)
+						writeTooManyRequests(w, d.RetryAfter, "rate limit exceeded")
+						return
+					}
+				}
+				if key.DailyQuota > 0 {
+					used, err := s.usage.Used(ctx, key.ID)
+					if err != nil {
+						writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to check api key quota"})
+						return
+					}
+					reset := s.usage.UntilReset()
+					remaining := int64(key.DailyQuota) - used
+					w.Header().Set("X-RateLimit-Quota-Limit", strconv.Itoa(key.DailyQuota))
+					w.Header().Set("X-RateLimit-Quota-Remaining", strconv.FormatInt(max(remaining-1, 0), 10))
+					w.Header().Set("X-RateLimit-Quota-Reset", strconv.Itoa(int(reset.Seconds())))
+					if remaining <= 0 {
+						s.usage.Record(key.ID, endpoint, true)
+						writeTooManyRequests(w, reset, "daily quota exceeded")
+						return
+					}
+				}
+			}
+			s.usage.Record(key.ID, endpoint, false)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routePattern names the mux route r matches, so usage is grouped by
+// endpoint rather than by every distinct id or slug.
+func routePattern(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	if pattern == "" {
+		return "unmatched"
+	}
+	return pattern
+}
+
+// clientIP uses X-Real-IP, as set by the nginx proxy, only when the API is
+// configured to trust it; otherwise clients could pick their own bucket.
+func (s *Server) clientIP(r *http.Request) string {
+	if s.trustProxy {
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, d apikeys.Decision) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(d.Reset.Seconds())))
+}
+
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, msg string) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(retryAfter.Seconds()), 1)))
+	writeJSON(w, http.StatusTooManyRequests, apiError{Error: msg})
+}
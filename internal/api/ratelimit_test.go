@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"flashlight-ratings-go/internal/apikeys"
+)
+
+func TestAnonymousRateLimit(t *testing.T) {
+	s := &Server{buckets: apikeys.NewBuckets(), keys: apikeys.NewStore(nil, 0), anonRate: 60, anonBurst: 2}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/brands", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := s.rateLimit(mux)(mux)
+
+	get := func(remoteAddr, realIP string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/brands", nil)
+		r.RemoteAddr = remoteAddr
+		if realIP != "" {
+			r.Header.Set("X-Real-IP", realIP)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := get("203.0.113.5:4000", ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, w.Code)
+		}
+	}
+	w := get("203.0.113.5:4001", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("headers: %v", w.Header())
+	}
+
+	// X-Real-IP is ignored unless the proxy is trusted.
+	if w := get("203.0.113.5:4002", "198.51.100.9"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed X-Real-IP got status %d", w.Code)
+	}
+	s.trustProxy = true
+	if w := get("10.0.0.2:5000", "198.51.100.9"); w.Code != http.StatusOK {
+		t.Fatalf("trusted X-Real-IP: status %d", w.Code)
+	}
+}
+
+func TestUnknownAPIKeyIsRejected(t *testing.T) {
+	s := &Server{buckets: apikeys.NewBuckets(), keys: apikeys.NewStore(nil, 0), anonRate: 60, anonBurst: 2}
+	r := httptest.NewRequest(http.MethodGet, "/brands", nil)
+	r.Header.Set("X-API-Key", "not-one-of-ours")
+	w := httptest.NewRecorder()
+	mux := http.NewServeMux()
+	s.rateLimit(mux)(mux).ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status %d, want 401", w.Code)
+	}
+}
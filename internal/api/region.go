@@ -0,0 +1,88 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"flashlight-ratings-go/internal/amazon"
+	"flashlight-ratings-go/pkg/client"
+)
+
+// countryHeaders are checked in order when no region parameter is given.
+// They are set by the CDN or load balancer in front of the API.
+var countryHeaders = []string{"CloudFront-Viewer-Country", "CF-IPCountry", "X-Country-Code"}
+
+// region selects which Amazon marketplace amazon_url points at. Requested is
+// what the client asked for; Code and Currency are what queries use, which
+// is US when the requested country has no marketplace.
+type region struct {
+	Requested string
+	Code      string
+	Currency  string
+}
+
+var defaultRegion = region{Requested: "US", Code: "US", Currency: "USD"}
+
+// regionalOffer is the region-specific part of a listing.
+type regionalOffer = client.RegionalOffer
+
+// requestRegion reads the region parameter, or failing that a country header.
+// An unknown region parameter is an error; an unknown country from a header
+// quietly falls back to US.
+func requestRegion(w http.ResponseWriter, r *http.Request) (region, error) {
+	w.Header().Add("Vary", strings.Join(countryHeaders, ", "))
+
+	if raw := strings.TrimSpace(r.URL.Query().Get("region")); raw != "" {
+		code, ok := amazon.NormalizeRegion(raw)
+		if !ok {
+			return region{}, invalidField("region", raw, errNotAllowed.Error(), regionCodes...)
+		}
+		currency, _ := amazon.RegionCurrency(code)
+		return region{Requested: code, Code: code, Currency: currency}, nil
+	}
+	for _, h := range countryHeaders {
+		raw := strings.TrimSpace(r.Header.Get(h))
+		if raw == "" {
+			continue
+		}
+		code, ok := amazon.NormalizeRegion(raw)
+		if !ok {
+			rg := defaultRegion
+			if len(code) == 2 {
+				rg.Requested = code
+			}
+			return rg, nil
+		}
+		currency, _ := amazon.RegionCurrency(code)
+		return region{Requested: code, Code: code, Currency: currency}, nil
+	}
+	return defaultRegion, nil
+}
+
+// offerColumns selects the region's offer from the read model row rm: the
+// affiliate URL and region of the active Amazon link, preferring rg and
+// falling back to US, then the latest price and currency in rg's currency.
+// Code and Currency come from the marketplace table, never from user input,
+// so they are safe to inline.
+func (rg region) offerColumns() string {
+	return fmt.Sprintf(`COALESCE(rm.offers -> '%[1]s', rm.offers -> 'US') ->> 'url',
+	COALESCE(rm.offers -> '%[1]s', rm.offers -> 'US') ->> 'region',
+	(rm.prices ->> '%[2]s')::NUMERIC,
+	CASE WHEN rm.prices -> '%[2]s' IS NOT NULL THEN '%[2]s' END`, rg.Code, rg.Currency)
+}
+
+// setOffer fills o from the offer columns scanned for rg.
+func (rg region) setOffer(o *regionalOffer, linkRegion sql.NullString, price sql.NullFloat64, currency sql.NullString) {
+	if linkRegion.Valid {
+		code := strings.TrimSpace(linkRegion.String)
+		o.AmazonRegion = &code
+		o.RegionFallback = code != rg.Requested
+	}
+	o.Price = nullFloat(price)
+	if currency.Valid {
+		c := strings.TrimSpace(currency.String)
+		o.Currency = &c
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestRegion(t *testing.T) {
+	cases := []struct {
+		name      string
+		url       string
+		header    string
+		requested string
+		code      string
+		currency  string
+		wantErr   bool
+	}{
+		{name: "default", url: "/flashlights", requested: "US", code: "US", currency: "USD"},
+		{name: "param", url: "/flashlights?region=de", requested: "DE", code: "DE", currency: "EUR"},
+		{name: "gb alias", url: "/flashlights?region=GB", requested: "UK", code: "UK", currency: "GBP"},
+		{name: "bad param", url: "/flashlights?region=zz", wantErr: true},
+		{name: "header", url: "/flashlights", header: "JP", requested: "JP", code: "JP", currency: "JPY"},
+		{name: "param wins", url: "/flashlights?region=ca", header: "JP", requested: "CA", code: "CA", currency: "CAD"},
+		{name: "unsupported country", url: "/flashlights", header: "NL", requested: "NL", code: "US", currency: "USD"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.url, nil)
+			if tc.header != "" {
+				r.Header.Set("CloudFront-Viewer-Country", tc.header)
+			}
+			w := httptest.NewRecorder()
+			rg, err := requestRegion(w, r)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("requestRegion: %v", err)
+			}
+			if rg.Requested != tc.requested || rg.Code != tc.code || rg.Currency != tc.currency {
+				t.Fatalf("region = %+v", rg)
+			}
+			if w.Header().Get("Vary") == "" {
+				t.Fatal("missing Vary header")
+			}
+		})
+	}
+}
+
+func TestRegionalOfferFallback(t *testing.T) {
+	de := region{Requested: "DE", Code: "DE", Currency: "EUR"}
+
+	var o regionalOffer
+	de.setOffer(&o, sql.NullString{String: "US", Valid: true}, sql.NullFloat64{}, sql.NullString{})
+	if !o.RegionFallback || o.AmazonRegion == nil || *o.AmazonRegion != "US" {
+		t.Fatalf("offer = %+v, want US fallback", o)
+	}
+	if o.Price != nil || o.Currency != nil {
+		t.Fatalf("offer price = %v %v, want none", o.Price, o.Currency)
+	}
+
+	o = regionalOffer{}
+	de.setOffer(&o, sql.NullString{String: "DE", Valid: true}, sql.NullFloat64{Float64: 39.9, Valid: true}, sql.NullString{String: "EUR", Valid: true})
+	if o.RegionFallback || *o.Price != 39.9 || *o.Currency != "EUR" {
+		t.Fatalf("offer = %+v", o)
+	}
+}
@@ -12,64 +12,12 @@ import (
 	"time"
 )
 
-type flashlightFilters struct {
-	BatteryType string
-	MinPrice    *float64
-	MaxPrice    *float64
-	IPRating    string
-	SortBy      string
-	Order       string
-	Page        int
-	PageSize    int
-}
-
-func (s *Server) listFlashlights(ctx context.Context, f flashlightFilters) ([]flashlightItem, int, error) {
-	where, args := buildFlashlightWhere(f)
-
-	sortExpr := sortColumn(f.SortBy)
-	order := "DESC"
-	if strings.EqualFold(f.Order, "asc") {
-		order = "ASC"
-	}
-	offset := (f.Page - 1) * f.PageSize
-
-	query := fmt.Sprintf(`
-WITH latest_run AS (
-	SELECT id
-	FROM scoring_runs
-	WHERE status = 'completed'
-	ORDER BY completed_at DESC NULLS LAST, id DESC
-	LIMIT 1
-),
-latest_price AS (
-	SELECT DISTINCT ON (p.flashlight_id)
-		p.flashlight_id,
-		p.price
-	FROM flashlight_price_snapshots p
-	WHERE p.currency_code = 'USD'
-	ORDER BY p.flashlight_id, p.captured_at DESC
-),
-latest_affiliate AS (
-	SELECT DISTINCT ON (a.flashlight_id)
-		a.flashlight_id,
-		a.affiliate_url
-	FROM affiliate_links a
-	WHERE a.provider = 'amazon'
-	  AND a.region_code = 'US'
-	  AND a.is_active = TRUE
-	ORDER BY a.flashlight_id, a.is_primary DESC, a.updated_at DESC, a.id DESC
-),
-latest_media AS (
-	SELECT DISTINCT ON (m.flashlight_id)
-		m.flashlight_id,
-		m.url
-	FROM flashlight_media m
-	WHERE m.media_type = 'image'
-	ORDER BY m.flashlight_id, m.sort_order ASC, m.id ASC
-),
-latest_scores AS (
+// pinnedScoresJoin recomputes the read model's score columns from an older
+// scoring run, for cursor pages whose first page was read before the
+// current run was published.
+const pinnedScoresJoin = `
+LEFT JOIN LATERAL (
 	SELECT
-		fs.flashlight_id,
 		MAX(CASE WHEN sp.slug = 'tactical' THEN fs.score END) AS tactical_score,
 		MAX(CASE WHEN sp.slug = 'edc' THEN fs.score END) AS edc_score,
 		MAX(CASE WHEN sp.slug = 'value' THEN fs.score END) AS value_score,
@@ -77,9 +25,44 @@ latest_scores AS (
 		MAX(CASE WHEN sp.slug = 'flood' THEN fs.score END) AS flood_score
 	FROM flashlight_scores fs
 	JOIN scoring_profiles sp ON sp.id = fs.profile_id
-	JOIN latest_run lr ON lr.id = fs.run_id
-	GROUP BY fs.flashlight_id
-)
+	WHERE fs.flashlight_id = f.id
+	  AND fs.run_id = $%d
+) pinned ON TRUE`
+
+// listFlashlights reads one page of the catalog: by number with a count
+// when f.Cursor is nil, otherwise the page next to the cursor.
+func (s *Server) listFlashlights(ctx context.Context, f flashlightFilters) (listPage[flashlightItem], error) {
+	where, args := buildFlashlightWhere(f)
+
+	sortExpr := sortColumn(f.SortBy)
+	desc := !strings.EqualFold(f.Order, "asc")
+	rg := f.Region
+	if rg.Code == "" {
+		rg = defaultRegion
+	}
+
+	scores, pinned, run := "rm", "", latestRunSQL
+	limit := fmt.Sprintf("LIMIT %d OFFSET %d", f.PageSize, (f.Page-1)*f.PageSize)
+	if c := f.Cursor; c != nil {
+		args = append(args, c.Run)
+		run = fmt.Sprintf("$%d::BIGINT", len(args))
+		if strings.HasSuffix(sortExpr, "_score") {
+			st, err := s.loadDataStamp(ctx)
+			if err != nil {
+				return listPage[flashlightItem]{}, err
+			}
+			if st.RunID != c.Run {
+				scores, pinned = "pinned", fmt.Sprintf(pinnedScoresJoin, len(args))
+				sortExpr = "pinned." + strings.TrimPrefix(sortExpr, "rm.")
+			}
+		}
+		cond, condArgs := keysetCondition(sortExpr, "f.id", desc, *c, len(args)+1)
+		where += " AND " + cond
+		args = append(args, condArgs...)
+		limit = fmt.Sprintf("LIMIT %d", f.PageSize+1)
+	}
+
+	query := fmt.Sprintf(`
 SELECT
 	f.id,
 	b.name,
@@ -87,45 +70,50 @@ SELECT
 	f.slug,
 	f.model_code,
 	f.description,
-	lm.url,
-	la.affiliate_url,
+	rm.image_url,
+	%[1]s,
 	s.max_lumens,
 	s.max_candela,
 	s.beam_distance_m,
 	s.runtime_high_min,
 	s.waterproof_rating,
-	lp.price,
-	ls.tactical_score,
-	ls.edc_score,
-	ls.value_score,
-	ls.throw_score,
-	ls.flood_score
+	rm.price_usd,
+	%[2]s.tactical_score,
+	%[2]s.edc_score,
+	%[2]s.value_score,
+	%[2]s.throw_score,
+	%[2]s.flood_score,
+	%[3]s,
+	%[4]s
 FROM flashlights f
 JOIN brands b ON b.id = f.brand_id
 LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
-LEFT JOIN latest_price lp ON lp.flashlight_id = f.id
-LEFT JOIN latest_affiliate la ON la.flashlight_id = f.id
-LEFT JOIN latest_media lm ON lm.flashlight_id = f.id
-LEFT JOIN latest_scores ls ON ls.flashlight_id = f.id
-%s
-ORDER BY %s %s, f.id ASC
-LIMIT %d OFFSET %d
-`, where, sortExpr, order, f.PageSize, offset)
+LEFT JOIN flashlight_read_model rm ON rm.flashlight_id = f.id
+%[5]s
+%[6]s
+ORDER BY %[7]s
+%[8]s
+`, rg.offerColumns(), scores, sortExpr, run, pinned, where, keysetOrder(sortExpr, "f.id", desc, f.Cursor), limit)
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return listPage[flashlightItem]{}, err
 	}
 	defer rows.Close()
 
-	items := make([]flashlightItem, 0, f.PageSize)
+	items := make([]flashlightItem, 0, f.PageSize+1)
+	keys := make([]keysetRow, 0, f.PageSize+1)
+	var runID int64
 	for rows.Next() {
 		var (
 			item                                      flashlightItem
 			maxLumens, maxCandela, beam, runtimeHi    sql.NullInt64
 			modelCode, description, imageURL          sql.NullString
 			ip, amazonURL                             sql.NullString
+			linkRegion, regionalCurrency              sql.NullString
+			regionalPrice                             sql.NullFloat64
 			price, tactical, edc, value, throw, flood sql.NullFloat64
+			key                                       sql.NullFloat64
 		)
 		if err := rows.Scan(
 			&item.ID,
@@ -136,6 +124,9 @@ LIMIT %d OFFSET %d
 			&description,
 			&imageURL,
 			&amazonURL,
+			&linkRegion,
+			&regionalPrice,
+			&regionalCurrency,
 			&maxLumens,
 			&maxCandela,
 			&beam,
@@ -147,13 +138,16 @@ LIMIT %d OFFSET %d
 			&value,
 			&throw,
 			&flood,
+			&key,
+			&runID,
 		); err != nil {
-			return nil, 0, err
+			return listPage[flashlightItem]{}, err
 		}
 		item.ModelCode = nullString(modelCode)
 		item.Description = nullString(description)
 		item.ImageURL = nullString(imageURL)
 		item.AmazonURL = nullString(amazonURL)
+		rg.setOffer(&item.RegionalOffer, linkRegion, regionalPrice, regionalCurrency)
 		item.MaxLumens = nullInt(maxLumens)
 		item.MaxCandela = nullInt(maxCandela)
 		item.BeamDistanceM = nullInt(beam)
@@ -166,34 +160,43 @@ LIMIT %d OFFSET %d
 		item.ThrowScore = nullFloat(throw)
 		item.FloodScore = nullFloat(flood)
 		items = append(items, item)
+		keys = append(keys, keysetRow{key: nullFloat(key), id: item.ID})
 	}
 	if err := rows.Err(); err != nil {
-		return nil, 0, err
+		return listPage[flashlightItem]{}, err
 	}
 
-	total, err := s.countFlashlights(ctx, where, args)
-	if err != nil {
-		return nil, 0, err
+	page := listPage[flashlightItem]{}
+	var hasNext, hasPrev bool
+	if f.Cursor != nil {
+		var more bool
+		page.items, more = keysetPage(items, f.PageSize, f.Cursor)
+		keys, _ = keysetPage(keys, f.PageSize, f.Cursor)
+		hasNext, hasPrev = more || f.Cursor.Before, more || !f.Cursor.Before
+	} else {
+		total, err := s.countFlashlights(ctx, where, args)
+		if err != nil {
+			return listPage[flashlightItem]{}, err
+		}
+		page.items, page.total = items, &total
+		hasNext, hasPrev = (f.Page-1)*f.PageSize+len(items) < total, f.Page > 1
 	}
-	return items, total, nil
+	base := cursor{Sort: flashlightsSort(f), Run: runID}
+	page.next, page.prev = pageCursors(base, keys, hasNext, hasPrev)
+	return page, nil
+}
+
+// flashlightsSort is the ordering a /flashlights cursor is bound to.
+func flashlightsSort(f flashlightFilters) string {
+	return f.SortBy + " " + f.Order
 }
 
 func (s *Server) countFlashlights(ctx context.Context, where string, args []any) (int, error) {
 	query := fmt.Sprintf(`
 SELECT COUNT(*)
-FROM flashlights f
-JOIN brands b ON b.id = f.brand_id
-LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
-LEFT JOIN (
-	SELECT DISTINCT ON (p.flashlight_id)
-		p.flashlight_id,
-		p.price
-	FROM flashlight_price_snapshots p
-	WHERE p.currency_code = 'USD'
-	ORDER BY p.flashlight_id, p.captured_at DESC
-) lp ON lp.flashlight_id = f.id
 %s
-`, where)
+%s
+`, flashlightFilterFrom, where)
 	var total int
 	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
 		return 0, err
@@ -201,52 +204,21 @@ LEFT JOIN (
 	return total, nil
 }
 
-func (s *Server) getFlashlightByID(ctx context.Context, id int64) (flashlightDetail, error) {
-	query := `
-WITH latest_run AS (
-	SELECT id
-	FROM scoring_runs
-	WHERE status = 'completed'
-	ORDER BY completed_at DESC NULLS LAST, id DESC
-	LIMIT 1
-),
-latest_price AS (
-	SELECT p.price, p.captured_at
-	FROM flashlight_price_snapshots p
-	WHERE p.flashlight_id = $1
-	  AND p.currency_code = 'USD'
-	ORDER BY p.captured_at DESC
-	LIMIT 1
-),
-latest_amazon AS (
-	SELECT aps.rating_count, aps.average_rating, aps.captured_at
-	FROM amazon_product_snapshots aps
-	WHERE aps.flashlight_id = $1
-	ORDER BY aps.captured_at DESC
-	LIMIT 1
-),
-latest_affiliate AS (
-	SELECT a.affiliate_url, a.asin
-	FROM affiliate_links a
-	WHERE a.flashlight_id = $1
-	  AND a.provider = 'amazon'
-	  AND a.region_code = 'US'
-	  AND a.is_active = TRUE
-	ORDER BY a.is_primary DESC, a.updated_at DESC, a.id DESC
-	LIMIT 1
-),
-latest_scores AS (
-	SELECT
-		MAX(CASE WHEN sp.slug = 'tactical' THEN fs.score END) AS tactical_score,
-		MAX(CASE WHEN sp.slug = 'edc' THEN fs.score END) AS edc_score,
-		MAX(CASE WHEN sp.slug = 'value' THEN fs.score END) AS value_score,
-		MAX(CASE WHEN sp.slug = 'throw' THEN fs.score END) AS throw_score,
-		MAX(CASE WHEN sp.slug = 'flood' THEN fs.score END) AS flood_score
-	FROM flashlight_scores fs
-	JOIN scoring_profiles sp ON sp.id = fs.profile_id
-	JOIN latest_run lr ON lr.id = fs.run_id
-	WHERE fs.flashlight_id = $1
-)
+func (s *Server) getFlashlightByID(ctx context.Context, id int64, rg region) (flashlightDetail, error) {
+	items, err := s.getFlashlightsByID(ctx, []int64{id}, rg)
+	if err != nil {
+		return flashlightDetail{}, err
+	}
+	if len(items) == 0 {
+		return flashlightDetail{}, sql.ErrNoRows
+	}
+	return items[0], nil
+}
+
+// getFlashlightsByID loads the detail view of each id that exists, in one
+// query and in no particular order.
+func (s *Server) getFlashlightsByID(ctx context.Context, ids []int64, rg region) ([]flashlightDetail, error) {
+	query := fmt.Sprintf(`
 SELECT
 	f.id,
 	b.name,
@@ -256,16 +228,12 @@ SELECT
 	EXTRACT(YEAR FROM f.launch_date)::INTEGER,
 	f.msrp_usd,
 	f.description,
-	(
-		SELECT m.url
-		FROM flashlight_media m
-		WHERE m.flashlight_id = f.id
-		  AND m.media_type = 'image'
-		ORDER BY m.sort_order ASC, m.id ASC
-		LIMIT 1
-	) AS image_url,
-	(SELECT affiliate_url FROM latest_affiliate),
-	(SELECT asin FROM latest_affiliate),
+	rm.image_url,
+	COALESCE(rm.offers -> '%[1]s', rm.offers -> 'US') ->> 'url',
+	COALESCE(rm.offers -> '%[1]s', rm.offers -> 'US') ->> 'asin',
+	COALESCE(rm.offers -> '%[1]s', rm.offers -> 'US') ->> 'region',
+	(rm.prices ->> '%[2]s')::NUMERIC,
+	CASE WHEN rm.prices -> '%[2]s' IS NOT NULL THEN '%[2]s' END,
 	s.max_lumens,
 	s.sustained_lumens,
 	s.max_candela,
@@ -300,16 +268,16 @@ SELECT
 	s.cri,
 	s.cct_min_k,
 	s.cct_max_k,
-	(SELECT price FROM latest_price),
-	(SELECT captured_at FROM latest_price),
-	(SELECT rating_count FROM latest_amazon),
-	(SELECT average_rating FROM latest_amazon),
-	(SELECT captured_at FROM latest_amazon),
-	(SELECT tactical_score FROM latest_scores),
-	(SELECT edc_score FROM latest_scores),
-	(SELECT value_score FROM latest_scores),
-	(SELECT throw_score FROM latest_scores),
-	(SELECT flood_score FROM latest_scores),
+	rm.price_usd,
+	rm.price_usd_captured_at,
+	rm.amazon_rating_count,
+	rm.amazon_average_rating,
+	rm.amazon_captured_at,
+	rm.tactical_score,
+	rm.edc_score,
+	rm.value_score,
+	rm.throw_score,
+	rm.flood_score,
 	COALESCE(
 		(
 			SELECT json_agg(bt.code ORDER BY bt.code)
@@ -357,358 +325,262 @@ SELECT
 FROM flashlights f
 JOIN brands b ON b.id = f.brand_id
 LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
-WHERE f.id = $1
-`
+LEFT JOIN flashlight_read_model rm ON rm.flashlight_id = f.id
+WHERE f.id = ANY($1)
+`, rg.Code, rg.Currency)
 
-	var (
-		item                                                                                                                                          flashlightDetail
-		modelCode, desc, imageURL, ip, amazonURL, asin, switchType, ledModel, beamPattern, rechargeType, bodyMaterial                                 sql.NullString
-		releaseYear, maxLumens, sustainedLumens, maxCandela, beam, runtimeLow, runtimeMedium, runtimeHi, runtimeTurbo, runtime500, turboStepdown, cri sql.NullInt64
-		cctMinK, cctMaxK                                                                                                                              sql.NullInt64
-		msrpUSD, weight, lengthMM, headMM, bodyMM, impact, price, amazonAvgRating, tactical, edc, value, throw, flood                                 sql.NullFloat64
-		batteryReplaceable, usbC, batteryIncluded, batteryRechargeable                                                                                sql.NullBool
-		hasStrobe, hasMemoryMode, hasLockout, hasMoonlight, hasMagTailcap, hasPocketClip                                                              sql.NullBool
-		priceUpdatedAt, amazonSyncedAt                                                                                                                sql.NullTime
-		amazonRatingCount                                                                                                                             sql.NullInt64
-		batteryTypesJSON, imageURLsJSON, modesJSON, useCaseTagsJSON                                                                                   []byte
-	)
-
-	if err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&item.ID,
-		&item.Brand,
-		&item.Name,
-		&item.Slug,
-		&modelCode,
-		&releaseYear,
-		&msrpUSD,
-		&desc,
-		&imageURL,
-		&amazonURL,
-		&asin,
-		&maxLumens,
-		&sustainedLumens,
-		&maxCandela,
-		&beam,
-		&runtimeLow,
-		&runtimeMedium,
-		&runtimeHi,
-		&runtimeTurbo,
-		&runtime500,
-		&turboStepdown,
-		&beamPattern,
-		&rechargeType,
-		&batteryReplaceable,
-		&ip,
-		&weight,
-		&lengthMM,
-		&headMM,
-		&bodyMM,
-		&impact,
-		&bodyMaterial,
-		&usbC,
-		&batteryIncluded,
-		&batteryRechargeable,
-		&hasStrobe,
-		&hasMemoryMode,
-		&hasLockout,
-		&hasMoonlight,
-		&hasMagTailcap,
-		&hasPocketClip,
-		&switchType,
-		&ledModel,
-		&cri,
-		&cctMinK,
-		&cctMaxK,
-		&price,
-		&priceUpdatedAt,
-		&amazonRatingCount,
-		&amazonAvgRating,
-		&amazonSyncedAt,
-		&tactical,
-		&edc,
-		&value,
-		&throw,
-		&flood,
-		&batteryTypesJSON,
-		&imageURLsJSON,
-		&modesJSON,
-		&useCaseTagsJSON,
-	); err != nil {
-		return flashlightDetail{}, err
-	}
-
-	item.ModelCode = nullString(modelCode)
-	item.ReleaseYear = nullInt(releaseYear)
-	item.MSRPUSD = nullFloat(msrpUSD)
-	item.Description = nullString(desc)
-	item.ImageURL = nullString(imageURL)
-	item.AmazonURL = nullString(amazonURL)
-	item.ASIN = nullString(asin)
-	item.MaxLumens = nullInt(maxLumens)
-	item.SustainedLumens = nullInt(sustainedLumens)
-	item.MaxCandela = nullInt(maxCandela)
-	item.BeamDistanceM = nullInt(beam)
-	item.RuntimeLowMin = nullInt(runtimeLow)
-	item.RuntimeMediumMin = nullInt(runtimeMedium)
-	item.RuntimeHighMin = nullInt(runtimeHi)
-	item.RuntimeTurboMin = nullInt(runtimeTurbo)
-	item.Runtime500Min = nullInt(runtime500)
-	item.TurboStepdownSec = nullInt(turboStepdown)
-	item.BeamPattern = nullString(beamPattern)
-	item.RechargeType = nullString(rechargeType)
-	item.BatteryReplaceable = nullBool(batteryReplaceable)
-	item.Waterproof = nullString(ip)
-	item.WeightG = nullFloat(weight)
-	item.LengthMM = nullFloat(lengthMM)
-	item.HeadDiameterMM = nullFloat(headMM)
-	item.BodyDiameterMM = nullFloat(bodyMM)
-	item.ImpactResistance = nullFloat(impact)
-	item.BodyMaterial = nullString(bodyMaterial)
-	item.USBCRechargeable = nullBool(usbC)
-	item.BatteryIncluded = nullBool(batteryIncluded)
-	item.BatteryRech = nullBool(batteryRechargeable)
-	item.HasStrobe = nullBool(hasStrobe)
-	item.HasMemoryMode = nullBool(hasMemoryMode)
-	item.HasLockout = nullBool(hasLockout)
-	item.HasMoonlightMode = nullBool(hasMoonlight)
-	item.HasMagTailcap = nullBool(hasMagTailcap)
-	item.HasPocketClip = nullBool(hasPocketClip)
-	item.SwitchType = nullString(switchType)
-	item.HasTailSwitch = switchHas(item.SwitchType, "tail")
-	item.HasSideSwitch = switchHas(item.SwitchType, "side")
-	item.LEDModel = nullString(ledModel)
-	item.CRI = nullInt(cri)
-	item.CCTMinK = nullInt(cctMinK)
-	item.CCTMaxK = nullInt(cctMaxK)
-	item.PriceUSD = nullFloat(price)
-	item.AmazonRatingCount = nullInt(amazonRatingCount)
-	item.AmazonAverageRating = nullFloat(amazonAvgRating)
-	item.PriceLastUpdatedAt = nullTimeString(priceUpdatedAt)
-	item.AmazonLastSyncedAt = nullTimeString(amazonSyncedAt)
-	item.TacticalScore = nullFloat(tactical)
-	item.EDCScore = nullFloat(edc)
-	item.ValueScore = nullFloat(value)
-	item.ThrowScore = nullFloat(throw)
-	item.FloodScore = nullFloat(flood)
-	item.BatteryTypes = decodeJSONStringArray(batteryTypesJSON)
-	item.ImageURLs = decodeJSONStringArray(imageURLsJSON)
-	item.Modes = decodeModesJSON(modesJSON)
-	item.UseCaseTags = decodeJSONStringArray(useCaseTagsJSON)
-	return item, nil
-}
-
-func (s *Server) compareFlashlights(ctx context.Context, ids []int64) ([]flashlightItem, error) {
-	ph := makePlaceholders(1, len(ids))
-	args := make([]any, 0, len(ids))
-	for _, id := range ids {
-		args = append(args, id)
-	}
-
-	query := fmt.Sprintf(`
-WITH latest_run AS (
-	SELECT id
-	FROM scoring_runs
-	WHERE status = 'completed'
-	ORDER BY completed_at DESC NULLS LAST, id DESC
-	LIMIT 1
-),
-latest_price AS (
-	SELECT DISTINCT ON (p.flashlight_id)
-		p.flashlight_id,
-		p.price
-	FROM flashlight_price_snapshots p
-	WHERE p.currency_code = 'USD'
-	ORDER BY p.flashlight_id, p.captured_at DESC
-),
-latest_affiliate AS (
-	SELECT DISTINCT ON (a.flashlight_id)
-		a.flashlight_id,
-		a.affiliate_url
-	FROM affiliate_links a
-	WHERE a.provider = 'amazon'
-	  AND a.region_code = 'US'
-	  AND a.is_active = TRUE
-	ORDER BY a.flashlight_id, a.is_primary DESC, a.updated_at DESC, a.id DESC
-),
-latest_media AS (
-	SELECT DISTINCT ON (m.flashlight_id)
-		m.flashlight_id,
-		m.url
-	FROM flashlight_media m
-	WHERE m.media_type = 'image'
-	ORDER BY m.flashlight_id, m.sort_order ASC, m.id ASC
-),
-latest_scores AS (
-	SELECT
-		fs.flashlight_id,
-		MAX(CASE WHEN sp.slug = 'tactical' THEN fs.score END) AS tactical_score,
-		MAX(CASE WHEN sp.slug = 'edc' THEN fs.score END) AS edc_score,
-		MAX(CASE WHEN sp.slug = 'value' THEN fs.score END) AS value_score,
-		MAX(CASE WHEN sp.slug = 'throw' THEN fs.score END) AS throw_score,
-		MAX(CASE WHEN sp.slug = 'flood' THEN fs.score END) AS flood_score
-	FROM flashlight_scores fs
-	JOIN scoring_profiles sp ON sp.id = fs.profile_id
-	JOIN latest_run lr ON lr.id = fs.run_id
-	GROUP BY fs.flashlight_id
-)
-SELECT
-	f.id,
-	b.name,
-	f.name,
-	f.slug,
-	f.model_code,
-	f.description,
-	lm.url,
-	la.affiliate_url,
-	s.max_lumens,
-	s.max_candela,
-	s.beam_distance_m,
-	s.runtime_high_min,
-	s.waterproof_rating,
-	lp.price,
-	ls.tactical_score,
-	ls.edc_score,
-	ls.value_score,
-	ls.throw_score,
-	ls.flood_score
-FROM flashlights f
-JOIN brands b ON b.id = f.brand_id
-LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
-LEFT JOIN latest_price lp ON lp.flashlight_id = f.id
-LEFT JOIN latest_affiliate la ON la.flashlight_id = f.id
-LEFT JOIN latest_media lm ON lm.flashlight_id = f.id
-LEFT JOIN latest_scores ls ON ls.flashlight_id = f.id
-WHERE f.id IN (%s)
-ORDER BY f.id ASC
-`, ph)
-
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.db.QueryContext(ctx, query, ids)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	items := make([]flashlightItem, 0, len(ids))
+	items := make([]flashlightDetail, 0, len(ids))
 	for rows.Next() {
 		var (
-			item                                      flashlightItem
-			maxLumens, maxCandela, beam, runtimeHi    sql.NullInt64
-			modelCode, description, imageURL          sql.NullString
-			ip, amazonURL                             sql.NullString
-			price, tactical, edc, value, throw, flood sql.NullFloat64
+			item                                                                                                                                          flashlightDetail
+			modelCode, desc, imageURL, ip, amazonURL, asin, switchType, ledModel, beamPattern, rechargeType, bodyMaterial                                 sql.NullString
+			releaseYear, maxLumens, sustainedLumens, maxCandela, beam, runtimeLow, runtimeMedium, runtimeHi, runtimeTurbo, runtime500, turboStepdown, cri sql.NullInt64
+			cctMinK, cctMaxK                                                                                                                              sql.NullInt64
+			msrpUSD, weight, lengthMM, headMM, bodyMM, impact, price, amazonAvgRating, tactical, edc, value, throw, flood                                 sql.NullFloat64
+			batteryReplaceable, usbC, batteryIncluded, batteryRechargeable                                                                                sql.NullBool
+			hasStrobe, hasMemoryMode, hasLockout, hasMoonlight, hasMagTailcap, hasPocketClip                                                              sql.NullBool
+			priceUpdatedAt, amazonSyncedAt                                                                                                                sql.NullTime
+			amazonRatingCount                                                                                                                             sql.NullInt64
+			batteryTypesJSON, imageURLsJSON, modesJSON, useCaseTagsJSON                                                                                   []byte
+			linkRegion, regionalCurrency                                                                                                                  sql.NullString
+			regionalPrice                                                                                                                                 sql.NullFloat64
 		)
+
 		if err := rows.Scan(
 			&item.ID,
 			&item.Brand,
 			&item.Name,
 			&item.Slug,
 			&modelCode,
-			&description,
+			&releaseYear,
+			&msrpUSD,
+			&desc,
 			&imageURL,
 			&amazonURL,
+			&asin,
+			&linkRegion,
+			&regionalPrice,
+			&regionalCurrency,
 			&maxLumens,
+			&sustainedLumens,
 			&maxCandela,
 			&beam,
+			&runtimeLow,
+			&runtimeMedium,
 			&runtimeHi,
+			&runtimeTurbo,
+			&runtime500,
+			&turboStepdown,
+			&beamPattern,
+			&rechargeType,
+			&batteryReplaceable,
 			&ip,
+			&weight,
+			&lengthMM,
+			&headMM,
+			&bodyMM,
+			&impact,
+			&bodyMaterial,
+			&usbC,
+			&batteryIncluded,
+			&batteryRechargeable,
+			&hasStrobe,
+			&hasMemoryMode,
+			&hasLockout,
+			&hasMoonlight,
+			&hasMagTailcap,
+			&hasPocketClip,
+			&switchType,
+			&ledModel,
+			&cri,
+			&cctMinK,
+			&cctMaxK,
 			&price,
+			&priceUpdatedAt,
+			&amazonRatingCount,
+			&amazonAvgRating,
+			&amazonSyncedAt,
 			&tactical,
 			&edc,
 			&value,
 			&throw,
 			&flood,
+			&batteryTypesJSON,
+			&imageURLsJSON,
+			&modesJSON,
+			&useCaseTagsJSON,
 		); err != nil {
 			return nil, err
 		}
+
 		item.ModelCode = nullString(modelCode)
-		item.Description = nullString(description)
+		item.ReleaseYear = nullInt(releaseYear)
+		item.MSRPUSD = nullFloat(msrpUSD)
+		item.Description = nullString(desc)
 		item.ImageURL = nullString(imageURL)
 		item.AmazonURL = nullString(amazonURL)
+		item.ASIN = nullString(asin)
+		rg.setOffer(&item.RegionalOffer, linkRegion, regionalPrice, regionalCurrency)
 		item.MaxLumens = nullInt(maxLumens)
+		item.SustainedLumens = nullInt(sustainedLumens)
 		item.MaxCandela = nullInt(maxCandela)
 		item.BeamDistanceM = nullInt(beam)
+		item.RuntimeLowMin = nullInt(runtimeLow)
+		item.RuntimeMediumMin = nullInt(runtimeMedium)
 		item.RuntimeHighMin = nullInt(runtimeHi)
+		item.RuntimeTurboMin = nullInt(runtimeTurbo)
+		item.Runtime500Min = nullInt(runtime500)
+		item.TurboStepdownSec = nullInt(turboStepdown)
+		item.BeamPattern = nullString(beamPattern)
+		item.RechargeType = nullString(rechargeType)
+		item.BatteryReplaceable = nullBool(batteryReplaceable)
 		item.Waterproof = nullString(ip)
+		item.WeightG = nullFloat(weight)
+		item.LengthMM = nullFloat(lengthMM)
+		item.HeadDiameterMM = nullFloat(headMM)
+		item.BodyDiameterMM = nullFloat(bodyMM)
+		item.ImpactResistance = nullFloat(impact)
+		item.BodyMaterial = nullString(bodyMaterial)
+		item.USBCRechargeable = nullBool(usbC)
+		item.BatteryIncluded = nullBool(batteryIncluded)
+		item.BatteryRech = nullBool(batteryRechargeable)
+		item.HasStrobe = nullBool(hasStrobe)
+		item.HasMemoryMode = nullBool(hasMemoryMode)
+		item.HasLockout = nullBool(hasLockout)
+		item.HasMoonlightMode = nullBool(hasMoonlight)
+		item.HasMagTailcap = nullBool(hasMagTailcap)
+		item.HasPocketClip = nullBool(hasPocketClip)
+		item.SwitchType = nullString(switchType)
+		item.HasTailSwitch = switchHas(item.SwitchType, "tail")
+		item.HasSideSwitch = switchHas(item.SwitchType, "side")
+		item.LEDModel = nullString(ledModel)
+		item.CRI = nullInt(cri)
+		item.CCTMinK = nullInt(cctMinK)
+		item.CCTMaxK = nullInt(cctMaxK)
 		item.PriceUSD = nullFloat(price)
+		item.AmazonRatingCount = nullInt(amazonRatingCount)
+		item.AmazonAverageRating = nullFloat(amazonAvgRating)
+		item.PriceLastUpdatedAt = nullTimeString(priceUpdatedAt)
+		item.AmazonLastSyncedAt = nullTimeString(amazonSyncedAt)
 		item.TacticalScore = nullFloat(tactical)
 		item.EDCScore = nullFloat(edc)
 		item.ValueScore = nullFloat(value)
 		item.ThrowScore = nullFloat(throw)
 		item.FloodScore = nullFloat(flood)
+		item.BatteryTypes = decodeJSONStringArray(batteryTypesJSON)
+		item.ImageURLs = decodeJSONStringArray(imageURLsJSON)
+		item.Modes = decodeModesJSON(modesJSON)
+		item.UseCaseTags = decodeJSONStringArray(useCaseTagsJSON)
 		items = append(items, item)
 	}
 	return items, rows.Err()
 }
 
-func (s *Server) rankings(ctx context.Context, useCase string, page, pageSize int) ([]rankedResponse, int, error) {
-	offset := (page - 1) * pageSize
-	query := `
-WITH latest_run AS (
-	SELECT id
-	FROM scoring_runs
-	WHERE status = 'completed'
-	ORDER BY completed_at DESC NULLS LAST, id DESC
-	LIMIT 1
-),
-selected_profile AS (
+// resolveFlashlightSlug returns the id and current slug for slug, following
+// flashlight_slug_history when slug has been retired.
+func (s *Server) resolveFlashlightSlug(ctx context.Context, slug string) (int64, string, error) {
+	var (
+		id        int64
+		canonical string
+	)
+	err := s.db.QueryRowContext(ctx, `
+SELECT id, slug
+FROM (
+	SELECT f.id, f.slug, 0 AS priority
+	FROM flashlights f
+	WHERE f.slug = $1
+	  AND f.is_active = TRUE
+	UNION ALL
+	SELECT f.id, f.slug, 1 AS priority
+	FROM flashlight_slug_history h
+	JOIN flashlights f ON f.id = h.flashlight_id
+	WHERE h.old_slug = $1
+	  AND f.is_active = TRUE
+) matches
+ORDER BY priority
+LIMIT 1
+`, slug).Scan(&id, &canonical)
+	return id, canonical, err
+}
+
+// rankings orders active lights by their score in the use case's profile.
+// Scores come from the run the read model was built from rather than the
+// pivoted columns, so profiles outside the pivot such as overall work too.
+// rankingScore is the key /rankings orders by, descending, then by id.
+const rankingScore = "COALESCE(fs.score, 0)"
+
+// rankings reads one page of a use case's ranking: by number with a count
+// when c is nil, otherwise the page next to the cursor, scored by the
+// cursor's run.
+func (s *Server) rankings(ctx context.Context, useCase string, page, pageSize int, c *cursor, rg region) (listPage[rankedResponse], error) {
+	args := []any{useCase}
+	run, keyset := latestRunSQL, ""
+	limit := fmt.Sprintf("LIMIT %d OFFSET %d", pageSize, (page-1)*pageSize)
+	if c != nil {
+		args = append(args, c.Run)
+		run = "$2::BIGINT"
+		cond, condArgs := keysetCondition(rankingScore, "f.id", true, *c, 3)
+		keyset = "AND " + cond
+		args = append(args, condArgs...)
+		limit = fmt.Sprintf("LIMIT %d", pageSize+1)
+	}
+
+	query := fmt.Sprintf(`
+WITH selected_profile AS (
 	SELECT id, slug
 	FROM scoring_profiles
 	WHERE slug = $1
 	LIMIT 1
+),
+pinned_run AS (
+	SELECT %[1]s AS id
 )
 SELECT
-	ROW_NUMBER() OVER (ORDER BY COALESCE(fs.score, 0) DESC, f.id ASC) AS rank_position,
-	COALESCE(fs.score, 0) AS score,
+	%[2]s AS score,
 	sp.slug,
 	f.id,
 	b.name,
 	f.name,
 	f.slug,
-	lm.url,
-	la.affiliate_url
+	rm.image_url,
+	%[3]s,
+	pr.id
 FROM flashlights f
 JOIN brands b ON b.id = f.brand_id
 JOIN selected_profile sp ON TRUE
+JOIN pinned_run pr ON TRUE
+LEFT JOIN flashlight_read_model rm ON rm.flashlight_id = f.id
 LEFT JOIN flashlight_scores fs ON fs.flashlight_id = f.id
 	AND fs.profile_id = sp.id
-	AND fs.run_id = (SELECT id FROM latest_run)
-LEFT JOIN LATERAL (
-	SELECT m.url
-	FROM flashlight_media m
-	WHERE m.flashlight_id = f.id
-	  AND m.media_type = 'image'
-	ORDER BY m.sort_order ASC, m.id ASC
-	LIMIT 1
-) lm ON TRUE
-LEFT JOIN LATERAL (
-	SELECT al.affiliate_url
-	FROM affiliate_links al
-	WHERE al.flashlight_id = f.id
-	  AND al.provider = 'amazon'
-	  AND al.region_code = 'US'
-	  AND al.is_active = TRUE
-	ORDER BY al.is_primary DESC, al.updated_at DESC, al.id DESC
-	LIMIT 1
-) la ON TRUE
+	AND fs.run_id = pr.id
 WHERE f.is_active = TRUE
-ORDER BY rank_position ASC
-LIMIT $2 OFFSET $3
-`
+%[4]s
+ORDER BY %[5]s
+%[6]s
+`, run, rankingScore, rg.offerColumns(), keyset, keysetOrder(rankingScore, "f.id", true, c), limit)
 
-	rows, err := s.db.QueryContext(ctx, query, useCase, pageSize, offset)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return listPage[rankedResponse]{}, err
 	}
 	defer rows.Close()
 
-	out := make([]rankedResponse, 0, pageSize)
+	out := make([]rankedResponse, 0, pageSize+1)
+	var runID int64
 	for rows.Next() {
 		var (
-			item                rankedResponse
-			imageURL, amazonURL sql.NullString
+			item                         rankedResponse
+			imageURL, amazonURL          sql.NullString
+			linkRegion, regionalCurrency sql.NullString
+			regionalPrice                sql.NullFloat64
 		)
 		if err := rows.Scan(
-			&item.Rank,
 			&item.Score,
 			&item.Profile,
 			&item.Flashlight.ID,
@@ -717,18 +589,56 @@ LIMIT $2 OFFSET $3
 			&item.Flashlight.Slug,
 			&imageURL,
 			&amazonURL,
+			&linkRegion,
+			&regionalPrice,
+			&regionalCurrency,
+			&runID,
 		); err != nil {
-			return nil, 0, err
+			return listPage[rankedResponse]{}, err
 		}
 		item.Flashlight.ImageURL = nullString(imageURL)
 		item.Flashlight.AmazonURL = nullString(amazonURL)
+		rg.setOffer(&item.Flashlight.RegionalOffer, linkRegion, regionalPrice, regionalCurrency)
 		out = append(out, item)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, 0, err
+		return listPage[rankedResponse]{}, err
 	}
 
-	countQuery := `
+	var (
+		result           listPage[rankedResponse]
+		first            int // rank of the page's first row
+		hasNext, hasPrev bool
+	)
+	if c != nil {
+		var more bool
+		result.items, more = keysetPage(out, pageSize, c)
+		hasNext, hasPrev = more || c.Before, more || !c.Before
+		first = c.Rank + 1
+		if c.Before {
+			first = c.Rank - len(result.items)
+		}
+	} else {
+		total, err := s.countRankings(ctx, useCase)
+		if err != nil {
+			return listPage[rankedResponse]{}, err
+		}
+		result.items, result.total = out, &total
+		first = (page-1)*pageSize + 1
+		hasNext, hasPrev = first-1+len(out) < total, page > 1
+	}
+	keys := make([]keysetRow, len(result.items))
+	for i := range result.items {
+		result.items[i].Rank = first + i
+		score := result.items[i].Score
+		keys[i] = keysetRow{key: &score, id: result.items[i].Flashlight.ID, rank: first + i}
+	}
+	result.next, result.prev = pageCursors(cursor{Sort: useCase, Run: runID}, keys, hasNext, hasPrev)
+	return result, nil
+}
+
+func (s *Server) countRankings(ctx context.Context, useCase string) (int, error) {
+	const query = `
 WITH selected_profile AS (
 	SELECT id
 	FROM scoring_profiles
@@ -741,19 +651,19 @@ JOIN selected_profile sp ON TRUE
 WHERE f.is_active = TRUE
 `
 	var total int
-	if err := s.db.QueryRowContext(ctx, countQuery, useCase).Scan(&total); err != nil {
-		return nil, 0, err
+	if err := s.db.QueryRowContext(ctx, query, useCase).Scan(&total); err != nil {
+		return 0, err
 	}
-	return out, total, nil
+	return total, nil
 }
 
-func (s *Server) finder(ctx context.Context, filters finderFilters, limit int) ([]finderRanking, error) {
+func (s *Server) finder(ctx context.Context, filters finderFilters, limit int, rg region) ([]finderRanking, error) {
 	clauses := []string{"f.is_active = TRUE"}
 	args := make([]any, 0, 5)
 	argn := 1
 
 	if filters.Budget != nil {
-		clauses = append(clauses, fmt.Sprintf("lp.price <= $%d", argn))
+		clauses = append(clauses, fmt.Sprintf("rm.price_usd <= $%d", argn))
 		args = append(args, *filters.Budget)
 		argn++
 	}
@@ -770,67 +680,29 @@ func (s *Server) finder(ctx context.Context, filters finderFilters, limit int) (
 	where := "WHERE " + strings.Join(clauses, " AND ")
 
 	query := fmt.Sprintf(`
-WITH latest_run AS (
-	SELECT id
-	FROM scoring_runs
-	WHERE status = 'completed'
-	ORDER BY completed_at DESC NULLS LAST, id DESC
-	LIMIT 1
-),
-latest_price AS (
-	SELECT DISTINCT ON (p.flashlight_id)
-		p.flashlight_id,
-		p.price
-	FROM flashlight_price_snapshots p
-	WHERE p.currency_code = 'USD'
-	ORDER BY p.flashlight_id, p.captured_at DESC
-),
-latest_affiliate AS (
-	SELECT DISTINCT ON (a.flashlight_id)
-		a.flashlight_id,
-		a.affiliate_url
-	FROM affiliate_links a
-	WHERE a.provider = 'amazon'
-	  AND a.region_code = 'US'
-	  AND a.is_active = TRUE
-	ORDER BY a.flashlight_id, a.is_primary DESC, a.updated_at DESC, a.id DESC
-),
-latest_scores AS (
-	SELECT
-		fs.flashlight_id,
-		MAX(CASE WHEN sp.slug = 'tactical' THEN fs.score END) AS tactical_score,
-		MAX(CASE WHEN sp.slug = 'throw' THEN fs.score END) AS throw_score,
-		MAX(CASE WHEN sp.slug = 'value' THEN fs.score END) AS value_score
-	FROM flashlight_scores fs
-	JOIN scoring_profiles sp ON sp.id = fs.profile_id
-	JOIN latest_run lr ON lr.id = fs.run_id
-	GROUP BY fs.flashlight_id
-)
 SELECT
 	f.id,
 	b.name,
 	f.name,
-	la.affiliate_url,
-	lp.price,
+	%s,
+	rm.price_usd,
 	s.beam_distance_m,
-	ls.tactical_score,
-	ls.throw_score,
-	ls.value_score,
+	rm.tactical_score,
+	rm.throw_score,
+	rm.value_score,
 	(
-		COALESCE(ls.tactical_score, 0) * 0.50 +
-		COALESCE(ls.throw_score, 0) * 0.30 +
-		COALESCE(ls.value_score, 0) * 0.20
+		COALESCE(rm.tactical_score, 0) * 0.50 +
+		COALESCE(rm.throw_score, 0) * 0.30 +
+		COALESCE(rm.value_score, 0) * 0.20
 	) AS finder_score
 FROM flashlights f
 JOIN brands b ON b.id = f.brand_id
 LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
-LEFT JOIN latest_price lp ON lp.flashlight_id = f.id
-LEFT JOIN latest_affiliate la ON la.flashlight_id = f.id
-LEFT JOIN latest_scores ls ON ls.flashlight_id = f.id
+LEFT JOIN flashlight_read_model rm ON rm.flashlight_id = f.id
 %s
 ORDER BY finder_score DESC, f.id ASC
 LIMIT %d
-`, where, limit)
+`, rg.offerColumns(), where, limit)
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -843,6 +715,8 @@ LIMIT %d
 		var (
 			item                          finderRanking
 			amazonURL                     sql.NullString
+			linkRegion, regionalCurrency  sql.NullString
+			regionalPrice                 sql.NullFloat64
 			price, tactical, throw, value sql.NullFloat64
 			beam                          sql.NullInt64
 		)
@@ -851,6 +725,9 @@ LIMIT %d
 			&item.Brand,
 			&item.Name,
 			&amazonURL,
+			&linkRegion,
+			&regionalPrice,
+			&regionalCurrency,
 			&price,
 			&beam,
 			&tactical,
@@ -861,6 +738,7 @@ LIMIT %d
 			return nil, err
 		}
 		item.AmazonURL = nullString(amazonURL)
+		rg.setOffer(&item.RegionalOffer, linkRegion, regionalPrice, regionalCurrency)
 		item.PriceUSD = nullFloat(price)
 		item.BeamDistanceM = nullInt(beam)
 		item.TacticalScore = nullFloat(tactical)
@@ -872,12 +750,13 @@ LIMIT %d
 }
 
 type intelligenceCandidate struct {
-	ID               int64
-	Brand            string
-	Name             string
-	Category         string
-	ImageURL         *string
-	AmazonURL        *string
+	ID        int64
+	Brand     string
+	Name      string
+	Category  string
+	ImageURL  *string
+	AmazonURL *string
+	regionalOffer
 	PriceUSD         *float64
 	MaxLumens        *int64
 	MaxCandela       *int64
@@ -895,8 +774,8 @@ type intelligenceCandidate struct {
 	FloodScore       *float64
 }
 
-func (s *Server) createIntelligenceRun(ctx context.Context, req intelligenceRunRequest) (intelligenceRunResponse, error) {
-	candidates, err := s.intelligenceCandidates(ctx)
+func (s *Server) createIntelligenceRun(ctx context.Context, req intelligenceRunRequest, rg region) (intelligenceRunResponse, error) {
+	candidates, err := s.intelligenceCandidates(ctx, rg)
 	if err != nil {
 		return intelligenceRunResponse{}, err
 	}
@@ -946,8 +825,8 @@ RETURNING id, created_at
 	}, nil
 }
 
-func (s *Server) intelligenceRecommendations(ctx context.Context, req intelligenceRunRequest) (intelligenceResponse, error) {
-	candidates, err := s.intelligenceCandidates(ctx)
+func (s *Server) intelligenceRecommendations(ctx context.Context, req intelligenceRunRequest, rg region) (intelligenceResponse, error) {
+	candidates, err := s.intelligenceCandidates(ctx, rg)
 	if err != nil {
 		return intelligenceResponse{}, err
 	}
@@ -1005,55 +884,9 @@ WHERE id = $1
 	return resp, nil
 }
 
-func (s *Server) intelligenceCandidates(ctx context.Context) ([]intelligenceCandidate, error) {
-	const q = `
-WITH latest_run AS (
-	SELECT id
-	FROM scoring_runs
-	WHERE status = 'completed'
-	ORDER BY completed_at DESC NULLS LAST, id DESC
-	LIMIT 1
-),
-latest_price AS (
-	SELECT DISTINCT ON (p.flashlight_id)
-		p.flashlight_id,
-		p.price
-	FROM flashlight_price_snapshots p
-	WHERE p.currency_code = 'USD'
-	ORDER BY p.flashlight_id, p.captured_at DESC
-),
-latest_affiliate AS (
-	SELECT DISTINCT ON (a.flashlight_id)
-		a.flashlight_id,
-		a.affiliate_url
-	FROM affiliate_links a
-	WHERE a.provider = 'amazon'
-	  AND a.region_code = 'US'
-	  AND a.is_active = TRUE
-	ORDER BY a.flashlight_id, a.is_primary DESC, a.updated_at DESC, a.id DESC
-),
-latest_media AS (
-	SELECT DISTINCT ON (m.flashlight_id)
-		m.flashlight_id,
-		m.url
-	FROM flashlight_media m
-	WHERE m.media_type = 'image'
-	ORDER BY m.flashlight_id, m.sort_order ASC, m.id ASC
-),
-latest_scores AS (
-	SELECT
-		fs.flashlight_id,
-		MAX(CASE WHEN sp.slug = 'tactical' THEN fs.score END) AS tactical_score,
-		MAX(CASE WHEN sp.slug = 'edc' THEN fs.score END) AS edc_score,
-		MAX(CASE WHEN sp.slug = 'value' THEN fs.score END) AS value_score,
-		MAX(CASE WHEN sp.slug = 'throw' THEN fs.score END) AS throw_score,
-		MAX(CASE WHEN sp.slug = 'flood' THEN fs.score END) AS flood_score
-	FROM flashlight_scores fs
-	JOIN scoring_profiles sp ON sp.id = fs.profile_id
-	JOIN latest_run lr ON lr.id = fs.run_id
-	GROUP BY fs.flashlight_id
-),
-battery_choice AS (
+func (s *Server) intelligenceCandidates(ctx context.Context, rg region) ([]intelligenceCandidate, error) {
+	q := fmt.Sprintf(`
+WITH battery_choice AS (
 	SELECT
 		fbc.flashlight_id,
 		MIN(bt.code) AS battery_code
@@ -1066,9 +899,9 @@ SELECT
 	b.name,
 	f.name,
 	COALESCE(uc.slug, 'general') AS category,
-	lm.url,
-	la.affiliate_url,
-	lp.price,
+	rm.image_url,
+	%s,
+	rm.price_usd,
 	s.max_lumens,
 	s.max_candela,
 	s.beam_distance_m,
@@ -1078,18 +911,15 @@ SELECT
 	s.length_mm,
 	s.waterproof_rating,
 	bc.battery_code,
-	ls.tactical_score,
-	ls.edc_score,
-	ls.value_score,
-	ls.throw_score,
-	ls.flood_score
+	rm.tactical_score,
+	rm.edc_score,
+	rm.value_score,
+	rm.throw_score,
+	rm.flood_score
 FROM flashlights f
 JOIN brands b ON b.id = f.brand_id
 LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
-LEFT JOIN latest_price lp ON lp.flashlight_id = f.id
-LEFT JOIN latest_affiliate la ON la.flashlight_id = f.id
-LEFT JOIN latest_media lm ON lm.flashlight_id = f.id
-LEFT JOIN latest_scores ls ON ls.flashlight_id = f.id
+LEFT JOIN flashlight_read_model rm ON rm.flashlight_id = f.id
 LEFT JOIN battery_choice bc ON bc.flashlight_id = f.id
 LEFT JOIN LATERAL (
 	SELECT u.slug
@@ -1100,7 +930,7 @@ LEFT JOIN LATERAL (
 	LIMIT 1
 ) uc ON TRUE
 WHERE f.is_active = TRUE
-`
+`, rg.offerColumns())
 	rows, err := s.db.QueryContext(ctx, q)
 	if err != nil {
 		return nil, err
@@ -1112,6 +942,8 @@ WHERE f.is_active = TRUE
 		var (
 			item                                                             intelligenceCandidate
 			imageURL, amazonURL, waterproof, batteryCode                     sql.NullString
+			linkRegion, regionalCurrency                                     sql.NullString
+			regionalPrice                                                    sql.NullFloat64
 			price, weight, lengthMM, tactical, edc, value, throwScore, flood sql.NullFloat64
 			maxLumens, maxCandela, beam, runtimeHigh, runtimeMedium          sql.NullInt64
 		)
@@ -1122,6 +954,9 @@ WHERE f.is_active = TRUE
 			&item.Category,
 			&imageURL,
 			&amazonURL,
+			&linkRegion,
+			&regionalPrice,
+			&regionalCurrency,
 			&price,
 			&maxLumens,
 			&maxCandela,
@@ -1142,6 +977,7 @@ WHERE f.is_active = TRUE
 		}
 		item.ImageURL = nullString(imageURL)
 		item.AmazonURL = nullString(amazonURL)
+		rg.setOffer(&item.regionalOffer, linkRegion, regionalPrice, regionalCurrency)
 		item.PriceUSD = nullFloat(price)
 		item.MaxLumens = nullInt(maxLumens)
 		item.MaxCandela = nullInt(maxCandela)
@@ -1177,6 +1013,7 @@ func rankIntelligence(candidates []intelligenceCandidate, req intelligenceRunReq
 			Category:          c.Category,
 			ImageURL:          c.ImageURL,
 			AmazonURL:         c.AmazonURL,
+			RegionalOffer:     c.regionalOffer,
 			PriceUSD:          c.PriceUSD,
 			MaxLumens:         c.MaxLumens,
 			MaxCandela:        c.MaxCandela,
@@ -1297,47 +1134,10 @@ func round1(v float64) float64 {
 	return math.Round(v*10) / 10
 }
 
-func buildFlashlightWhere(f flashlightFilters) (string, []any) {
-	clauses := []string{"f.is_active = TRUE"}
-	args := make([]any, 0, 6)
-	argn := 1
-
-	if f.BatteryType != "" {
-		clauses = append(clauses, fmt.Sprintf(`
-EXISTS (
-	SELECT 1
-	FROM flashlight_battery_compatibility fbc
-	JOIN battery_types bt ON bt.id = fbc.battery_type_id
-	WHERE fbc.flashlight_id = f.id
-	  AND bt.code = $%d
-)`, argn))
-		args = append(args, strings.ToUpper(f.BatteryType))
-		argn++
-	}
-
-	if f.MinPrice != nil {
-		clauses = append(clauses, fmt.Sprintf("lp.price >= $%d", argn))
-		args = append(args, *f.MinPrice)
-		argn++
-	}
-	if f.MaxPrice != nil {
-		clauses = append(clauses, fmt.Sprintf("lp.price <= $%d", argn))
-		args = append(args, *f.MaxPrice)
-		argn++
-	}
-	if f.IPRating != "" {
-		clauses = append(clauses, fmt.Sprintf("s.waterproof_rating = $%d", argn))
-		args = append(args, strings.ToUpper(f.IPRating))
-		argn++
-	}
-
-	return "WHERE " + strings.Join(clauses, " AND "), args
-}
-
 func sortColumn(sortBy string) string {
 	switch strings.ToLower(strings.TrimSpace(sortBy)) {
 	case "price":
-		return "lp.price"
+		return "rm.price_usd"
 	case "max_lumens":
 		return "s.max_lumens"
 	case "max_candela":
@@ -1345,17 +1145,17 @@ func sortColumn(sortBy string) string {
 	case "runtime_high_min":
 		return "s.runtime_high_min"
 	case "tactical_score":
-		return "ls.tactical_score"
+		return "rm.tactical_score"
 	case "edc_score":
-		return "ls.edc_score"
+		return "rm.edc_score"
 	case "value_score":
-		return "ls.value_score"
+		return "rm.value_score"
 	case "throw_score":
-		return "ls.throw_score"
+		return "rm.throw_score"
 	case "flood_score":
-		return "ls.flood_score"
+		return "rm.flood_score"
 	default:
-		return "ls.tactical_score"
+		return "rm.tactical_score"
 	}
 }
 
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	maxSearchTokens   = 8
+	maxSearchTokenLen = 40
+)
+
+type searchHit struct {
+	ID        int64    `json:"id"`
+	Brand     string   `json:"brand"`
+	Name      string   `json:"name"`
+	Slug      string   `json:"slug"`
+	ModelCode *string  `json:"model_code,omitempty"`
+	LEDModel  *string  `json:"led_model,omitempty"`
+	ImageURL  *string  `json:"image_url,omitempty"`
+	PriceUSD  *float64 `json:"price_usd,omitempty"`
+	Highlight string   `json:"highlight"`
+	Score     float64  `json:"score"`
+}
+
+type searchResponse struct {
+	Query string      `json:"query"`
+	Items []searchHit `json:"items"`
+}
+
+type searchSuggestion struct {
+	ID    int64  `json:"id"`
+	Slug  string `json:"slug"`
+	Label string `json:"label"`
+}
+
+type searchSuggestResponse struct {
+	Query       string             `json:"query"`
+	Suggestions []searchSuggestion `json:"suggestions"`
+}
+
+// maxSearchQuery bounds q so a single request cannot build a huge query.
+const maxSearchQuery = 200
+
+var (
+	searchParams  = []param{{Name: "q", Type: typeString, MaxLen: maxSearchQuery, Required: true}, intParam("limit", 20, 1, 50)}
+	suggestParams = []param{stringParam("q", maxSearchQuery), intParam("limit", 8, 1, 20)}
+)
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	q, ok := readQuery(w, r, searchParams)
+	if !ok {
+		return
+	}
+	raw := q.str("q")
+	tokens := searchTokens(raw)
+	if len(tokens) == 0 {
+		writeBadRequest(w, r, invalidField("q", raw, "must contain at least one letter or digit"))
+		return
+	}
+	limit := q.int("limit")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := s.search(ctx, tokens, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to search flashlights"})
+		return
+	}
+	writeJSON(w, http.StatusOK, searchResponse{Query: raw, Items: items})
+}
+
+func (s *Server) handleSearchSuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	q, ok := readQuery(w, r, suggestParams)
+	if !ok {
+		return
+	}
+	raw := q.str("q")
+	tokens := searchTokens(raw)
+	if len(tokens) == 0 {
+		writeJSON(w, http.StatusOK, searchSuggestResponse{Query: raw, Suggestions: []searchSuggestion{}})
+		return
+	}
+	limit := q.int("limit")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	suggestions, err := s.searchSuggest(ctx, tokens, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch suggestions"})
+		return
+	}
+	writeJSON(w, http.StatusOK, searchSuggestResponse{Query: raw, Suggestions: suggestions})
+}
+
+// A fuzzy match needs at least this trigram similarity: searchFuzzy for
+// /search, suggestFuzzy for /search/suggest. The trigram operators compare
+// against pg_trgm's threshold settings rather than a literal, so searchTx
+// sets those for the query's transaction.
+const (
+	searchFuzzy  = 0.35
+	suggestFuzzy = 0.5
+)
+
+// searchDocs is shared by /search and /search/suggest. It selects the lights
+// whose search document in the read model matches: search_title holds brand,
+// name and model code, and search_compact strips separators from title and LED
+// model so that "pd 36r" and "pd36r" meet in the middle. Each condition is on
+// one read model column with an operator its GIN index supports, so the
+// planner can combine index scans instead of reading every document. $1 is
+// the prefix tsquery, $2 the phrase and $3 the compact phrase.
+const searchDocs = `
+search_docs AS (
+	SELECT
+		f.id,
+		b.name::TEXT AS brand,
+		f.name,
+		f.slug,
+		f.model_code,
+		f.description,
+		s.led_model,
+		rm.image_url,
+		rm.price_usd,
+		rm.search_doc AS doc,
+		rm.search_title AS title,
+		rm.search_model_code,
+		rm.search_led_model,
+		rm.search_compact AS compact
+	FROM flashlight_read_model rm
+	JOIN flashlights f ON f.id = rm.flashlight_id
+	JOIN brands b ON b.id = f.brand_id
+	LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
+	WHERE f.is_active = TRUE
+	  AND (
+		rm.search_doc @@ to_tsquery('simple', $1::TEXT)
+		OR $2::TEXT <% rm.search_title
+		OR rm.search_model_code % $2::TEXT
+		OR rm.search_led_model % $2::TEXT
+		OR rm.search_compact LIKE '%' || $3::TEXT || '%'
+	  )
+)`
+
+// searchTx begins the read-only transaction a search runs in, with pg_trgm's
+// similarity thresholds set to threshold until it ends.
+func (s *Server) searchTx(ctx context.Context, threshold float64) (*sql.Tx, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	t := strconv.FormatFloat(threshold, 'f', -1, 64)
+	if _, err := tx.ExecContext(ctx, `
+SELECT
+	set_config('pg_trgm.similarity_threshold', $1, true),
+	set_config('pg_trgm.word_similarity_threshold', $1, true)
+`, t); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (s *Server) search(ctx context.Context, tokens []string, limit int) ([]searchHit, error) {
+	tx, err := s.searchTx(ctx, searchFuzzy)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+WITH` + searchDocs + `,
+q AS (
+	SELECT to_tsquery('simple', $1) AS tsq, $2::TEXT AS phrase, $3::TEXT AS compact
+),
+scored AS (
+	SELECT
+		d.*,
+		ts_rank_cd(d.doc, q.tsq) AS text_rank,
+		GREATEST(
+			word_similarity(q.phrase, d.title),
+			similarity(d.search_model_code, q.phrase),
+			similarity(d.search_led_model, q.phrase),
+			CASE WHEN strpos(d.compact, q.compact) > 0 THEN 1.0 ELSE 0 END
+		) AS fuzzy_rank,
+		d.doc @@ q.tsq AS text_match
+	FROM search_docs d, q
+)
+SELECT
+	sc.id,
+	sc.brand,
+	sc.name,
+	sc.slug,
+	sc.model_code,
+	sc.led_model,
+	sc.image_url,
+	sc.price_usd,
+	CASE
+		WHEN sc.text_match THEN ts_headline('simple',
+			sc.brand || ' ' || sc.name || COALESCE(' — ' || sc.description, ''),
+			q.tsq,
+			'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, ShortWord=1')
+		ELSE sc.brand || ' ' || sc.name
+	END AS highlight,
+	(sc.text_rank * 2 + sc.fuzzy_rank)::FLOAT8 AS score
+FROM scored sc
+CROSS JOIN q
+ORDER BY score DESC, sc.id ASC
+LIMIT $4
+`
+	rows, err := tx.QueryContext(ctx, query, prefixTSQuery(tokens), strings.Join(tokens, " "), strings.Join(tokens, ""), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]searchHit, 0, limit)
+	for rows.Next() {
+		var (
+			hit                           searchHit
+			modelCode, ledModel, imageURL sql.NullString
+			price                         sql.NullFloat64
+		)
+		if err := rows.Scan(
+			&hit.ID,
+			&hit.Brand,
+			&hit.Name,
+			&hit.Slug,
+			&modelCode,
+			&ledModel,
+			&imageURL,
+			&price,
+			&hit.Highlight,
+			&hit.Score,
+		); err != nil {
+			return nil, err
+		}
+		hit.ModelCode = nullString(modelCode)
+		hit.LEDModel = nullString(ledModel)
+		hit.ImageURL = nullString(imageURL)
+		hit.PriceUSD = nullFloat(price)
+		hit.Score = round1(hit.Score * 100)
+		items = append(items, hit)
+	}
+	return items, rows.Err()
+}
+
+func (s *Server) searchSuggest(ctx context.Context, tokens []string, limit int) ([]searchSuggestion, error) {
+	tx, err := s.searchTx(ctx, suggestFuzzy)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+WITH` + searchDocs + `
+SELECT
+	d.id,
+	d.slug,
+	d.brand || ' ' || d.name AS label
+FROM search_docs d
+WHERE d.doc @@ to_tsquery('simple', $1::TEXT)
+   OR strpos(d.compact, $3::TEXT) > 0
+   OR $2::TEXT <% d.title
+ORDER BY
+	(d.title LIKE $2 || '%' OR lower(d.name) LIKE $2 || '%') DESC,
+	word_similarity($2, d.title) DESC,
+	d.id ASC
+LIMIT $4
+`
+	rows, err := tx.QueryContext(ctx, query, prefixTSQuery(tokens), strings.Join(tokens, " "), strings.Join(tokens, ""), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]searchSuggestion, 0, limit)
+	for rows.Next() {
+		var sg searchSuggestion
+		if err := rows.Scan(&sg.ID, &sg.Slug, &sg.Label); err != nil {
+			return nil, err
+		}
+		out = append(out, sg)
+	}
+	return out, rows.Err()
+}
+
+// searchTokens lowercases the query and splits it on anything that is not a
+// letter or digit. Only the resulting tokens ever reach to_tsquery, so user
+// input cannot inject tsquery operators.
+func searchTokens(q string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if r := []rune(f); len(r) > maxSearchTokenLen {
+			f = string(r[:maxSearchTokenLen])
+		}
+		out = append(out, f)
+		if len(out) == maxSearchTokens {
+			break
+		}
+	}
+	return out
+}
+
+func prefixTSQuery(tokens []string) string {
+	parts := make([]string, len(tokens))
+	for i, t := range tokens {
+		parts[i] = t + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchTokens(t *testing.T) {
+	got := searchTokens("  Fenix PD-36R  'pro'; DROP ")
+	if strings.Join(got, ",") != "fenix,pd,36r,pro,drop" {
+		t.Fatalf("unexpected tokens %v", got)
+	}
+	if q := prefixTSQuery(got[:2]); q != "fenix:* & pd:*" {
+		t.Fatalf("unexpected tsquery %q", q)
+	}
+	if len(searchTokens("a b c d e f g h i j")) != maxSearchTokens {
+		t.Fatalf("expected tokens capped at %d", maxSearchTokens)
+	}
+	if len(searchTokens("!!! -- ::")) != 0 {
+		t.Fatalf("expected no tokens for punctuation-only query")
+	}
+}
@@ -7,302 +7,368 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"flashlight-ratings-go/internal/alerts"
+	"flashlight-ratings-go/internal/apikeys"
+	"flashlight-ratings-go/internal/catalog"
+	"flashlight-ratings-go/pkg/client"
 )
 
+type Config struct {
+	// PublicBaseURL is the address clients use to reach the API. It is used
+	// to build the links in emails.
+	PublicBaseURL string
+	// Mailer sends alert confirmation emails. Defaults to alerts.LogSender.
+	Mailer alerts.Sender
+	// AdminTokens maps bearer tokens accepted by /admin to the actor name
+	// recorded in the audit log. With no tokens every /admin request is
+	// rejected.
+	AdminTokens map[string]string
+	// AmazonPartnerTag tags affiliate links written through /admin.
+	AmazonPartnerTag string
+	// AnonymousRatePerMinute and AnonymousBurst limit requests without an
+	// API key, per client address. Default 120 per minute, burst 60.
+	AnonymousRatePerMinute int
+	AnonymousBurst         int
+	// TrustProxyHeaders takes the client address from X-Real-IP. Only enable
+	// it behind a proxy that sets the header.
+	TrustProxyHeaders bool
+	// Freshness SLAs checked by /status. Defaults: the latest scoring run and
+	// Amazon sync at most 3 hours old, and at most 5% of Amazon listings with
+	// a USD price older than 24 hours.
+	MaxScoringAge      time.Duration
+	MaxSyncAge         time.Duration
+	PriceStaleAfter    time.Duration
+	MaxStalePriceShare float64
+	// CORSAllowedOrigins lists the origins browsers may call the API from,
+	// e.g. https://flashlightratings.com. "*" allows any origin. Empty
+	// disables CORS, which suits a site that reaches the API through its own
+	// proxy.
+	CORSAllowedOrigins []string
+	// LegacySunset, when set, is announced in a Sunset header on requests
+	// to unversioned paths: the date their /v1 aliases stop answering.
+	LegacySunset time.Time
+}
+
 type Server struct {
-	db *sql.DB
+	db           *sql.DB
+	alerts       *alerts.Subscriptions
+	catalog      *catalog.Builder
+	adminTokens  map[string]string
+	keys         *apikeys.Store
+	buckets      *apikeys.Buckets
+	usage        *apikeys.Usage
+	cache        *responseCache
+	anonRate     int
+	anonBurst    int
+	trustProxy   bool
+	sla          freshnessSLA
+	cors         corsPolicy
+	draining     atomic.Bool
+	legacySunset time.Time
 }
 
-func NewServer(db *sql.DB) *Server {
-	return &Server{db: db}
+func NewServer(db *sql.DB, cfg Config) *Server {
+	if cfg.AnonymousRatePerMinute <= 0 {
+		cfg.AnonymousRatePerMinute = 120
+	}
+	if cfg.AnonymousBurst <= 0 {
+		cfg.AnonymousBurst = 60
+	}
+	if cfg.MaxScoringAge <= 0 {
+		cfg.MaxScoringAge = 3 * time.Hour
+	}
+	if cfg.MaxSyncAge <= 0 {
+		cfg.MaxSyncAge = 3 * time.Hour
+	}
+	if cfg.PriceStaleAfter <= 0 {
+		cfg.PriceStaleAfter = 24 * time.Hour
+	}
+	if cfg.MaxStalePriceShare <= 0 {
+		cfg.MaxStalePriceShare = 0.05
+	}
+	s := &Server{
+		db:          db,
+		alerts:      alerts.NewSubscriptions(db, cfg.Mailer, strings.TrimRight(cfg.PublicBaseURL, "/")+apiVersion),
+		catalog:     catalog.NewBuilder(db, cfg.AmazonPartnerTag),
+		adminTokens: cfg.AdminTokens,
+		keys:        apikeys.NewStore(db, time.Minute),
+		buckets:     apikeys.NewBuckets(),
+		usage:       apikeys.NewUsage(db, 10*time.Second),
+		anonRate:    cfg.AnonymousRatePerMinute,
+		anonBurst:   cfg.AnonymousBurst,
+		trustProxy:  cfg.TrustProxyHeaders,
+		sla: freshnessSLA{
+			scoringMaxAge:   cfg.MaxScoringAge,
+			syncMaxAge:      cfg.MaxSyncAge,
+			priceStaleAfter: cfg.PriceStaleAfter,
+			maxStaleShare:   cfg.MaxStalePriceShare,
+		},
+		cors:         newCORSPolicy(cfg.CORSAllowedOrigins),
+		legacySunset: cfg.LegacySunset,
+	}
+	s.cache = newResponseCache(s.loadDataStamp)
+	return s
 }
 
 func (s *Server) Routes() http.Handler {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/flashlights", s.handleFlashlights)
-	mux.HandleFunc("/flashlights/", s.handleFlashlightByID)
-	mux.HandleFunc("/compare", s.handleCompare)
-	mux.HandleFunc("/rankings", s.handleRankings)
-	mux.HandleFunc("/finder", s.handleFinder)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/parameters", s.handleParameters)
+	mux.HandleFunc("/openapi.json", s.handleOpenAPI)
+	mux.HandleFunc("/flashlights", s.cached(s.handleFlashlights))
+	mux.HandleFunc("/flashlights/", s.cached(s.handleFlashlightByID))
+	mux.HandleFunc("/flashlights/by-slug/", s.cached(s.handleFlashlightBySlug))
+	mux.HandleFunc("/search", s.cached(s.handleSearch))
+	mux.HandleFunc("/search/suggest", s.cached(s.handleSearchSuggest))
+	mux.HandleFunc("/compare", s.cached(s.handleCompare))
+	mux.HandleFunc("/versus/", s.cached(s.handleVersus))
+	mux.HandleFunc("/brands", s.cached(s.handleBrands))
+	mux.HandleFunc("/brands/", s.cached(s.handleBrandBySlug))
+	mux.HandleFunc("/use-cases", s.cached(s.handleUseCases))
+	mux.HandleFunc("/use-cases/", s.cached(s.handleUseCaseFlashlights))
+	mux.HandleFunc("/trending", s.cached(s.handleTrending))
+	mux.HandleFunc("/go/", s.handleGo)
+	mux.HandleFunc("/alerts", s.handleAlerts)
+	mux.HandleFunc("/alerts/", s.handleAlertAction)
+	mux.HandleFunc("/admin/", s.handleAdmin)
+	mux.HandleFunc("/rankings", s.cached(s.handleRankings))
+	mux.HandleFunc("/finder", s.cached(s.handleFinder))
 	mux.HandleFunc("/intelligence/recommendations", s.handleIntelligenceRecommendations)
 	mux.HandleFunc("/intelligence/runs", s.handleIntelligenceRuns)
 	mux.HandleFunc("/intelligence/runs/", s.handleIntelligenceRunByID)
-	return mux
-}
-
-type apiError struct {
-	Error string `json:"error"`
-}
-
-type flashlightItem struct {
-	ID             int64    `json:"id"`
-	Brand          string   `json:"brand"`
-	Name           string   `json:"name"`
-	Slug           string   `json:"slug"`
-	ModelCode      *string  `json:"model_code,omitempty"`
-	Description    *string  `json:"description,omitempty"`
-	ImageURL       *string  `json:"image_url,omitempty"`
-	AmazonURL      *string  `json:"amazon_url,omitempty"`
-	MaxLumens      *int64   `json:"max_lumens,omitempty"`
-	MaxCandela     *int64   `json:"max_candela,omitempty"`
-	BeamDistanceM  *int64   `json:"beam_distance_m,omitempty"`
-	RuntimeHighMin *int64   `json:"runtime_high_min,omitempty"`
-	Waterproof     *string  `json:"waterproof_rating,omitempty"`
-	PriceUSD       *float64 `json:"price_usd,omitempty"`
-	TacticalScore  *float64 `json:"tactical_score,omitempty"`
-	EDCScore       *float64 `json:"edc_score,omitempty"`
-	ValueScore     *float64 `json:"value_score,omitempty"`
-	ThrowScore     *float64 `json:"throw_score,omitempty"`
-	FloodScore     *float64 `json:"flood_score,omitempty"`
+	// The outer recoverPanics also covers versioned and observe. The inner one
+	// answers a handler panic before observe, so the 500 is still counted and
+	// logged with its request ID.
+	return chain(mux,
+		recoverPanics,
+		s.versioned,
+		s.observe(mux),
+		recoverPanics,
+		s.cors.wrap,
+		compress,
+		s.rateLimit(mux),
+	)
 }
 
-type paginatedFlashlightsResponse struct {
-	Page      int              `json:"page"`
-	PageSize  int              `json:"page_size"`
-	Total     int              `json:"total"`
-	TotalPage int              `json:"total_pages"`
-	Items     []flashlightItem `json:"items"`
+// Drain makes /readyz fail so load balancers stop sending new requests
+// while in-flight ones finish. Call it when shutdown begins.
+func (s *Server) Drain() {
+	s.draining.Store(true)
 }
 
-type flashlightDetail struct {
-	flashlightItem
-	ReleaseYear         *int64           `json:"release_year,omitempty"`
-	MSRPUSD             *float64         `json:"msrp_usd,omitempty"`
-	ASIN                *string          `json:"asin,omitempty"`
-	WeightG             *float64         `json:"weight_g,omitempty"`
-	LengthMM            *float64         `json:"length_mm,omitempty"`
-	HeadDiameterMM      *float64         `json:"head_diameter_mm,omitempty"`
-	BodyDiameterMM      *float64         `json:"body_diameter_mm,omitempty"`
-	ImpactResistance    *float64         `json:"impact_resistance_m,omitempty"`
-	SustainedLumens     *int64           `json:"sustained_lumens,omitempty"`
-	RuntimeLowMin       *int64           `json:"runtime_low_min,omitempty"`
-	RuntimeMediumMin    *int64           `json:"runtime_medium_min,omitempty"`
-	RuntimeTurboMin     *int64           `json:"runtime_turbo_min,omitempty"`
-	Runtime500Min       *int64           `json:"runtime_500_min,omitempty"`
-	TurboStepdownSec    *int64           `json:"turbo_stepdown_sec,omitempty"`
-	BeamPattern         *string          `json:"beam_pattern,omitempty"`
-	RechargeType        *string          `json:"recharge_type,omitempty"`
-	BatteryReplaceable  *bool            `json:"battery_replaceable,omitempty"`
-	HasTailSwitch       *bool            `json:"has_tail_switch,omitempty"`
-	HasSideSwitch       *bool            `json:"has_side_switch,omitempty"`
-	BodyMaterial        *string          `json:"body_material,omitempty"`
-	USBCRechargeable    *bool            `json:"usb_c_rechargeable,omitempty"`
-	BatteryIncluded     *bool            `json:"battery_included,omitempty"`
-	BatteryRech         *bool            `json:"battery_rechargeable,omitempty"`
-	HasStrobe           *bool            `json:"has_strobe,omitempty"`
-	HasMemoryMode       *bool            `json:"has_memory_mode,omitempty"`
-	HasLockout          *bool            `json:"has_lockout,omitempty"`
-	HasMoonlightMode    *bool            `json:"has_moonlight_mode,omitempty"`
-	HasMagTailcap       *bool            `json:"has_magnetic_tailcap,omitempty"`
-	HasPocketClip       *bool            `json:"has_pocket_clip,omitempty"`
-	SwitchType          *string          `json:"switch_type,omitempty"`
-	LEDModel            *string          `json:"led_model,omitempty"`
-	CRI                 *int64           `json:"cri,omitempty"`
-	CCTMinK             *int64           `json:"cct_min_k,omitempty"`
-	CCTMaxK             *int64           `json:"cct_max_k,omitempty"`
-	AmazonRatingCount   *int64           `json:"amazon_rating_count,omitempty"`
-	AmazonAverageRating *float64         `json:"amazon_average_rating,omitempty"`
-	AmazonLastSyncedAt  *string          `json:"amazon_last_synced_at,omitempty"`
-	PriceLastUpdatedAt  *string          `json:"price_last_updated_at,omitempty"`
-	Modes               []flashlightMode `json:"modes"`
-	ImageURLs           []string         `json:"image_urls"`
-	BatteryTypes        []string         `json:"battery_types"`
-	UseCaseTags         []string         `json:"use_case_tags"`
+// Flush writes buffered API key usage counts. Call it after the HTTP server
+// has shut down so the last requests are counted.
+func (s *Server) Flush(ctx context.Context) error {
+	return s.usage.Flush(ctx)
 }
 
-type flashlightMode struct {
-	Name          string `json:"name"`
-	OutputLumens  *int64 `json:"output_lumens,omitempty"`
-	RuntimeMin    *int64 `json:"runtime_min,omitempty"`
-	Candela       *int64 `json:"candela,omitempty"`
-	BeamDistanceM *int64 `json:"beam_distance_m,omitempty"`
-}
-
-type compareResponse struct {
-	Items []flashlightItem `json:"items"`
-}
-
-type rankingsResponse struct {
-	UseCase   string           `json:"use_case"`
-	Page      int              `json:"page"`
-	PageSize  int              `json:"page_size"`
-	Total     int              `json:"total"`
-	TotalPage int              `json:"total_pages"`
-	Items     []rankedResponse `json:"items"`
-}
-
-type rankedResponse struct {
-	Rank       int     `json:"rank"`
-	Score      float64 `json:"score"`
-	Profile    string  `json:"profile"`
-	Flashlight struct {
-		ID        int64   `json:"id"`
-		Brand     string  `json:"brand"`
-		Name      string  `json:"name"`
-		Slug      string  `json:"slug"`
-		ImageURL  *string `json:"image_url,omitempty"`
-		AmazonURL *string `json:"amazon_url,omitempty"`
-	} `json:"flashlight"`
+type apiError struct {
+	Error string `json:"error"`
 }
 
-type finderResponse struct {
-	Filters finderFilters   `json:"filters"`
-	Items   []finderRanking `json:"items"`
-}
+// The request and response types are shared with pkg/client, which decodes
+// them; they are defined there so both sides use the same declarations.
+type (
+	flashlightItem               = client.FlashlightItem
+	paginatedFlashlightsResponse = client.PaginatedFlashlightsResponse
+	flashlightDetail             = client.FlashlightDetail
+	flashlightMode               = client.FlashlightMode
+	rankingsResponse             = client.RankingsResponse
+	rankedResponse               = client.RankedResponse
+	finderResponse               = client.FinderResponse
+	finderFilters                = client.FinderFilters
+	finderRanking                = client.FinderRanking
+	intelligenceRunRequest       = client.IntelligenceRunRequest
+	intelligenceRunResult        = client.IntelligenceRunResult
+	intelligenceRunResponse      = client.IntelligenceRunResponse
+	intelligenceResponse         = client.IntelligenceResponse
+)
 
-type finderFilters struct {
-	Budget   *float64 `json:"budget,omitempty"`
-	USBC     *bool    `json:"usb_c,omitempty"`
-	MinThrow *int64   `json:"min_throw,omitempty"`
+type slugRedirectResponse struct {
+	ID       int64  `json:"id"`
+	Slug     string `json:"slug"`
+	Location string `json:"location"`
 }
 
-type finderRanking struct {
-	FlashlightID  int64    `json:"flashlight_id"`
-	Brand         string   `json:"brand"`
-	Name          string   `json:"name"`
-	AmazonURL     *string  `json:"amazon_url,omitempty"`
-	PriceUSD      *float64 `json:"price_usd,omitempty"`
-	BeamDistanceM *int64   `json:"beam_distance_m,omitempty"`
-	TacticalScore *float64 `json:"tactical_score,omitempty"`
-	ThrowScore    *float64 `json:"throw_score,omitempty"`
-	ValueScore    *float64 `json:"value_score,omitempty"`
-	FinderScore   float64  `json:"finder_score"`
-}
+func (s *Server) handleFlashlights(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
 
-type intelligenceRunRequest struct {
-	IntendedUse       string  `json:"intended_use"`
-	BudgetUSD         float64 `json:"budget_usd"`
-	BatteryPreference string  `json:"battery_preference"`
-	SizeConstraint    string  `json:"size_constraint"`
-}
+	filters, err := parseFlashlightFilters(r.URL.Query())
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	if filters.Region, err = requestRegion(w, r); err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
 
-type intelligenceRunResult struct {
-	ModelID           int64    `json:"model_id"`
-	Brand             string   `json:"brand"`
-	Name              string   `json:"name"`
-	Category          string   `json:"category"`
-	ImageURL          *string  `json:"image_url,omitempty"`
-	AmazonURL         *string  `json:"amazon_url,omitempty"`
-	PriceUSD          *float64 `json:"price_usd,omitempty"`
-	MaxLumens         *int64   `json:"max_lumens,omitempty"`
-	MaxCandela        *int64   `json:"max_candela,omitempty"`
-	BeamDistanceM     *int64   `json:"beam_distance_m,omitempty"`
-	RuntimeHighMin    *int64   `json:"runtime_high_min,omitempty"`
-	RuntimeMediumMin  *int64   `json:"runtime_medium_min,omitempty"`
-	WeightG           *float64 `json:"weight_g,omitempty"`
-	LengthMM          *float64 `json:"length_mm,omitempty"`
-	WaterproofRating  *string  `json:"waterproof_rating,omitempty"`
-	BatteryType       *string  `json:"battery_type,omitempty"`
-	OverallScore      float64  `json:"overall_score"`
-	UseCaseScore      float64  `json:"use_case_score"`
-	BudgetScore       float64  `json:"budget_score"`
-	BatteryMatchScore float64  `json:"battery_match_score"`
-	SizeFitScore      float64  `json:"size_fit_score"`
-	TacticalScore     *float64 `json:"tactical_score,omitempty"`
-	EDCScore          *float64 `json:"edc_score,omitempty"`
-	ValueScore        *float64 `json:"value_score,omitempty"`
-	ThrowScore        *float64 `json:"throw_score,omitempty"`
-	FloodScore        *float64 `json:"flood_score,omitempty"`
-}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
 
-type intelligenceRunResponse struct {
-	RunID             int64                   `json:"run_id"`
-	CreatedAt         string                  `json:"created_at"`
-	IntendedUse       string                  `json:"intended_use"`
-	BudgetUSD         float64                 `json:"budget_usd"`
-	BatteryPreference string                  `json:"battery_preference"`
-	SizeConstraint    string                  `json:"size_constraint"`
-	AlgorithmVersion  string                  `json:"algorithm_version"`
-	TopResults        []intelligenceRunResult `json:"top_results"`
+	page, err := s.listFlashlights(ctx, filters)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch flashlights"})
+		return
+	}
+	var facets map[string][]facetValue
+	if filters.WithFacets {
+		facets, err = s.flashlightFacetCounts(ctx, filters)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to compute facets"})
+			return
+		}
+	}
+	resp := paginatedFlashlightsResponse{
+		PageSize:   filters.PageSize,
+		NextCursor: page.next,
+		PrevCursor: page.prev,
+		Items:      page.items,
+		Facets:     facets,
+	}
+	if page.total != nil {
+		resp.Page, resp.Total, resp.TotalPage = filters.Page, page.total, totalPages(*page.total, filters.PageSize)
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
-type intelligenceResponse struct {
-	IntendedUse       string                  `json:"intended_use"`
-	BudgetUSD         float64                 `json:"budget_usd"`
-	BatteryPreference string                  `json:"battery_preference"`
-	SizeConstraint    string                  `json:"size_constraint"`
-	AlgorithmVersion  string                  `json:"algorithm_version"`
-	TopResults        []intelligenceRunResult `json:"top_results"`
+// totalPages is how many pages of size hold total rows.
+func totalPages(total, size int) *int {
+	n := (total + size - 1) / size
+	return &n
 }
 
-func (s *Server) handleFlashlights(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleFlashlightByID(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
 		return
 	}
 
-	page := clamp(parseIntDefault(r.URL.Query().Get("page"), 1), 1, 100000)
-	pageSize := clamp(parseIntDefault(r.URL.Query().Get("page_size"), 20), 1, 100)
-	filters := flashlightFilters{
-		BatteryType: strings.TrimSpace(r.URL.Query().Get("battery_type")),
-		IPRating:    strings.TrimSpace(r.URL.Query().Get("ip_rating")),
-		SortBy:      strings.TrimSpace(r.URL.Query().Get("sort_by")),
-		Order:       strings.TrimSpace(r.URL.Query().Get("order")),
-		Page:        page,
-		PageSize:    pageSize,
+	idPart, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/flashlights/"), "/")
+	idPart = strings.TrimSpace(idPart)
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id <= 0 {
+		writeBadRequest(w, r, invalidField("id", idPart, "must be a positive integer"))
+		return
 	}
 
-	if min := strings.TrimSpace(r.URL.Query().Get("min_price")); min != "" {
-		v, err := strconv.ParseFloat(min, 64)
-		if err != nil {
-			writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid min_price"})
-			return
-		}
-		filters.MinPrice = &v
+	switch sub {
+	case "":
+	case "similar":
+		s.handleSimilarFlashlights(w, r, id)
+		return
+	case "prices":
+		s.handleFlashlightPrices(w, r, id)
+		return
+	default:
+		writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+		return
 	}
-	if max := strings.TrimSpace(r.URL.Query().Get("max_price")); max != "" {
-		v, err := strconv.ParseFloat(max, 64)
-		if err != nil {
-			writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid max_price"})
+
+	if _, ok := readQuery(w, r, regionParams); !ok {
+		return
+	}
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	item, err := s.getFlashlightByID(ctx, id, rg)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, apiError{Error: "flashlight not found"})
 			return
 		}
-		filters.MaxPrice = &v
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch flashlight"})
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *Server) handleSimilarFlashlights(w http.ResponseWriter, r *http.Request, id int64) {
+	q, ok := readQuery(w, r, similarParams)
+	if !ok {
+		return
+	}
+	limit := q.int("limit")
+	cheaperOnly := q.boolean("cheaper") != nil && *q.boolean("cheaper")
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	items, total, err := s.listFlashlights(ctx, filters)
+	resp, err := s.similarFlashlights(ctx, id, limit, cheaperOnly, rg)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch flashlights"})
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, apiError{Error: "flashlight not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to find similar flashlights"})
 		return
 	}
-	totalPages := (total + pageSize - 1) / pageSize
-	writeJSON(w, http.StatusOK, paginatedFlashlightsResponse{
-		Page:      page,
-		PageSize:  pageSize,
-		Total:     total,
-		TotalPage: totalPages,
-		Items:     items,
-	})
+	writeJSON(w, http.StatusOK, resp)
 }
 
-func (s *Server) handleFlashlightByID(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleFlashlightBySlug(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
 		return
 	}
 
-	idPart := strings.TrimPrefix(r.URL.Path, "/flashlights/")
-	idPart = strings.TrimSpace(idPart)
-	if idPart == "" || strings.Contains(idPart, "/") {
-		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid flashlight id"})
+	slug := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/flashlights/by-slug/")))
+	if !slugRe.MatchString(slug) {
+		writeBadRequest(w, r, invalidField("slug", slug, errBadSlug.Error()))
 		return
 	}
-
-	id, err := strconv.ParseInt(idPart, 10, 64)
-	if err != nil || id <= 0 {
-		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid flashlight id"})
+	if _, ok := readQuery(w, r, regionParams); !ok {
+		return
+	}
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	item, err := s.getFlashlightByID(ctx, id)
+	id, canonical, err := s.resolveFlashlightSlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, apiError{Error: "flashlight not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch flashlight"})
+		return
+	}
+	if canonical != slug {
+		location := versionPrefix(r.Context()) + "/flashlights/by-slug/" + canonical
+		w.Header().Set("Location", location)
+		writeJSON(w, http.StatusMovedPermanently, slugRedirectResponse{ID: id, Slug: canonical, Location: location})
+		return
+	}
+
+	item, err := s.getFlashlightByID(ctx, id, rg)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSON(w, http.StatusNotFound, apiError{Error: "flashlight not found"})
@@ -319,21 +385,32 @@ func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
 		return
 	}
-	ids, err := parseIDList(r.URL.Query().Get("ids"), 20)
-	if err != nil || len(ids) == 0 {
-		writeJSON(w, http.StatusBadRequest, apiError{Error: "ids must be a comma-separated list of positive integers"})
+	q, ok := readQuery(w, r, compareParams)
+	if !ok {
+		return
+	}
+	ids := idList(q.list("ids"))
+	if len(ids) == 0 {
+		writeBadRequest(w, r, invalidField("ids", "", "is required"))
+		return
+	}
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	items, err := s.compareFlashlights(ctx, ids)
+	items, missing, err := s.compareFlashlights(ctx, ids, rg)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to compare flashlights"})
 		return
 	}
-	writeJSON(w, http.StatusOK, compareResponse{Items: items})
+	resp := buildComparison(items)
+	resp.MissingIDs = missing
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) handleRankings(w http.ResponseWriter, r *http.Request) {
@@ -342,35 +419,44 @@ func (s *Server) handleRankings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	useCase := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("use_case")))
-	if useCase == "" {
-		useCase = "overall"
+	q, ok := readQuery(w, r, rankingsParams)
+	if !ok {
+		return
 	}
-	if !validUseCase(useCase) {
-		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid use_case. expected one of overall, tactical, edc, value, throw, flood"})
+	useCase := q.str("use_case")
+	page := q.int("page")
+	pageSize := q.int("page_size")
+	verr := &validationError{}
+	c := pageCursor(q, verr, useCase)
+	if err := verr.err(); err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
 		return
 	}
-
-	page := clamp(parseIntDefault(r.URL.Query().Get("page"), 1), 1, 100000)
-	pageSize := clamp(parseIntDefault(r.URL.Query().Get("page_size"), 200), 1, 1000)
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	items, total, err := s.rankings(ctx, useCase, page, pageSize)
+	result, err := s.rankings(ctx, useCase, page, pageSize, c, rg)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch rankings"})
 		return
 	}
-	totalPages := (total + pageSize - 1) / pageSize
-	writeJSON(w, http.StatusOK, rankingsResponse{
-		UseCase:   useCase,
-		Page:      page,
-		PageSize:  pageSize,
-		Total:     total,
-		TotalPage: totalPages,
-		Items:     items,
-	})
+	resp := rankingsResponse{
+		UseCase:    useCase,
+		PageSize:   pageSize,
+		NextCursor: result.next,
+		PrevCursor: result.prev,
+		Items:      result.items,
+	}
+	if result.total != nil {
+		resp.Page, resp.Total, resp.TotalPage = page, result.total, totalPages(*result.total, pageSize)
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) handleFinder(w http.ResponseWriter, r *http.Request) {
@@ -379,38 +465,26 @@ func (s *Server) handleFinder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var filters finderFilters
-	if v := strings.TrimSpace(r.URL.Query().Get("budget")); v != "" {
-		p, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid budget"})
-			return
-		}
-		filters.Budget = &p
+	q, ok := readQuery(w, r, finderParams)
+	if !ok {
+		return
 	}
-	if v := strings.TrimSpace(r.URL.Query().Get("usb_c")); v != "" {
-		b, err := strconv.ParseBool(v)
-		if err != nil {
-			writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid usb_c"})
-			return
-		}
-		filters.USBC = &b
+	filters := finderFilters{
+		Budget:   q.float("budget"),
+		USBC:     q.boolean("usb_c"),
+		MinThrow: q.int64("min_throw"),
 	}
-	if v := strings.TrimSpace(r.URL.Query().Get("min_throw")); v != "" {
-		m, err := strconv.ParseInt(v, 10, 64)
-		if err != nil {
-			writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid min_throw"})
-			return
-		}
-		filters.MinThrow = &m
+	limit := q.int("limit")
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
 	}
 
-	limit := clamp(parseIntDefault(r.URL.Query().Get("limit"), 25), 1, 100)
-
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	items, err := s.finder(ctx, filters, limit)
+	items, err := s.finder(ctx, filters, limit, rg)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to run finder"})
 		return
@@ -427,32 +501,25 @@ func (s *Server) handleIntelligenceRuns(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var req intelligenceRunRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid json body"})
+	if _, ok := readQuery(w, r, regionParams); !ok {
 		return
 	}
-
-	req.IntendedUse = strings.TrimSpace(strings.ToLower(req.IntendedUse))
-	if req.IntendedUse == "" {
-		req.IntendedUse = "edc"
-	}
-	if req.BudgetUSD <= 0 {
-		req.BudgetUSD = 80
-	}
-	req.BatteryPreference = strings.TrimSpace(strings.ToLower(req.BatteryPreference))
-	if req.BatteryPreference == "" {
-		req.BatteryPreference = "any"
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
 	}
-	req.SizeConstraint = strings.TrimSpace(strings.ToLower(req.SizeConstraint))
-	if req.SizeConstraint == "" {
-		req.SizeConstraint = "any"
+
+	req, err := decodeIntelligenceRequest(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	resp, err := s.createIntelligenceRun(ctx, req)
+	resp, err := s.createIntelligenceRun(ctx, req, rg)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to create intelligence run"})
 		return
@@ -466,32 +533,25 @@ func (s *Server) handleIntelligenceRecommendations(w http.ResponseWriter, r *htt
 		return
 	}
 
-	var req intelligenceRunRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid json body"})
+	if _, ok := readQuery(w, r, regionParams); !ok {
 		return
 	}
-
-	req.IntendedUse = strings.TrimSpace(strings.ToLower(req.IntendedUse))
-	if req.IntendedUse == "" {
-		req.IntendedUse = "edc"
-	}
-	if req.BudgetUSD <= 0 {
-		req.BudgetUSD = 80
-	}
-	req.BatteryPreference = strings.TrimSpace(strings.ToLower(req.BatteryPreference))
-	if req.BatteryPreference == "" {
-		req.BatteryPreference = "any"
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
 	}
-	req.SizeConstraint = strings.TrimSpace(strings.ToLower(req.SizeConstraint))
-	if req.SizeConstraint == "" {
-		req.SizeConstraint = "any"
+
+	req, err := decodeIntelligenceRequest(r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	resp, err := s.intelligenceRecommendations(ctx, req)
+	resp, err := s.intelligenceRecommendations(ctx, req, rg)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to compute intelligence recommendations"})
 		return
@@ -507,14 +567,12 @@ func (s *Server) handleIntelligenceRunByID(w http.ResponseWriter, r *http.Reques
 
 	idPart := strings.TrimPrefix(r.URL.Path, "/intelligence/runs/")
 	idPart = strings.TrimSpace(idPart)
-	if idPart == "" || strings.Contains(idPart, "/") {
-		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid run id"})
-		return
-	}
-
 	id, err := strconv.ParseInt(idPart, 10, 64)
 	if err != nil || id <= 0 {
-		writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid run id"})
+		writeBadRequest(w, r, invalidField("id", idPart, "must be a positive integer"))
+		return
+	}
+	if _, ok := readQuery(w, r, noParams); !ok {
 		return
 	}
 
@@ -533,53 +591,106 @@ func (s *Server) handleIntelligenceRunByID(w http.ResponseWriter, r *http.Reques
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// rankingUseCases are the use cases /rankings scores flashlights for.
+var rankingUseCases = []string{"overall", "tactical", "edc", "value", "throw", "flood"}
+
 func validUseCase(v string) bool {
-	switch v {
-	case "overall", "tactical", "edc", "value", "throw", "flood":
-		return true
-	default:
-		return false
-	}
+	return oneOf(v, rankingUseCases...)
 }
 
-func parseIDList(input string, max int) ([]int64, error) {
-	if strings.TrimSpace(input) == "" {
-		return nil, nil
-	}
-	parts := strings.Split(input, ",")
-	if len(parts) > max {
-		return nil, fmt.Errorf("too many ids")
-	}
-	out := make([]int64, 0, len(parts))
-	for _, p := range parts {
-		v, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64)
-		if err != nil || v <= 0 {
-			return nil, fmt.Errorf("invalid id")
-		}
-		out = append(out, v)
+var (
+	regionParams  = []param{regionParam}
+	similarParams = []param{intParam("limit", 6, 1, 24), boolParam("cheaper"), regionParam}
+	compareParams = []param{
+		{Name: "ids", Type: typeList, MaxItems: maxCompareIDs, Required: true, check: checkPositiveID},
+		regionParam,
+	}
+	rankingsParams = []param{
+		enumParam("use_case", "overall", rankingUseCases...),
+		intParam("page", 1, 1, 100000),
+		intParam("page_size", 200, 1, 1000),
+		cursorParam,
+		regionParam,
+	}
+	finderParams = []param{
+		numberParam("budget", 0),
+		boolParam("usb_c"),
+		{Name: "min_throw", Type: typeInteger, Minimum: &zero},
+		intParam("limit", 25, 1, 100),
+		regionParam,
 	}
-	return out, nil
+)
+
+// intelligenceFields are the body fields the intelligence endpoints accept.
+// Absent fields take their default.
+var intelligenceFields = []param{
+	enumParam("intended_use", "edc", "edc", "tactical", "law-enforcement", "camping", "search-rescue", "weapon-mount", "keychain"),
+	{Name: "budget_usd", Type: typeNumber, Default: "80", Minimum: &zero},
+	enumParam("battery_preference", "any", "any", "14500", "16340", "18350", "18650", "21700", "26650", "aa", "aaa", "cr123a", "builtin", "proprietary"),
+	enumParam("size_constraint", "any", "any", "pocket", "compact", "full-size"),
 }
 
-func parseIntDefault(v string, fallback int) int {
-	if strings.TrimSpace(v) == "" {
-		return fallback
+// decodeIntelligenceRequest reads an intelligence request body, rejecting
+// unknown fields and values outside intelligenceFields, and fills defaults.
+func decodeIntelligenceRequest(r *http.Request) (intelligenceRunRequest, error) {
+	var req intelligenceRunRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return req, fmt.Errorf("invalid json body: %w", err)
+	}
+
+	// The body goes through the same checks as a query string would.
+	v := url.Values{}
+	for name, raw := range map[string]string{
+		"intended_use":       req.IntendedUse,
+		"battery_preference": req.BatteryPreference,
+		"size_constraint":    req.SizeConstraint,
+	} {
+		v.Set(name, raw)
+	}
+	if req.BudgetUSD != 0 {
+		v.Set("budget_usd", strconv.FormatFloat(req.BudgetUSD, 'f', -1, 64))
+	}
+	q, verr := parseQuery(v, intelligenceFields)
+	if err := verr.err(); err != nil {
+		return req, err
+	}
+	req.IntendedUse = q.str("intended_use")
+	req.BatteryPreference = q.str("battery_preference")
+	req.SizeConstraint = q.str("size_constraint")
+	if req.BudgetUSD == 0 {
+		req.BudgetUSD = 80
 	}
-	n, err := strconv.Atoi(v)
-	if err != nil {
-		return fallback
+	return req, nil
+}
+
+func checkPositiveID(v string) (string, error) {
+	if id, err := strconv.ParseInt(v, 10, 64); err != nil || id <= 0 {
+		return "", errors.New("must be a positive integer")
 	}
-	return n
+	return v, nil
 }
 
-func clamp(v, min, max int) int {
-	if v < min {
-		return min
+// idList converts ids already checked by checkPositiveID.
+func idList(values []string) []int64 {
+	ids := make([]int64, len(values))
+	for i, v := range values {
+		ids[i], _ = strconv.ParseInt(v, 10, 64)
 	}
-	if v > max {
-		return max
+	return ids
+}
+
+var (
+	slugRe     = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	errBadSlug = errors.New("must be lowercase letters and digits separated by hyphens")
+)
+
+func checkSlug(v string) (string, error) {
+	if !slugRe.MatchString(v) {
+		return "", errBadSlug
 	}
-	return v
+	return v, nil
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
@@ -0,0 +1,291 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+
+	"flashlight-ratings-go/internal/scoring"
+)
+
+// batteryMismatchPenalty is added to the spec distance when two lights share
+// no battery type, which matters more to buyers than a few points of output.
+const batteryMismatchPenalty = 15.0
+
+type similarFlashlight struct {
+	ID        int64   `json:"id"`
+	Brand     string  `json:"brand"`
+	Name      string  `json:"name"`
+	Slug      string  `json:"slug"`
+	ImageURL  *string `json:"image_url,omitempty"`
+	AmazonURL *string `json:"amazon_url,omitempty"`
+	regionalOffer
+	PriceUSD      *float64 `json:"price_usd,omitempty"`
+	MaxLumens     *int64   `json:"max_lumens,omitempty"`
+	BeamDistanceM *int64   `json:"beam_distance_m,omitempty"`
+	WeightG       *float64 `json:"weight_g,omitempty"`
+	BatteryTypes  []string `json:"battery_types"`
+	Similarity    float64  `json:"similarity"`
+	Reason        string   `json:"reason"`
+	Tags          []string `json:"tags"`
+}
+
+type similarAlternatives struct {
+	Cheaper  *similarFlashlight `json:"cheaper,omitempty"`
+	Brighter *similarFlashlight `json:"brighter,omitempty"`
+	Smaller  *similarFlashlight `json:"smaller,omitempty"`
+}
+
+type similarResponse struct {
+	FlashlightID int64               `json:"flashlight_id"`
+	Items        []similarFlashlight `json:"items"`
+	Alternatives similarAlternatives `json:"alternatives"`
+}
+
+type similarCandidate struct {
+	item      similarFlashlight
+	lumens    float64
+	candela   float64
+	beam      float64
+	weight    float64
+	length    float64
+	price     float64
+	batteries []string
+}
+
+func (s *Server) similarFlashlights(ctx context.Context, id int64, limit int, cheaperOnly bool, rg region) (similarResponse, error) {
+	candidates, err := s.specCandidates(ctx, id, rg)
+	if err != nil {
+		return similarResponse{}, err
+	}
+	var (
+		target similarCandidate
+		found  bool
+		pool   = make([]similarCandidate, 0, len(candidates))
+	)
+	for _, c := range candidates {
+		if c.item.ID == id {
+			target, found = c, true
+			continue
+		}
+		pool = append(pool, c)
+	}
+	if !found {
+		return similarResponse{}, sql.ErrNoRows
+	}
+	return rankSimilar(target, pool, limit, cheaperOnly), nil
+}
+
+// specCandidates loads the raw specs used for spec-space distance for every
+// active light, plus includeID even when it is inactive.
+func (s *Server) specCandidates(ctx context.Context, includeID int64, rg region) ([]similarCandidate, error) {
+	query := fmt.Sprintf(`
+WITH batteries AS (
+	SELECT fbc.flashlight_id, json_agg(bt.code ORDER BY bt.code) AS codes
+	FROM flashlight_battery_compatibility fbc
+	JOIN battery_types bt ON bt.id = fbc.battery_type_id
+	GROUP BY fbc.flashlight_id
+)
+SELECT
+	f.id,
+	b.name,
+	f.name,
+	f.slug,
+	rm.image_url,
+	%s,
+	s.max_lumens,
+	s.max_candela,
+	s.beam_distance_m,
+	s.weight_g,
+	s.length_mm,
+	rm.price_usd,
+	bat.codes
+FROM flashlights f
+JOIN brands b ON b.id = f.brand_id
+LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
+LEFT JOIN flashlight_read_model rm ON rm.flashlight_id = f.id
+LEFT JOIN batteries bat ON bat.flashlight_id = f.id
+WHERE f.is_active = TRUE OR f.id = $1
+`, rg.offerColumns())
+	rows, err := s.db.QueryContext(ctx, query, includeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []similarCandidate
+	for rows.Next() {
+		var (
+			c                            similarCandidate
+			imageURL, amazonURL          sql.NullString
+			linkRegion, regionalCurrency sql.NullString
+			regionalPrice                sql.NullFloat64
+			lumens, candela, beam        sql.NullInt64
+			weight, length, price        sql.NullFloat64
+			batteriesJSON                []byte
+		)
+		if err := rows.Scan(
+			&c.item.ID,
+			&c.item.Brand,
+			&c.item.Name,
+			&c.item.Slug,
+			&imageURL,
+			&amazonURL,
+			&linkRegion,
+			&regionalPrice,
+			&regionalCurrency,
+			&lumens,
+			&candela,
+			&beam,
+			&weight,
+			&length,
+			&price,
+			&batteriesJSON,
+		); err != nil {
+			return nil, err
+		}
+		c.item.ImageURL = nullString(imageURL)
+		c.item.AmazonURL = nullString(amazonURL)
+		rg.setOffer(&c.item.regionalOffer, linkRegion, regionalPrice, regionalCurrency)
+		c.item.MaxLumens = nullInt(lumens)
+		c.item.BeamDistanceM = nullInt(beam)
+		c.item.WeightG = nullFloat(weight)
+		c.item.PriceUSD = nullFloat(price)
+		c.batteries = decodeJSONStringArray(batteriesJSON)
+		c.item.BatteryTypes = c.batteries
+		c.lumens = float64(lumens.Int64)
+		c.candela = float64(candela.Int64)
+		c.beam = float64(beam.Int64)
+		c.weight = weight.Float64
+		c.length = length.Float64
+		c.price = price.Float64
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// rankSimilar orders pool by distance to target in the scoring engine's
+// normalized spec space and picks the closest cheaper, brighter and smaller
+// alternatives.
+func rankSimilar(target similarCandidate, pool []similarCandidate, limit int, cheaperOnly bool) similarResponse {
+	ranked := make([]similarFlashlight, 0, len(pool))
+	for _, c := range pool {
+		d, ok := specDistance(target, c)
+		if !ok {
+			continue
+		}
+		item := c.item
+		item.Similarity = round1(math.Max(0, 100-d))
+		item.Tags = similarTags(target, c)
+		item.Reason = "similar_specs"
+		for _, tag := range []string{"cheaper", "brighter", "smaller", "longer_throw"} {
+			if oneOf(tag, item.Tags...) {
+				item.Reason = tag
+				break
+			}
+		}
+		ranked = append(ranked, item)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Similarity != ranked[j].Similarity {
+			return ranked[i].Similarity > ranked[j].Similarity
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+
+	resp := similarResponse{FlashlightID: target.item.ID, Items: make([]similarFlashlight, 0, limit)}
+	for i := range ranked {
+		item := &ranked[i]
+		if resp.Alternatives.Cheaper == nil && oneOf("cheaper", item.Tags...) {
+			resp.Alternatives.Cheaper = item
+		}
+		if resp.Alternatives.Brighter == nil && oneOf("brighter", item.Tags...) {
+			resp.Alternatives.Brighter = item
+		}
+		if resp.Alternatives.Smaller == nil && oneOf("smaller", item.Tags...) {
+			resp.Alternatives.Smaller = item
+		}
+		if len(resp.Items) < limit && (!cheaperOnly || oneOf("cheaper", item.Tags...)) {
+			resp.Items = append(resp.Items, *item)
+		}
+	}
+	return resp
+}
+
+// specDistance is the RMS difference across the size, output, throw and price
+// axes both lights have data for, plus a penalty for incompatible batteries.
+// Pairs sharing fewer than two axes are not comparable.
+func specDistance(a, b similarCandidate) (float64, bool) {
+	axes := [][2]float64{
+		{scoring.SizeAxis(a.weight, a.length), scoring.SizeAxis(b.weight, b.length)},
+		{scoring.LumensAxis(a.lumens), scoring.LumensAxis(b.lumens)},
+		{throwAxis(a), throwAxis(b)},
+		{scoring.PriceAxis(a.price), scoring.PriceAxis(b.price)},
+	}
+	known := []bool{
+		(a.weight > 0 || a.length > 0) && (b.weight > 0 || b.length > 0),
+		a.lumens > 0 && b.lumens > 0,
+		(a.candela > 0 || a.beam > 0) && (b.candela > 0 || b.beam > 0),
+		a.price > 0 && b.price > 0,
+	}
+	var sum, n float64
+	for i, ax := range axes {
+		if !known[i] {
+			continue
+		}
+		diff := ax[0] - ax[1]
+		sum += diff * diff
+		n++
+	}
+	if n < 2 {
+		return 0, false
+	}
+	d := math.Sqrt(sum / n)
+	if len(a.batteries) > 0 && len(b.batteries) > 0 && !sharesAny(a.batteries, b.batteries) {
+		d += batteryMismatchPenalty
+	}
+	return d, true
+}
+
+func throwAxis(c similarCandidate) float64 {
+	switch {
+	case c.candela > 0 && c.beam > 0:
+		return (scoring.CandelaAxis(c.candela) + scoring.BeamDistanceAxis(c.beam)) / 2
+	case c.candela > 0:
+		return scoring.CandelaAxis(c.candela)
+	default:
+		return scoring.BeamDistanceAxis(c.beam)
+	}
+}
+
+func similarTags(target, c similarCandidate) []string {
+	tags := []string{}
+	if target.price > 0 && c.price > 0 && c.price <= target.price*0.9 {
+		tags = append(tags, "cheaper")
+	}
+	if target.lumens > 0 && c.lumens >= target.lumens*1.15 {
+		tags = append(tags, "brighter")
+	}
+	if (target.weight > 0 && c.weight > 0 && c.weight <= target.weight*0.85) ||
+		(target.weight == 0 && target.length > 0 && c.length > 0 && c.length <= target.length*0.85) {
+		tags = append(tags, "smaller")
+	}
+	if target.beam > 0 && c.beam >= target.beam*1.15 {
+		tags = append(tags, "longer_throw")
+	}
+	if sharesAny(target.batteries, c.batteries) {
+		tags = append(tags, "same_battery")
+	}
+	return tags
+}
+
+func sharesAny(a, b []string) bool {
+	for _, x := range a {
+		if oneOf(x, b...) {
+			return true
+		}
+	}
+	return false
+}
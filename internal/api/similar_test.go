@@ -0,0 +1,46 @@
+package api
+
+import "testing"
+
+func TestRankSimilarPrefersNearestAndTagsAlternatives(t *testing.T) {
+	target := similarCandidate{
+		item:   similarFlashlight{ID: 1},
+		lumens: 1500, candela: 20000, beam: 280, weight: 120, length: 130, price: 80,
+		batteries: []string{"18650"},
+	}
+	twin := similarCandidate{
+		item:   similarFlashlight{ID: 2},
+		lumens: 1600, candela: 21000, beam: 290, weight: 125, length: 132, price: 85,
+		batteries: []string{"18650"},
+	}
+	budget := similarCandidate{
+		item:   similarFlashlight{ID: 3},
+		lumens: 1200, candela: 15000, beam: 240, weight: 110, length: 125, price: 40,
+		batteries: []string{"18650"},
+	}
+	tank := similarCandidate{
+		item:   similarFlashlight{ID: 4},
+		lumens: 4500, candela: 90000, beam: 600, weight: 400, length: 220, price: 150,
+		batteries: []string{"21700"},
+	}
+	sparse := similarCandidate{item: similarFlashlight{ID: 5}, lumens: 1500}
+
+	resp := rankSimilar(target, []similarCandidate{tank, budget, twin, sparse}, 10, false)
+	if len(resp.Items) != 3 {
+		t.Fatalf("expected sparse candidate to be skipped, got %d items", len(resp.Items))
+	}
+	if resp.Items[0].ID != 2 || resp.Items[2].ID != 4 {
+		t.Fatalf("unexpected order %d,%d,%d", resp.Items[0].ID, resp.Items[1].ID, resp.Items[2].ID)
+	}
+	if resp.Alternatives.Cheaper == nil || resp.Alternatives.Cheaper.ID != 3 || resp.Alternatives.Cheaper.Reason != "cheaper" {
+		t.Fatalf("expected budget light as cheaper alternative, got %+v", resp.Alternatives.Cheaper)
+	}
+	if resp.Alternatives.Brighter == nil || resp.Alternatives.Brighter.ID != 4 {
+		t.Fatalf("expected tank as brighter alternative, got %+v", resp.Alternatives.Brighter)
+	}
+
+	cheaper := rankSimilar(target, []similarCandidate{tank, budget, twin}, 10, true)
+	if len(cheaper.Items) != 1 || cheaper.Items[0].ID != 3 {
+		t.Fatalf("cheaper=true should keep only cheaper lights, got %+v", cheaper.Items)
+	}
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type trendingFlashlight struct {
+	flashlightItem
+	Rank          int     `json:"rank"`
+	RatingCount   int64   `json:"rating_count"`
+	Delta1d       *int64  `json:"rating_count_delta_1d,omitempty"`
+	Delta7d       *int64  `json:"rating_count_delta_7d,omitempty"`
+	Delta30d      *int64  `json:"rating_count_delta_30d,omitempty"`
+	VelocityScore float64 `json:"velocity_score"`
+}
+
+type trendingResponse struct {
+	MetricDate *string              `json:"metric_date,omitempty"`
+	UseCase    *useCaseSummary      `json:"use_case,omitempty"`
+	Items      []trendingFlashlight `json:"items"`
+}
+
+var trendingParams = []param{
+	intParam("limit", 20, 1, 100),
+	{Name: "use_case", Type: typeString, MaxLen: 64, norm: strings.ToLower, check: checkSlug},
+	regionParam,
+}
+
+// handleTrending lists the lights gathering ratings fastest on the latest
+// rolled-up day, optionally limited to one use case.
+func (s *Server) handleTrending(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	q, ok := readQuery(w, r, trendingParams)
+	if !ok {
+		return
+	}
+	limit := q.int("limit")
+	slug := q.str("use_case")
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	resp := trendingResponse{}
+	var useCaseID int64
+	if slug != "" {
+		ucs, err := s.useCases(ctx, slug)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch use case"})
+			return
+		}
+		if len(ucs) == 0 {
+			writeJSON(w, http.StatusNotFound, apiError{Error: "use case not found"})
+			return
+		}
+		resp.UseCase = &ucs[0]
+		useCaseID = ucs[0].ID
+	}
+
+	day, items, err := s.trending(ctx, useCaseID, limit, rg)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch trending flashlights"})
+		return
+	}
+	resp.MetricDate = day
+	resp.Items = items
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) trending(ctx context.Context, useCaseID int64, limit int, rg region) (*string, []trendingFlashlight, error) {
+	var day sql.NullTime
+	if err := s.db.QueryRowContext(ctx, `SELECT MAX(metric_date) FROM flashlight_review_velocity_daily`).Scan(&day); err != nil {
+		return nil, nil, err
+	}
+	items := make([]trendingFlashlight, 0, limit)
+	if !day.Valid {
+		return nil, items, nil
+	}
+	metricDate := day.Time.Format("2006-01-02")
+
+	useCaseFilter := ""
+	args := []any{day.Time}
+	if useCaseID > 0 {
+		useCaseFilter = "AND EXISTS (SELECT 1 FROM flashlight_use_cases fuc WHERE fuc.flashlight_id = f.id AND fuc.use_case_id = $2)"
+		args = append(args, useCaseID)
+	}
+
+	query := fmt.Sprintf(`
+SELECT
+	f.id,
+	b.name,
+	f.name,
+	f.slug,
+	f.model_code,
+	f.description,
+	rm.image_url,
+	%s,
+	s.max_lumens,
+	s.max_candela,
+	s.beam_distance_m,
+	s.runtime_high_min,
+	s.waterproof_rating,
+	rm.price_usd,
+	rm.tactical_score,
+	rm.edc_score,
+	rm.value_score,
+	rm.throw_score,
+	rm.flood_score,
+	v.rating_count,
+	v.rating_count_delta_1d,
+	v.rating_count_delta_7d,
+	v.rating_count_delta_30d,
+	v.velocity_score::FLOAT8
+FROM flashlight_review_velocity_daily v
+JOIN flashlights f ON f.id = v.flashlight_id
+JOIN brands b ON b.id = f.brand_id
+LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
+LEFT JOIN flashlight_read_model rm ON rm.flashlight_id = f.id
+WHERE v.metric_date = $1
+  AND v.velocity_score > 0
+  AND f.is_active = TRUE
+  %s
+ORDER BY v.velocity_score DESC, v.rating_count_delta_7d DESC NULLS LAST, f.id ASC
+LIMIT %d
+`, rg.offerColumns(), useCaseFilter, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			item                                      trendingFlashlight
+			maxLumens, maxCandela, beam, runtimeHi    sql.NullInt64
+			modelCode, description, imageURL          sql.NullString
+			ip, amazonURL                             sql.NullString
+			linkRegion, regionalCurrency              sql.NullString
+			regionalPrice                             sql.NullFloat64
+			price, tactical, edc, value, throw, flood sql.NullFloat64
+			ratingCount, d1, d7, d30                  sql.NullInt64
+		)
+		if err := rows.Scan(
+			&item.ID,
+			&item.Brand,
+			&item.Name,
+			&item.Slug,
+			&modelCode,
+			&description,
+			&imageURL,
+			&amazonURL,
+			&linkRegion,
+			&regionalPrice,
+			&regionalCurrency,
+			&maxLumens,
+			&maxCandela,
+			&beam,
+			&runtimeHi,
+			&ip,
+			&price,
+			&tactical,
+			&edc,
+			&value,
+			&throw,
+			&flood,
+			&ratingCount,
+			&d1,
+			&d7,
+			&d30,
+			&item.VelocityScore,
+		); err != nil {
+			return nil, nil, err
+		}
+		item.ModelCode = nullString(modelCode)
+		item.Description = nullString(description)
+		item.ImageURL = nullString(imageURL)
+		item.AmazonURL = nullString(amazonURL)
+		rg.setOffer(&item.RegionalOffer, linkRegion, regionalPrice, regionalCurrency)
+		item.MaxLumens = nullInt(maxLumens)
+		item.MaxCandela = nullInt(maxCandela)
+		item.BeamDistanceM = nullInt(beam)
+		item.RuntimeHighMin = nullInt(runtimeHi)
+		item.Waterproof = nullString(ip)
+		item.PriceUSD = nullFloat(price)
+		item.TacticalScore = nullFloat(tactical)
+		item.EDCScore = nullFloat(edc)
+		item.ValueScore = nullFloat(value)
+		item.ThrowScore = nullFloat(throw)
+		item.FloodScore = nullFloat(flood)
+		item.RatingCount = ratingCount.Int64
+		item.Delta1d = nullInt(d1)
+		item.Delta7d = nullInt(d7)
+		item.Delta30d = nullInt(d30)
+		item.Rank = len(items) + 1
+		items = append(items, item)
+	}
+	return &metricDate, items, rows.Err()
+}
@@ -0,0 +1,285 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// useCaseProfiles maps catalog use-case slugs to the scoring profile that
+// ranks them. Slugs that are themselves profile names rank by that profile;
+// anything else falls back to tactical, the catalog's default sort.
+var useCaseProfiles = map[string]string{
+	"camping":       "flood",
+	"search-rescue": "throw",
+	"keychain":      "edc",
+	"weapon-mount":  "tactical",
+}
+
+type useCaseSummary struct {
+	ID              int64  `json:"id"`
+	Name            string `json:"name"`
+	Slug            string `json:"slug"`
+	Profile         string `json:"profile"`
+	FlashlightCount int    `json:"flashlight_count"`
+}
+
+type useCasesResponse struct {
+	Items []useCaseSummary `json:"items"`
+}
+
+type useCaseFlashlight struct {
+	flashlightItem
+	Rank         int      `json:"rank"`
+	ProfileScore *float64 `json:"profile_score,omitempty"`
+	Confidence   float64  `json:"confidence"`
+}
+
+type useCaseFlashlightsResponse struct {
+	UseCase   useCaseSummary      `json:"use_case"`
+	Page      int                 `json:"page"`
+	PageSize  int                 `json:"page_size"`
+	Total     int                 `json:"total"`
+	TotalPage int                 `json:"total_pages"`
+	Items     []useCaseFlashlight `json:"items"`
+}
+
+func useCaseProfile(slug string) string {
+	if p, ok := useCaseProfiles[slug]; ok {
+		return p
+	}
+	if validUseCase(slug) && slug != "overall" {
+		return slug
+	}
+	return "tactical"
+}
+
+func (s *Server) handleUseCases(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	if _, ok := readQuery(w, r, noParams); !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	items, err := s.useCases(ctx, "")
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch use cases"})
+		return
+	}
+	writeJSON(w, http.StatusOK, useCasesResponse{Items: items})
+}
+
+var useCaseFlashlightsParams = []param{
+	intParam("page", 1, 1, 100000),
+	intParam("page_size", 20, 1, 100),
+	regionParam,
+}
+
+func (s *Server) handleUseCaseFlashlights(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	slug, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/use-cases/"), "/")
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if sub != "flashlights" {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+		return
+	}
+	if !slugRe.MatchString(slug) {
+		writeBadRequest(w, r, invalidField("slug", slug, errBadSlug.Error()))
+		return
+	}
+
+	q, ok := readQuery(w, r, useCaseFlashlightsParams)
+	if !ok {
+		return
+	}
+	page := q.int("page")
+	pageSize := q.int("page_size")
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	ucs, err := s.useCases(ctx, slug)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch use case"})
+		return
+	}
+	if len(ucs) == 0 {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "use case not found"})
+		return
+	}
+
+	items, err := s.useCaseFlashlights(ctx, ucs[0], page, pageSize, rg)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch use case flashlights"})
+		return
+	}
+	total := ucs[0].FlashlightCount
+	writeJSON(w, http.StatusOK, useCaseFlashlightsResponse{
+		UseCase:   ucs[0],
+		Page:      page,
+		PageSize:  pageSize,
+		Total:     total,
+		TotalPage: (total + pageSize - 1) / pageSize,
+		Items:     items,
+	})
+}
+
+// useCases lists use cases with their active flashlight counts, or only the
+// one with the given slug when slug is set.
+func (s *Server) useCases(ctx context.Context, slug string) ([]useCaseSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT
+	u.id,
+	u.name,
+	u.slug,
+	COUNT(f.id)
+FROM use_cases u
+LEFT JOIN flashlight_use_cases fuc ON fuc.use_case_id = u.id
+LEFT JOIN flashlights f ON f.id = fuc.flashlight_id AND f.is_active = TRUE
+WHERE ($1 = '' OR u.slug = $1)
+GROUP BY u.id
+ORDER BY u.name ASC
+`, slug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]useCaseSummary, 0)
+	for rows.Next() {
+		var uc useCaseSummary
+		if err := rows.Scan(&uc.ID, &uc.Name, &uc.Slug, &uc.FlashlightCount); err != nil {
+			return nil, err
+		}
+		uc.Profile = useCaseProfile(uc.Slug)
+		out = append(out, uc)
+	}
+	return out, rows.Err()
+}
+
+// useCaseFlashlights ranks the lights tagged with uc by their score in the
+// use case's profile, weighted by how confident the tag is.
+func (s *Server) useCaseFlashlights(ctx context.Context, uc useCaseSummary, page, pageSize int, rg region) ([]useCaseFlashlight, error) {
+	scoreExpr := sortColumn(uc.Profile + "_score")
+	offset := (page - 1) * pageSize
+
+	query := fmt.Sprintf(`
+SELECT
+	f.id,
+	b.name,
+	f.name,
+	f.slug,
+	f.model_code,
+	f.description,
+	rm.image_url,
+	%[4]s,
+	s.max_lumens,
+	s.max_candela,
+	s.beam_distance_m,
+	s.runtime_high_min,
+	s.waterproof_rating,
+	rm.price_usd,
+	rm.tactical_score,
+	rm.edc_score,
+	rm.value_score,
+	rm.throw_score,
+	rm.flood_score,
+	%[1]s,
+	fuc.confidence::FLOAT8
+FROM flashlight_use_cases fuc
+JOIN flashlights f ON f.id = fuc.flashlight_id
+JOIN brands b ON b.id = f.brand_id
+LEFT JOIN flashlight_specs s ON s.flashlight_id = f.id
+LEFT JOIN flashlight_read_model rm ON rm.flashlight_id = f.id
+WHERE fuc.use_case_id = $1
+  AND f.is_active = TRUE
+ORDER BY COALESCE(%[1]s, 0) * fuc.confidence DESC, fuc.confidence DESC, f.id ASC
+LIMIT %[2]d OFFSET %[3]d
+`, scoreExpr, pageSize, offset, rg.offerColumns())
+
+	rows, err := s.db.QueryContext(ctx, query, uc.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]useCaseFlashlight, 0, pageSize)
+	for rows.Next() {
+		var (
+			item                                      useCaseFlashlight
+			maxLumens, maxCandela, beam, runtimeHi    sql.NullInt64
+			modelCode, description, imageURL          sql.NullString
+			ip, amazonURL                             sql.NullString
+			linkRegion, regionalCurrency              sql.NullString
+			regionalPrice                             sql.NullFloat64
+			price, tactical, edc, value, throw, flood sql.NullFloat64
+			profileScore                              sql.NullFloat64
+		)
+		if err := rows.Scan(
+			&item.ID,
+			&item.Brand,
+			&item.Name,
+			&item.Slug,
+			&modelCode,
+			&description,
+			&imageURL,
+			&amazonURL,
+			&linkRegion,
+			&regionalPrice,
+			&regionalCurrency,
+			&maxLumens,
+			&maxCandela,
+			&beam,
+			&runtimeHi,
+			&ip,
+			&price,
+			&tactical,
+			&edc,
+			&value,
+			&throw,
+			&flood,
+			&profileScore,
+			&item.Confidence,
+		); err != nil {
+			return nil, err
+		}
+		item.ModelCode = nullString(modelCode)
+		item.Description = nullString(description)
+		item.ImageURL = nullString(imageURL)
+		item.AmazonURL = nullString(amazonURL)
+		rg.setOffer(&item.RegionalOffer, linkRegion, regionalPrice, regionalCurrency)
+		item.MaxLumens = nullInt(maxLumens)
+		item.MaxCandela = nullInt(maxCandela)
+		item.BeamDistanceM = nullInt(beam)
+		item.RuntimeHighMin = nullInt(runtimeHi)
+		item.Waterproof = nullString(ip)
+		item.PriceUSD = nullFloat(price)
+		item.TacticalScore = nullFloat(tactical)
+		item.EDCScore = nullFloat(edc)
+		item.ValueScore = nullFloat(value)
+		item.ThrowScore = nullFloat(throw)
+		item.FloodScore = nullFloat(flood)
+		item.ProfileScore = nullFloat(profileScore)
+		item.Rank = offset + len(items) + 1
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUseCaseProfile(t *testing.T) {
+	cases := map[string]string{
+		"camping":       "flood",
+		"search-rescue": "throw",
+		"keychain":      "edc",
+		"weapon-mount":  "tactical",
+		"edc":           "edc",
+		"throw":         "throw",
+		"value":         "value",
+		"overall":       "tactical",
+		"diving":        "tactical",
+	}
+	for slug, want := range cases {
+		if got := useCaseProfile(slug); got != want {
+			t.Errorf("useCaseProfile(%q) = %q, want %q", slug, got, want)
+		}
+	}
+}
+
+func TestUseCaseFlashlightsRankByProfileScoreAndConfidence(t *testing.T) {
+	for slug, profile := range useCaseProfiles {
+		second := row(fixtureListing, 60.0, 0.5)
+		second[0] = int64(2)
+		db := openFakeDB(t,
+			fixture{match: "FROM use_cases u", arg: slug, rows: [][]driver.Value{{int64(4), "Use case", slug, int64(9)}}},
+			fixture{
+				match: "ORDER BY COALESCE(rm." + profile + "_score, 0) * fuc.confidence DESC, fuc.confidence DESC, f.id ASC",
+				rows:  [][]driver.Value{row(fixtureListing, 90.0, 0.8), second},
+			},
+		)
+		s := &Server{db: db}
+		w := httptest.NewRecorder()
+		s.handleUseCaseFlashlights(w, httptest.NewRequest(http.MethodGet, "/use-cases/"+slug+"/flashlights?page=2&page_size=2", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", slug, w.Code, w.Body.String())
+			continue
+		}
+
+		var resp useCaseFlashlightsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.UseCase.Profile != profile || resp.Total != 9 || resp.TotalPage != 5 {
+			t.Errorf("%s: profile %q, total %d, total_pages %d", slug, resp.UseCase.Profile, resp.Total, resp.TotalPage)
+		}
+		if len(resp.Items) != 2 || resp.Items[0].Rank != 3 || resp.Items[1].Rank != 4 {
+			t.Errorf("%s: items %+v", slug, resp.Items)
+			continue
+		}
+		if first := resp.Items[0]; first.ProfileScore == nil || *first.ProfileScore != 90 || first.Confidence != 0.8 {
+			t.Errorf("%s: first item score %v, confidence %v", slug, first.ProfileScore, first.Confidence)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"flashlight-ratings-go/internal/telemetry"
+)
+
+// apiVersion prefixes every route of the current API version. The same
+// routes answer without it during the deprecation window, for clients that
+// predate versioning.
+const apiVersion = "/v1"
+
+// legacyDeprecatedAt is when unversioned paths were deprecated, sent as the
+// RFC 9745 Deprecation header.
+var legacyDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+type versionKey struct{}
+
+// versionPrefix returns apiVersion when the request came in under it and ""
+// for a legacy path. Handlers prefix the paths they send back with it, so
+// clients stay on the version they called.
+func versionPrefix(ctx context.Context) string {
+	v, _ := ctx.Value(versionKey{}).(string)
+	return v
+}
+
+// versioned strips apiVersion from request paths so the mux, the cache and
+// handlers see one set of routes. Requests to legacy paths are served the
+// same way but marked deprecated, with a Link to their /v1 successor and a
+// Sunset date once one is configured. Probes stay unversioned.
+func (s *Server) versioned(next http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(legacyDeprecatedAt.Unix(), 10)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rest, ok := strings.CutPrefix(r.URL.Path, apiVersion); ok && (rest == "" || rest[0] == '/') {
+			next.ServeHTTP(w, stripVersion(r, rest))
+			return
+		}
+		if !probePaths[r.URL.Path] {
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			h.Add("Link", "<"+apiVersion+r.URL.EscapedPath()+`>; rel="successor-version"`)
+			if !s.legacySunset.IsZero() {
+				h.Set("Sunset", s.legacySunset.UTC().Format(http.TimeFormat))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func stripVersion(r *http.Request, rest string) *http.Request {
+	if rest == "" {
+		rest = "/"
+	}
+	r2 := r.WithContext(context.WithValue(r.Context(), versionKey{}, apiVersion))
+	u := new(url.URL)
+	*u = *r.URL
+	u.Path = rest
+	u.RawPath = strings.TrimPrefix(r.URL.RawPath, apiVersion)
+	r2.URL = u
+	return r2
+}
+
+// countLegacy records a request to a legacy path by route, so we can see
+// who still needs to move before the aliases are removed.
+func countLegacy(r *http.Request, route string) {
+	if versionPrefix(r.Context()) == "" && !probePaths[r.URL.Path] {
+		telemetry.HTTPLegacyRequests.WithLabelValues(route).Inc()
+	}
+}
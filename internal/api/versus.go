@@ -0,0 +1,261 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxVersusPriceRatio bounds how far apart two lights may be in price before
+// a head-to-head page stops being a plausible buying decision.
+const maxVersusPriceRatio = 1.35
+
+type versusResponse struct {
+	CanonicalSlug string             `json:"canonical_slug"`
+	Path          string             `json:"path"`
+	A             flashlightDetail   `json:"a"`
+	B             flashlightDetail   `json:"b"`
+	ScoreDiffs    []versusScoreDiff  `json:"score_diffs"`
+	Attributes    []compareAttribute `json:"attributes"`
+	Axes          []compareAxes      `json:"axes"`
+	Summary       []compareSummary   `json:"summary"`
+}
+
+// versusScoreDiff is A minus B for one scoring profile.
+type versusScoreDiff struct {
+	Profile  string   `json:"profile"`
+	A        *float64 `json:"a,omitempty"`
+	B        *float64 `json:"b,omitempty"`
+	Diff     *float64 `json:"diff,omitempty"`
+	WinnerID *int64   `json:"winner_id,omitempty"`
+}
+
+type versusRedirectResponse struct {
+	CanonicalSlug string `json:"canonical_slug"`
+	Location      string `json:"location"`
+}
+
+type versusPair struct {
+	CanonicalSlug string  `json:"canonical_slug"`
+	Path          string  `json:"path"`
+	SlugA         string  `json:"slug_a"`
+	SlugB         string  `json:"slug_b"`
+	NameA         string  `json:"name_a"`
+	NameB         string  `json:"name_b"`
+	SizeClass     string  `json:"size_class"`
+	Similarity    float64 `json:"similarity"`
+}
+
+type versusPairsResponse struct {
+	Items []versusPair `json:"items"`
+}
+
+var (
+	versusPairsParams = []param{intParam("limit", 200, 1, 1000)}
+	errSelfComparison = errors.New("cannot compare a flashlight with itself")
+)
+
+func (s *Server) handleVersus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/versus/"), "/")
+	if rest == "pairs" {
+		s.handleVersusPairs(w, r)
+		return
+	}
+	slugA, slugB, ok := strings.Cut(strings.ToLower(rest), "/")
+	if !ok || !slugRe.MatchString(slugA) || !slugRe.MatchString(slugB) {
+		writeBadRequest(w, r, errors.New("expected /versus/{slugA}/{slugB}"))
+		return
+	}
+	if slugA == slugB {
+		writeBadRequest(w, r, errSelfComparison)
+		return
+	}
+	if _, ok := readQuery(w, r, regionParams); !ok {
+		return
+	}
+	rg, err := requestRegion(w, r)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	idA, canonA, errA := s.resolveFlashlightSlug(ctx, slugA)
+	idB, canonB, errB := s.resolveFlashlightSlug(ctx, slugB)
+	if errors.Is(errA, sql.ErrNoRows) || errors.Is(errB, sql.ErrNoRows) {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "flashlight not found"})
+		return
+	}
+	if errA != nil || errB != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch flashlights"})
+		return
+	}
+	if idA == idB {
+		writeBadRequest(w, r, errSelfComparison)
+		return
+	}
+	if canonB < canonA {
+		idA, idB = idB, idA
+		canonA, canonB = canonB, canonA
+	}
+	path := versionPrefix(r.Context()) + versusPath(canonA, canonB)
+	if slugA != canonA || slugB != canonB {
+		w.Header().Set("Location", path)
+		writeJSON(w, http.StatusMovedPermanently, versusRedirectResponse{
+			CanonicalSlug: versusSlug(canonA, canonB),
+			Location:      path,
+		})
+		return
+	}
+
+	a, err := s.getFlashlightByID(ctx, idA, rg)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch flashlights"})
+		return
+	}
+	b, err := s.getFlashlightByID(ctx, idB, rg)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to fetch flashlights"})
+		return
+	}
+
+	cmp := buildComparison([]flashlightDetail{a, b})
+	writeJSON(w, http.StatusOK, versusResponse{
+		CanonicalSlug: versusSlug(canonA, canonB),
+		Path:          path,
+		A:             a,
+		B:             b,
+		ScoreDiffs:    versusScoreDiffs(a, b),
+		Attributes:    cmp.Attributes,
+		Axes:          cmp.Axes,
+		Summary:       cmp.Summary,
+	})
+}
+
+func (s *Server) handleVersusPairs(w http.ResponseWriter, r *http.Request) {
+	q, ok := readQuery(w, r, versusPairsParams)
+	if !ok {
+		return
+	}
+	limit := q.int("limit")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	candidates, err := s.specCandidates(ctx, 0, defaultRegion)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "failed to list versus pairs"})
+		return
+	}
+	writeJSON(w, http.StatusOK, versusPairsResponse{Items: versusPairs(candidates, limit)})
+}
+
+func versusScoreDiffs(a, b flashlightDetail) []versusScoreDiff {
+	profiles := []struct {
+		name   string
+		scores [2]*float64
+	}{
+		{"tactical", [2]*float64{a.TacticalScore, b.TacticalScore}},
+		{"edc", [2]*float64{a.EDCScore, b.EDCScore}},
+		{"value", [2]*float64{a.ValueScore, b.ValueScore}},
+		{"throw", [2]*float64{a.ThrowScore, b.ThrowScore}},
+		{"flood", [2]*float64{a.FloodScore, b.FloodScore}},
+	}
+	out := make([]versusScoreDiff, 0, len(profiles))
+	for _, p := range profiles {
+		d := versusScoreDiff{Profile: p.name, A: p.scores[0], B: p.scores[1]}
+		if d.A != nil && d.B != nil {
+			d.Diff = ptrFloat(round1(*d.A - *d.B))
+			switch {
+			case *d.A > *d.B:
+				d.WinnerID = &a.ID
+			case *d.B > *d.A:
+				d.WinnerID = &b.ID
+			}
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// versusPairs lists head-to-head pages worth publishing: lights in the same
+// size class whose prices are within maxVersusPriceRatio, closest first.
+func versusPairs(candidates []similarCandidate, limit int) []versusPair {
+	pairs := make([]versusPair, 0)
+	for i := range candidates {
+		for j := i + 1; j < len(candidates); j++ {
+			a, b := candidates[i], candidates[j]
+			class := sizeClass(a)
+			if class == "" || class != sizeClass(b) {
+				continue
+			}
+			if a.price <= 0 || b.price <= 0 || math.Max(a.price, b.price)/math.Min(a.price, b.price) > maxVersusPriceRatio {
+				continue
+			}
+			d, ok := specDistance(a, b)
+			if !ok {
+				continue
+			}
+			if b.item.Slug < a.item.Slug {
+				a, b = b, a
+			}
+			pairs = append(pairs, versusPair{
+				CanonicalSlug: versusSlug(a.item.Slug, b.item.Slug),
+				Path:          versusPath(a.item.Slug, b.item.Slug),
+				SlugA:         a.item.Slug,
+				SlugB:         b.item.Slug,
+				NameA:         a.item.Brand + " " + a.item.Name,
+				NameB:         b.item.Brand + " " + b.item.Name,
+				SizeClass:     class,
+				Similarity:    round1(math.Max(0, 100-d)),
+			})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].Similarity != pairs[j].Similarity {
+			return pairs[i].Similarity > pairs[j].Similarity
+		}
+		return pairs[i].CanonicalSlug < pairs[j].CanonicalSlug
+	})
+	if len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+	return pairs
+}
+
+// sizeClass buckets a light by length, falling back to weight when the
+// length is unknown.
+func sizeClass(c similarCandidate) string {
+	switch {
+	case c.length > 0 && c.length < 100, c.length == 0 && c.weight > 0 && c.weight < 60:
+		return "compact"
+	case c.length > 0 && c.length < 140, c.length == 0 && c.weight > 0 && c.weight < 130:
+		return "edc"
+	case c.length > 0 && c.length < 200, c.length == 0 && c.weight > 0 && c.weight < 300:
+		return "full"
+	case c.length > 0 || c.weight > 0:
+		return "large"
+	default:
+		return ""
+	}
+}
+
+func versusSlug(a, b string) string {
+	return a + "-vs-" + b
+}
+
+func versusPath(a, b string) string {
+	return "/versus/" + a + "/" + b
+}
@@ -0,0 +1,28 @@
+package api
+
+import "testing"
+
+func TestVersusPairsCanonicalAndFiltered(t *testing.T) {
+	light := func(id int64, slug string, length, price float64) similarCandidate {
+		return similarCandidate{
+			item:   similarFlashlight{ID: id, Slug: slug, Brand: "B", Name: slug},
+			lumens: 1500, beam: 250, length: length, price: price,
+		}
+	}
+	pairs := versusPairs([]similarCandidate{
+		light(1, "olight-warrior-3s", 125, 90),
+		light(2, "fenix-pd36r-pro", 130, 100),
+		light(3, "acebeam-e75", 120, 200),
+		light(4, "nitecore-mt21c", 180, 95),
+	}, 10)
+	if len(pairs) != 1 {
+		t.Fatalf("expected only the same-class, close-price pair, got %+v", pairs)
+	}
+	p := pairs[0]
+	if p.CanonicalSlug != "fenix-pd36r-pro-vs-olight-warrior-3s" || p.Path != "/versus/fenix-pd36r-pro/olight-warrior-3s" {
+		t.Fatalf("pair not in canonical order: %+v", p)
+	}
+	if p.SizeClass != "edc" {
+		t.Fatalf("unexpected size class %q", p.SizeClass)
+	}
+}
@@ -0,0 +1,112 @@
+package apikeys
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Decision is the outcome of taking a token from a bucket.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token when Allowed is false.
+	RetryAfter time.Duration
+}
+
+// Buckets holds in-memory token buckets keyed by caller. Each API instance
+// keeps its own, so the effective limit scales with the number of instances.
+type Buckets struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// maxBuckets caps how many callers are tracked at once. A new caller at the
+// cap triggers evict, which brings the count down to lowBuckets so the scan
+// runs at most once per maxBuckets-lowBuckets new callers.
+const (
+	maxBuckets = 50000
+	lowBuckets = maxBuckets - maxBuckets/10
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will have refilled to its burst.
+	full time.Time
+}
+
+func NewBuckets() *Buckets {
+	return &Buckets{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Take removes one token from id's bucket, which refills at ratePerMinute and
+// holds at most burst tokens. A new bucket starts full.
+func (b *Buckets) Take(id string, ratePerMinute, burst int) Decision {
+	if burst <= 0 {
+		burst = ratePerMinute
+	}
+	perSec := float64(ratePerMinute) / 60
+	now := b.now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bk, ok := b.buckets[id]
+	if !ok {
+		if len(b.buckets) >= maxBuckets {
+			b.evict(now)
+		}
+		bk = &bucket{tokens: float64(burst), last: now}
+		b.buckets[id] = bk
+	}
+	bk.tokens = math.Min(float64(burst), bk.tokens+now.Sub(bk.last).Seconds()*perSec)
+	bk.last = now
+
+	d := Decision{Limit: burst}
+	if bk.tokens >= 1 {
+		bk.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((1 - bk.tokens) / perSec)
+	}
+	d.Remaining = int(bk.tokens)
+	d.Reset = seconds((float64(burst) - bk.tokens) / perSec)
+	bk.full = now.Add(d.Reset)
+	return d
+}
+
+// evict shrinks the map to lowBuckets. Buckets that have refilled go first,
+// since a returning caller gets the same full bucket either way; then the
+// least recently used, whose callers get their burst back early. b.mu must
+// be held.
+func (b *Buckets) evict(now time.Time) {
+	for id, bk := range b.buckets {
+		if !bk.full.After(now) {
+			delete(b.buckets, id)
+		}
+	}
+	excess := len(b.buckets) - lowBuckets
+	if excess <= 0 {
+		return
+	}
+	ids := make([]string, 0, len(b.buckets))
+	for id := range b.buckets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return b.buckets[ids[i]].last.Before(b.buckets[ids[j]].last)
+	})
+	for _, id := range ids[:excess] {
+		delete(b.buckets, id)
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s)) * time.Second
+}
@@ -0,0 +1,70 @@
+package apikeys
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBucketBurstThenRefill(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBuckets()
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		d := b.Take("k", 60, 3)
+		if !d.Allowed {
+			t.Fatalf("request %d refused within burst", i)
+		}
+		if d.Remaining != 2-i || d.Limit != 3 {
+			t.Fatalf("request %d: remaining=%d limit=%d", i, d.Remaining, d.Limit)
+		}
+	}
+	d := b.Take("k", 60, 3)
+	if d.Allowed || d.RetryAfter != time.Second {
+		t.Fatalf("over burst: %+v, want refused with 1s retry", d)
+	}
+	if d.Reset != 3*time.Second {
+		t.Fatalf("reset = %v, want 3s", d.Reset)
+	}
+
+	now = now.Add(2 * time.Second)
+	if d := b.Take("k", 60, 3); !d.Allowed || d.Remaining != 1 {
+		t.Fatalf("after refill: %+v", d)
+	}
+	if d := b.Take("other", 60, 3); !d.Allowed || d.Remaining != 2 {
+		t.Fatalf("buckets are not independent: %+v", d)
+	}
+}
+
+func TestBucketsStayWithinCap(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBuckets()
+	b.now = func() time.Time { return now }
+
+	// "hot" keeps draining its bucket while one-off callers arrive a second
+	// apart, with bursts so large that none of them refills.
+	for i := 0; i < 2*maxBuckets; i++ {
+		now = now.Add(time.Second)
+		b.Take("ip-"+strconv.Itoa(i), 1, 1e6)
+		if i%10 == 0 {
+			b.Take("hot", 1, 1)
+		}
+		if len(b.buckets) > maxBuckets {
+			t.Fatalf("after %d callers: %d buckets, cap %d", i+1, len(b.buckets), maxBuckets)
+		}
+	}
+	if _, ok := b.buckets["ip-0"]; ok {
+		t.Fatal("least recently used bucket was kept")
+	}
+	if d := b.Take("hot", 1, 1); d.Allowed {
+		t.Fatalf("recently used bucket was evicted: %+v", d)
+	}
+}
+
+func TestLookupRejectsForeignSecrets(t *testing.T) {
+	s := NewStore(nil, 0)
+	if _, err := s.Lookup(t.Context(), "sk_live_123"); err != ErrNotFound {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
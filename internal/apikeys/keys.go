@@ -0,0 +1,197 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretPrefix marks our keys so they are easy to spot in leaked-secret scans.
+const secretPrefix = "flr_"
+
+var ErrNotFound = errors.New("api key not found")
+
+// Key is an issued API key. Zero limits mean unlimited.
+type Key struct {
+	ID            int64
+	Name          string
+	Prefix        string
+	RatePerMinute int
+	Burst         int
+	DailyQuota    int
+	Internal      bool
+	Active        bool
+	CreatedAt     time.Time
+	LastUsedAt    *time.Time
+}
+
+// Store issues and looks up keys. Lookups are cached for cacheTTL, so a
+// revoked key stops working within that window on every API instance.
+type Store struct {
+	db       *sql.DB
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedKey
+}
+
+type cachedKey struct {
+	key      Key
+	found    bool
+	loadedAt time.Time
+}
+
+// maxCachedKeys bounds the cache, which also remembers unknown secrets so
+// random keys cannot hammer the database.
+const maxCachedKeys = 10000
+
+func NewStore(db *sql.DB, cacheTTL time.Duration) *Store {
+	if cacheTTL <= 0 {
+		cacheTTL = time.Minute
+	}
+	return &Store{db: db, cacheTTL: cacheTTL, cache: make(map[string]cachedKey)}
+}
+
+// Issue stores k and returns it with its secret. The secret is only ever
+// returned here; the database keeps its hash.
+func (s *Store) Issue(ctx context.Context, k Key) (Key, string, error) {
+	k.Name = strings.TrimSpace(k.Name)
+	if k.Name == "" {
+		return Key{}, "", errors.New("name is required")
+	}
+	if k.RatePerMinute < 0 || k.Burst < 0 || k.DailyQuota < 0 {
+		return Key{}, "", errors.New("limits must not be negative")
+	}
+	if k.RatePerMinute > 0 && k.Burst == 0 {
+		k.Burst = k.RatePerMinute
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return Key{}, "", fmt.Errorf("generate key: %w", err)
+	}
+	secret := secretPrefix + hex.EncodeToString(b)
+	k.Prefix = secret[:len(secretPrefix)+8]
+	k.Active = true
+
+	err := s.db.QueryRowContext(ctx, `
+INSERT INTO api_keys (name, key_prefix, key_hash, rate_per_minute, burst, daily_quota, is_internal)
+VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, 0), NULLIF($6, 0), $7)
+RETURNING id, created_at
+`, k.Name, k.Prefix, hashSecret(secret), k.RatePerMinute, k.Burst, k.DailyQuota, k.Internal).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return Key{}, "", err
+	}
+	return k, secret, nil
+}
+
+// Revoke deactivates a key. Cached lookups expire within the cache TTL.
+func (s *Store) Revoke(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, `
+UPDATE api_keys SET is_active = FALSE, revoked_at = NOW() WHERE id = $1 AND is_active = TRUE
+`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) List(ctx context.Context) ([]Key, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, name, key_prefix, rate_per_minute, burst, daily_quota, is_internal, is_active, created_at, last_used_at
+FROM api_keys
+ORDER BY id
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]Key, 0)
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Lookup returns the active key for secret, or ErrNotFound.
+func (s *Store) Lookup(ctx context.Context, secret string) (Key, error) {
+	if !strings.HasPrefix(secret, secretPrefix) {
+		return Key{}, ErrNotFound
+	}
+	hash := hashSecret(secret)
+
+	s.mu.Lock()
+	c, ok := s.cache[hash]
+	s.mu.Unlock()
+	if ok && time.Since(c.loadedAt) < s.cacheTTL {
+		if !c.found {
+			return Key{}, ErrNotFound
+		}
+		return c.key, nil
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+SELECT id, name, key_prefix, rate_per_minute, burst, daily_quota, is_internal, is_active, created_at, last_used_at
+FROM api_keys
+WHERE key_hash = $1 AND is_active = TRUE
+`, hash)
+	k, err := scanKey(row)
+	found := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Key{}, err
+	}
+
+	s.mu.Lock()
+	if len(s.cache) >= maxCachedKeys {
+		s.cache = make(map[string]cachedKey)
+	}
+	s.cache[hash] = cachedKey{key: k, found: found, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	if !found {
+		return Key{}, ErrNotFound
+	}
+	return k, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner) (Key, error) {
+	var (
+		k                  Key
+		rate, burst, quota sql.NullInt64
+		lastUsed           sql.NullTime
+	)
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &rate, &burst, &quota, &k.Internal, &k.Active, &k.CreatedAt, &lastUsed); err != nil {
+		return Key{}, err
+	}
+	k.RatePerMinute = int(rate.Int64)
+	k.Burst = int(burst.Int64)
+	k.DailyQuota = int(quota.Int64)
+	if lastUsed.Valid {
+		k.LastUsedAt = &lastUsed.Time
+	}
+	return k, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,189 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Usage counts requests per key, UTC day and endpoint in memory and writes
+// them to api_key_usage_daily every interval. It also tracks how much of
+// each key's daily quota is used; with several API instances each one adds
+// its own requests to the count it loaded, so quotas are approximate.
+type Usage struct {
+	db       *sql.DB
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	pending   map[usageKey]usageCount
+	used      map[int64]dayUsage
+	lastFlush time.Time
+	flushing  atomic.Bool
+}
+
+type usageKey struct {
+	KeyID    int64
+	Day      string
+	Endpoint string
+}
+
+type usageCount struct {
+	Requests int64
+	Rejected int64
+}
+
+type dayUsage struct {
+	Day   string
+	Count int64
+}
+
+func NewUsage(db *sql.DB, interval time.Duration) *Usage {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &Usage{
+		db:        db,
+		interval:  interval,
+		now:       time.Now,
+		pending:   make(map[usageKey]usageCount),
+		used:      make(map[int64]dayUsage),
+		lastFlush: time.Now(),
+	}
+}
+
+// Used returns the requests counted against keyID today, loading the stored
+// count the first time the key is seen each day.
+func (u *Usage) Used(ctx context.Context, keyID int64) (int64, error) {
+	day := u.day()
+	u.mu.Lock()
+	d, ok := u.used[keyID]
+	u.mu.Unlock()
+	if ok && d.Day == day {
+		return d.Count, nil
+	}
+
+	var stored int64
+	err := u.db.QueryRowContext(ctx, `
+SELECT COALESCE(SUM(request_count), 0)::BIGINT
+FROM api_key_usage_daily
+WHERE api_key_id = $1 AND usage_date = $2::DATE
+`, keyID, day).Scan(&stored)
+	if err != nil {
+		return 0, err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if d, ok := u.used[keyID]; ok && d.Day == day {
+		return d.Count, nil
+	}
+	for k, c := range u.pending {
+		if k.KeyID == keyID && k.Day == day {
+			stored += c.Requests
+		}
+	}
+	u.used[keyID] = dayUsage{Day: day, Count: stored}
+	return stored, nil
+}
+
+// Record counts one request for keyID and starts a background flush when
+// one is due.
+func (u *Usage) Record(keyID int64, endpoint string, rejected bool) {
+	day := u.day()
+	k := usageKey{KeyID: keyID, Day: day, Endpoint: endpoint}
+
+	u.mu.Lock()
+	c := u.pending[k]
+	if rejected {
+		c.Rejected++
+	} else {
+		c.Requests++
+		if d, ok := u.used[keyID]; ok && d.Day == day {
+			d.Count++
+			u.used[keyID] = d
+		}
+	}
+	u.pending[k] = c
+	due := u.now().Sub(u.lastFlush) >= u.interval
+	u.mu.Unlock()
+
+	if due && u.flushing.CompareAndSwap(false, true) {
+		go func() {
+			defer u.flushing.Store(false)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := u.Flush(ctx); err != nil {
+				slog.Warn("flush api key usage", "err", err)
+			}
+		}()
+	}
+}
+
+// Flush writes pending counts. On failure they are kept for the next flush.
+func (u *Usage) Flush(ctx context.Context) error {
+	u.mu.Lock()
+	batch := u.pending
+	u.pending = make(map[usageKey]usageCount)
+	u.lastFlush = u.now()
+	u.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := u.write(ctx, batch); err != nil {
+		u.mu.Lock()
+		for k, c := range batch {
+			p := u.pending[k]
+			p.Requests += c.Requests
+			p.Rejected += c.Rejected
+			u.pending[k] = p
+		}
+		u.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (u *Usage) write(ctx context.Context, batch map[usageKey]usageCount) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int64, 0, len(batch))
+	seen := make(map[int64]bool, len(batch))
+	for k, c := range batch {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO api_key_usage_daily (api_key_id, usage_date, endpoint, request_count, rejected_count)
+VALUES ($1, $2::DATE, $3, $4, $5)
+ON CONFLICT (api_key_id, usage_date, endpoint) DO UPDATE SET
+	request_count = api_key_usage_daily.request_count + EXCLUDED.request_count,
+	rejected_count = api_key_usage_daily.rejected_count + EXCLUDED.rejected_count
+`, k.KeyID, k.Day, k.Endpoint, c.Requests, c.Rejected); err != nil {
+			return err
+		}
+		if !seen[k.KeyID] {
+			seen[k.KeyID] = true
+			ids = append(ids, k.KeyID)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = ANY($1)`, ids); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UntilReset is how long until daily quotas reset at midnight UTC.
+func (u *Usage) UntilReset() time.Duration {
+	now := u.now().UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
+
+func (u *Usage) day() string {
+	return u.now().UTC().Format("2006-01-02")
+}
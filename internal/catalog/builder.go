@@ -5,9 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
+
+	"flashlight-ratings-go/internal/amazon"
+	"flashlight-ratings-go/internal/readmodel"
 )
 
 type Builder struct {
@@ -20,6 +23,7 @@ type BuildResult struct {
 	Products   int
 	Images     int
 	Affiliates int
+	Redirects  int
 }
 
 func NewBuilder(db *sql.DB, partnerTag string) *Builder {
@@ -54,17 +58,33 @@ func (b *Builder) Build(ctx context.Context, cat *Catalog) (*BuildResult, error)
 		return nil, fmt.Errorf("upsert battery types: %w", err)
 	}
 
+	current := make(map[string]bool, len(cat.Products))
+	for _, p := range cat.Products {
+		current[p.Slug] = true
+	}
+
 	for _, p := range cat.Products {
 		brandID, ok := brandIDs[p.BrandSlug]
 		if !ok {
 			return nil, fmt.Errorf("brand not found for slug %q", p.BrandSlug)
 		}
 
+		oldSlugs, err := b.renameFlashlight(ctx, tx, p, current)
+		if err != nil {
+			return nil, fmt.Errorf("rename flashlight %s: %w", p.Slug, err)
+		}
+
 		fID, err := b.upsertFlashlight(ctx, tx, p, brandID)
 		if err != nil {
 			return nil, fmt.Errorf("upsert flashlight %s: %w", p.Slug, err)
 		}
 
+		redirects, err := b.recordSlugHistory(ctx, tx, fID, p.Slug, oldSlugs)
+		if err != nil {
+			return nil, fmt.Errorf("slug history for %s: %w", p.Slug, err)
+		}
+		result.Redirects += redirects
+
 		if err := b.upsertSpecs(ctx, tx, fID, p.Specs); err != nil {
 			return nil, fmt.Errorf("upsert specs for %s: %w", p.Slug, err)
 		}
@@ -75,8 +95,14 @@ func (b *Builder) Build(ctx context.Context, cat *Catalog) (*BuildResult, error)
 		}
 		result.Images += n
 
+		if len(p.Modes) > 0 {
+			if err := b.replaceModes(ctx, tx, fID, p.Modes); err != nil {
+				return nil, fmt.Errorf("replace modes for %s: %w", p.Slug, err)
+			}
+		}
+
 		if p.ASIN != "" {
-			if err := b.upsertAffiliateLink(ctx, tx, fID, p); err != nil {
+			if err := b.upsertAffiliateLink(ctx, tx, fID, "US", p.ASIN); err != nil {
 				return nil, fmt.Errorf("upsert affiliate for %s: %w", p.Slug, err)
 			}
 			result.Affiliates++
@@ -102,9 +128,12 @@ func (b *Builder) Build(ctx context.Context, cat *Catalog) (*BuildResult, error)
 		}
 
 		result.Products++
-		log.Printf("  %s %s (id=%d, %d images)", p.Brand, p.Name, fID, len(p.Images))
+		slog.Info("catalog product saved", "brand", p.Brand, "name", p.Name, "flashlight_id", fID, "images", len(p.Images))
 	}
 
+	if _, err := readmodel.Refresh(ctx, tx); err != nil {
+		return nil, fmt.Errorf("refresh read model: %w", err)
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("commit: %w", err)
 	}
@@ -112,6 +141,116 @@ func (b *Builder) Build(ctx context.Context, cat *Catalog) (*BuildResult, error)
 	return result, nil
 }
 
+// SaveProduct writes one product inside tx with the same upserts Build uses,
+// for callers that edit a single light. id is the light being edited, or 0
+// to create one; when its slug changes the old slug is kept as a redirect.
+// Specs, media and modes are only written when p has them, and price
+// snapshots are left to the sync worker.
+func (b *Builder) SaveProduct(ctx context.Context, tx *sql.Tx, id int64, p Product) (int64, error) {
+	brandIDs, err := b.upsertBrands(ctx, tx, []Product{p})
+	if err != nil {
+		return 0, fmt.Errorf("upsert brand: %w", err)
+	}
+	useCaseIDs, err := b.upsertUseCases(ctx, tx, []Product{p})
+	if err != nil {
+		return 0, fmt.Errorf("upsert use cases: %w", err)
+	}
+	if err := b.upsertBatteryTypes(ctx, tx, []Product{p}); err != nil {
+		return 0, fmt.Errorf("upsert battery types: %w", err)
+	}
+
+	oldSlugs := make([]string, 0, len(p.PreviousSlugs)+1)
+	for _, old := range p.PreviousSlugs {
+		if old != "" && old != p.Slug {
+			oldSlugs = append(oldSlugs, old)
+		}
+	}
+	if id > 0 {
+		var current string
+		if err := tx.QueryRowContext(ctx, `SELECT slug FROM flashlights WHERE id = $1`, id).Scan(&current); err != nil {
+			return 0, err
+		}
+		if current != p.Slug {
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE flashlights SET slug = $2, updated_at = NOW() WHERE id = $1
+			`, id, p.Slug); err != nil {
+				return 0, fmt.Errorf("rename flashlight: %w", err)
+			}
+			oldSlugs = append(oldSlugs, current)
+		}
+	}
+
+	fID, err := b.upsertFlashlight(ctx, tx, p, brandIDs[p.BrandSlug])
+	if err != nil {
+		return 0, fmt.Errorf("upsert flashlight: %w", err)
+	}
+	if id > 0 && fID != id {
+		return 0, fmt.Errorf("slug %s belongs to flashlight %d", p.Slug, fID)
+	}
+	if _, err := b.recordSlugHistory(ctx, tx, fID, p.Slug, oldSlugs); err != nil {
+		return 0, fmt.Errorf("slug history: %w", err)
+	}
+
+	if p.Specs != (Specs{}) {
+		if err := b.SaveSpecs(ctx, tx, fID, p.Specs); err != nil {
+			return 0, err
+		}
+	}
+	if len(p.Images) > 0 {
+		if _, err := b.replaceMedia(ctx, tx, fID, p); err != nil {
+			return 0, fmt.Errorf("replace media: %w", err)
+		}
+	}
+	if len(p.Modes) > 0 {
+		if err := b.replaceModes(ctx, tx, fID, p.Modes); err != nil {
+			return 0, fmt.Errorf("replace modes: %w", err)
+		}
+	}
+	if p.ASIN != "" {
+		if err := b.upsertAffiliateLink(ctx, tx, fID, "US", p.ASIN); err != nil {
+			return 0, fmt.Errorf("upsert affiliate: %w", err)
+		}
+	}
+	if err := b.upsertUseCaseMapping(ctx, tx, fID, p.UseCases, useCaseIDs); err != nil {
+		return 0, fmt.Errorf("use case mapping: %w", err)
+	}
+	return fID, nil
+}
+
+// SaveSpecs upserts a light's specs and its primary battery inside tx. Zero
+// fields keep their stored value.
+func (b *Builder) SaveSpecs(ctx context.Context, tx *sql.Tx, fID int64, s Specs) error {
+	if err := b.upsertBatteryTypes(ctx, tx, []Product{{Specs: s}}); err != nil {
+		return fmt.Errorf("upsert battery types: %w", err)
+	}
+	if err := b.upsertSpecs(ctx, tx, fID, s); err != nil {
+		return fmt.Errorf("upsert specs: %w", err)
+	}
+	if s.BatteryType != "" {
+		if err := b.upsertBatteryCompat(ctx, tx, fID, s); err != nil {
+			return fmt.Errorf("battery compat: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReplaceMedia replaces a light's images with p.Images inside tx. p.Brand and
+// p.Name supply the default alt text.
+func (b *Builder) ReplaceMedia(ctx context.Context, tx *sql.Tx, fID int64, p Product) (int, error) {
+	return b.replaceMedia(ctx, tx, fID, p)
+}
+
+// ReplaceModes replaces a light's modes inside tx.
+func (b *Builder) ReplaceModes(ctx context.Context, tx *sql.Tx, fID int64, modes []Mode) error {
+	return b.replaceModes(ctx, tx, fID, modes)
+}
+
+// SaveAffiliateLink points a light's primary Amazon link for region at asin,
+// reactivating it if needed.
+func (b *Builder) SaveAffiliateLink(ctx context.Context, tx *sql.Tx, fID int64, region, asin string) error {
+	return b.upsertAffiliateLink(ctx, tx, fID, region, asin)
+}
+
 func (b *Builder) ensureSchema(ctx context.Context, tx *sql.Tx) error {
 	_, err := tx.ExecContext(ctx, `
 		ALTER TABLE flashlights
@@ -126,6 +265,14 @@ func (b *Builder) ensureSchema(ctx context.Context, tx *sql.Tx) error {
 		  ADD COLUMN IF NOT EXISTS body_material TEXT;
 		CREATE UNIQUE INDEX IF NOT EXISTS uq_affiliate_primary
 		  ON affiliate_links (flashlight_id, provider, region_code) WHERE is_primary = TRUE;
+		CREATE TABLE IF NOT EXISTS flashlight_slug_history (
+		  old_slug TEXT PRIMARY KEY,
+		  flashlight_id BIGINT NOT NULL REFERENCES flashlights(id) ON DELETE CASCADE,
+		  changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		  CHECK (old_slug ~ '^[a-z0-9]+(?:-[a-z0-9]+)*$')
+		);
+		CREATE INDEX IF NOT EXISTS idx_slug_history_flashlight
+		  ON flashlight_slug_history (flashlight_id);
 	`)
 	return err
 }
@@ -230,6 +377,109 @@ func (b *Builder) upsertFlashlight(ctx context.Context, tx *sql.Tx, p Product, b
 	return id, err
 }
 
+// renameFlashlight moves an existing row to p.Slug when the product is
+// already stored under an older slug, so its id, scores and snapshots survive
+// the rename. The old row is found through previous_slugs or, failing that,
+// through a primary Amazon link with the same ASIN whose slug is no longer in
+// the catalog. It returns every old slug that should redirect to p.Slug.
+func (b *Builder) renameFlashlight(ctx context.Context, tx *sql.Tx, p Product, current map[string]bool) ([]string, error) {
+	oldSlugs := make([]string, 0, len(p.PreviousSlugs)+1)
+	for _, old := range p.PreviousSlugs {
+		if old != "" && old != p.Slug && !current[old] {
+			oldSlugs = append(oldSlugs, old)
+		}
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM flashlights WHERE slug = $1)`, p.Slug).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists {
+		return oldSlugs, nil
+	}
+
+	var renameFrom string
+	for _, old := range oldSlugs {
+		var found bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM flashlights WHERE slug = $1)`, old).Scan(&found); err != nil {
+			return nil, err
+		}
+		if found {
+			renameFrom = old
+			break
+		}
+	}
+	if renameFrom == "" && p.ASIN != "" {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT f.slug
+			FROM affiliate_links a
+			JOIN flashlights f ON f.id = a.flashlight_id
+			WHERE a.provider = 'amazon' AND a.is_primary = TRUE AND a.asin = $1
+			ORDER BY f.updated_at DESC, f.id DESC
+		`, p.ASIN)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var slug string
+			if err := rows.Scan(&slug); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if !current[slug] {
+				renameFrom = slug
+				oldSlugs = append(oldSlugs, slug)
+				break
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if renameFrom == "" {
+		return oldSlugs, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE flashlights SET slug = $2, updated_at = NOW() WHERE slug = $1
+	`, renameFrom, p.Slug); err != nil {
+		return nil, err
+	}
+	slog.Info("flashlight renamed", "from", renameFrom, "to", p.Slug)
+	return oldSlugs, nil
+}
+
+// recordSlugHistory points every old slug at fID. Any row still stored under
+// an old slug is a superseded duplicate and is deactivated. A slug that comes
+// back into use stops redirecting.
+func (b *Builder) recordSlugHistory(ctx context.Context, tx *sql.Tx, fID int64, slug string, oldSlugs []string) (int, error) {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM flashlight_slug_history WHERE old_slug = $1`, slug); err != nil {
+		return 0, err
+	}
+	for _, old := range oldSlugs {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO flashlight_slug_history (old_slug, flashlight_id, changed_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (old_slug) DO UPDATE
+			SET flashlight_id = EXCLUDED.flashlight_id,
+				changed_at = CASE
+					WHEN flashlight_slug_history.flashlight_id = EXCLUDED.flashlight_id THEN flashlight_slug_history.changed_at
+					ELSE EXCLUDED.changed_at
+				END
+		`, old, fID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE flashlights SET is_active = FALSE, updated_at = NOW()
+			WHERE slug = $1 AND id <> $2 AND is_active = TRUE
+		`, old, fID); err != nil {
+			return 0, err
+		}
+	}
+	return len(oldSlugs), nil
+}
+
 func (b *Builder) upsertSpecs(ctx context.Context, tx *sql.Tx, fID int64, s Specs) error {
 	usbC := s.RechargeType == "usb-c"
 	rechargeable := s.RechargeType != "" && s.RechargeType != "none"
@@ -318,26 +568,47 @@ func (b *Builder) replaceMedia(ctx context.Context, tx *sql.Tx, fID int64, p Pro
 	return len(p.Images), nil
 }
 
-func (b *Builder) upsertAffiliateLink(ctx context.Context, tx *sql.Tx, fID int64, p Product) error {
-	affiliateURL := fmt.Sprintf("https://www.amazon.com/dp/%s?tag=%s", p.ASIN, b.partnerTag)
+func (b *Builder) replaceModes(ctx context.Context, tx *sql.Tx, fID int64, modes []Mode) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM flashlight_modes WHERE flashlight_id = $1`, fID); err != nil {
+		return err
+	}
+	for i, m := range modes {
+		order := m.Order
+		if order == 0 {
+			order = i + 1
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO flashlight_modes (flashlight_id, mode_name, output_lumens, runtime_min, candela, beam_distance_m, mode_order)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, fID, strings.TrimSpace(m.Name), nullInt(m.OutputLumens), nullInt(m.RuntimeMin),
+			nullInt(m.Candela), nullInt(m.BeamDistanceM), order)
+		if err != nil {
+			return fmt.Errorf("mode %q: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+func (b *Builder) upsertAffiliateLink(ctx context.Context, tx *sql.Tx, fID int64, region, asin string) error {
+	affiliateURL := amazon.AffiliateURL(region, asin, b.partnerTag)
 
 	_, err := tx.ExecContext(ctx, `
 		INSERT INTO affiliate_links (flashlight_id, provider, region_code, affiliate_url, asin, is_primary, is_active, updated_at)
-		VALUES ($1, 'amazon', 'US', $2, $3, TRUE, TRUE, NOW())
+		VALUES ($1, 'amazon', $2, $3, $4, TRUE, TRUE, NOW())
 		ON CONFLICT (flashlight_id, provider, region_code) WHERE is_primary = TRUE
 		DO UPDATE SET
 			affiliate_url = EXCLUDED.affiliate_url,
 			asin = EXCLUDED.asin,
 			is_active = TRUE,
 			updated_at = NOW()
-	`, fID, affiliateURL, p.ASIN)
+	`, fID, region, affiliateURL, asin)
 	if err != nil {
 		// Fallback: try update then insert
 		res, err2 := tx.ExecContext(ctx, `
 			UPDATE affiliate_links
 			SET affiliate_url = $3, asin = $4, is_active = TRUE, updated_at = NOW()
-			WHERE flashlight_id = $1 AND provider = 'amazon' AND region_code = 'US' AND is_primary = TRUE
-		`, fID, "amazon", affiliateURL, p.ASIN)
+			WHERE flashlight_id = $1 AND provider = 'amazon' AND region_code = $2 AND is_primary = TRUE
+		`, fID, region, affiliateURL, asin)
 		if err2 != nil {
 			return err
 		}
@@ -345,8 +616,8 @@ func (b *Builder) upsertAffiliateLink(ctx context.Context, tx *sql.Tx, fID int64
 		if rows == 0 {
 			_, err2 = tx.ExecContext(ctx, `
 				INSERT INTO affiliate_links (flashlight_id, provider, region_code, affiliate_url, asin, is_primary, is_active, updated_at)
-				VALUES ($1, 'amazon', 'US', $2, $3, TRUE, TRUE, NOW())
-			`, fID, affiliateURL, p.ASIN)
+				VALUES ($1, 'amazon', $2, $3, $4, TRUE, TRUE, NOW())
+			`, fID, region, affiliateURL, asin)
 			return err2
 		}
 	}
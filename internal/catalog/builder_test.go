@@ -0,0 +1,162 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// slugDB is a database holding only flashlight slugs. It answers the
+// builder's slug existence checks and records every statement run through
+// Exec, so a test can see which renames and history rows a build writes.
+type slugDB struct {
+	slugs map[string]bool
+	execs []execCall
+}
+
+type execCall struct {
+	query string
+	args  []driver.Value
+}
+
+func (d *slugDB) Connect(context.Context) (driver.Conn, error) { return slugConn{d}, nil }
+func (d *slugDB) Driver() driver.Driver                        { return nil }
+
+// wrote reports whether a statement starting with prefix ran with args.
+func (d *slugDB) wrote(prefix string, args ...driver.Value) bool {
+	for _, e := range d.execs {
+		if strings.HasPrefix(e.query, prefix) && len(e.args) == len(args) {
+			match := true
+			for i := range args {
+				match = match && e.args[i] == args[i]
+			}
+			if match {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type slugConn struct{ db *slugDB }
+
+func (c slugConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("slugdb: prepare") }
+func (c slugConn) Close() error                        { return nil }
+func (c slugConn) Begin() (driver.Tx, error)           { return slugTx{}, nil }
+
+func (c slugConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "SELECT EXISTS (SELECT 1 FROM flashlights WHERE slug = $1)") {
+		return nil, errors.New("slugdb: unexpected query " + query)
+	}
+	return &boolRows{v: c.db.slugs[args[0].Value.(string)]}, nil
+}
+
+func (c slugConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	call := execCall{query: strings.Join(strings.Fields(query), " ")}
+	for _, a := range args {
+		call.args = append(call.args, a.Value)
+	}
+	c.db.execs = append(c.db.execs, call)
+	if strings.HasPrefix(call.query, "UPDATE flashlights SET slug = $2") {
+		delete(c.db.slugs, call.args[0].(string))
+		c.db.slugs[call.args[1].(string)] = true
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type slugTx struct{}
+
+func (slugTx) Commit() error   { return nil }
+func (slugTx) Rollback() error { return nil }
+
+type boolRows struct {
+	v    bool
+	done bool
+}
+
+func (r *boolRows) Columns() []string { return []string{"exists"} }
+func (r *boolRows) Close() error      { return nil }
+func (r *boolRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	dest[0], r.done = r.v, true
+	return nil
+}
+
+// renameAndRecord runs the slug steps of a build for p with fID as the id
+// the upsert returns.
+func renameAndRecord(t *testing.T, d *slugDB, p Product, current map[string]bool, fID int64) []string {
+	t.Helper()
+	ctx := context.Background()
+	db := sql.OpenDB(d)
+	defer db.Close()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	b := &Builder{db: db}
+	oldSlugs, err := b.renameFlashlight(ctx, tx, p, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := b.recordSlugHistory(ctx, tx, fID, p.Slug, oldSlugs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(oldSlugs) {
+		t.Fatalf("recordSlugHistory = %d, want %d", n, len(oldSlugs))
+	}
+	return oldSlugs
+}
+
+func TestRenameRecordsSlugHistory(t *testing.T) {
+	d := &slugDB{slugs: map[string]bool{"acme-one": true}}
+	p := Product{Slug: "acme-one-v2", PreviousSlugs: []string{"acme-one"}}
+	old := renameAndRecord(t, d, p, map[string]bool{"acme-one-v2": true}, 7)
+
+	if len(old) != 1 || old[0] != "acme-one" {
+		t.Fatalf("old slugs = %v", old)
+	}
+	if !d.wrote("UPDATE flashlights SET slug = $2", "acme-one", "acme-one-v2") {
+		t.Fatal("existing row not renamed")
+	}
+	if !d.wrote("DELETE FROM flashlight_slug_history WHERE old_slug = $1", "acme-one-v2") {
+		t.Fatal("new slug still redirects")
+	}
+	if !d.wrote("INSERT INTO flashlight_slug_history", "acme-one", int64(7)) {
+		t.Fatal("no history row for the old slug")
+	}
+}
+
+func TestPreviousSlugOwnedByAnotherLight(t *testing.T) {
+	// acme-two is still the slug of another product in the catalog, so it is
+	// neither taken over nor redirected.
+	d := &slugDB{slugs: map[string]bool{"acme-one": true, "acme-two": true}}
+	p := Product{Slug: "acme-one", PreviousSlugs: []string{"acme-two"}}
+	old := renameAndRecord(t, d, p, map[string]bool{"acme-one": true, "acme-two": true}, 7)
+	if len(old) != 0 || d.wrote("INSERT INTO flashlight_slug_history", "acme-two", int64(7)) {
+		t.Fatalf("previous slug of a catalog light was recorded: %v", old)
+	}
+
+	// acme-old is a leftover row no catalog product uses any more. Both it and
+	// the new slug exist, so the leftover is deactivated and redirects.
+	d = &slugDB{slugs: map[string]bool{"acme-one": true, "acme-old": true}}
+	p = Product{Slug: "acme-one", PreviousSlugs: []string{"acme-old"}}
+	renameAndRecord(t, d, p, map[string]bool{"acme-one": true}, 7)
+	if d.wrote("UPDATE flashlights SET slug = $2", "acme-old", "acme-one") {
+		t.Fatal("renamed onto a slug that is already taken")
+	}
+	if !d.wrote("INSERT INTO flashlight_slug_history", "acme-old", int64(7)) {
+		t.Fatal("no history row for the leftover slug")
+	}
+	if !d.wrote("UPDATE flashlights SET is_active = FALSE", "acme-old", int64(7)) {
+		t.Fatal("leftover row under the old slug stays active")
+	}
+}
@@ -10,69 +10,85 @@ import (
 )
 
 type Catalog struct {
-	Products []Product `yaml:"products"`
+	Products []Product `yaml:"products" json:"products"`
 }
 
 type Product struct {
-	Brand        string  `yaml:"brand"`
-	BrandSlug    string  `yaml:"brand_slug"`
-	BrandCountry string  `yaml:"brand_country"`
-	BrandWebsite string  `yaml:"brand_website"`
-
-	Name        string  `yaml:"name"`
-	Slug        string  `yaml:"slug"`
-	Code        string  `yaml:"code"`
-	Description string  `yaml:"description"`
-	ReleaseYear int     `yaml:"release_year"`
-	MSRP        float64 `yaml:"msrp_usd"`
-
-	ASIN            string  `yaml:"asin"`
-	PriceUSD        float64 `yaml:"price_usd"`
-	RatingCount     int     `yaml:"rating_count"`
-	AverageRating   float64 `yaml:"average_rating"`
-	ManufacturerURL string  `yaml:"manufacturer_url"`
-
-	Images   []Image  `yaml:"images"`
-	Specs    Specs    `yaml:"specs"`
-	UseCases []string `yaml:"use_cases"`
+	Brand        string `yaml:"brand" json:"brand"`
+	BrandSlug    string `yaml:"brand_slug" json:"brand_slug"`
+	BrandCountry string `yaml:"brand_country" json:"brand_country"`
+	BrandWebsite string `yaml:"brand_website" json:"brand_website"`
+
+	Name string `yaml:"name" json:"name"`
+	Slug string `yaml:"slug" json:"slug"`
+	// PreviousSlugs lists slugs this product was published under before.
+	// The builder records them in flashlight_slug_history so old URLs
+	// keep resolving.
+	PreviousSlugs []string `yaml:"previous_slugs" json:"previous_slugs"`
+	Code          string   `yaml:"code" json:"code"`
+	Description   string   `yaml:"description" json:"description"`
+	ReleaseYear   int      `yaml:"release_year" json:"release_year"`
+	MSRP          float64  `yaml:"msrp_usd" json:"msrp_usd"`
+
+	ASIN            string  `yaml:"asin" json:"asin"`
+	PriceUSD        float64 `yaml:"price_usd" json:"price_usd"`
+	RatingCount     int     `yaml:"rating_count" json:"rating_count"`
+	AverageRating   float64 `yaml:"average_rating" json:"average_rating"`
+	ManufacturerURL string  `yaml:"manufacturer_url" json:"manufacturer_url"`
+
+	Images   []Image  `yaml:"images" json:"images"`
+	Specs    Specs    `yaml:"specs" json:"specs"`
+	Modes    []Mode   `yaml:"modes" json:"modes"`
+	UseCases []string `yaml:"use_cases" json:"use_cases"`
 }
 
 type Image struct {
-	URL string `yaml:"url"`
-	Alt string `yaml:"alt"`
+	URL string `yaml:"url" json:"url"`
+	Alt string `yaml:"alt" json:"alt"`
+}
+
+// Mode is one output level. Modes are listed brightest first unless Order is
+// set.
+type Mode struct {
+	Name          string `yaml:"name" json:"name"`
+	OutputLumens  int    `yaml:"output_lumens" json:"output_lumens"`
+	RuntimeMin    int    `yaml:"runtime_min" json:"runtime_min"`
+	Candela       int    `yaml:"candela" json:"candela"`
+	BeamDistanceM int    `yaml:"beam_distance_m" json:"beam_distance_m"`
+	Order         int    `yaml:"order" json:"order"`
 }
 
 type Specs struct {
-	MaxLumens        int     `yaml:"max_lumens"`
-	SustainedLumens  int     `yaml:"sustained_lumens"`
-	MaxCandela       int     `yaml:"max_candela"`
-	BeamDistanceM    int     `yaml:"beam_distance_m"`
-	RuntimeHighMin   int     `yaml:"runtime_high_min"`
-	Runtime500Min    int     `yaml:"runtime_500_min"`
-	TurboStepdownSec int     `yaml:"turbo_stepdown_sec"`
-	BeamPattern      string  `yaml:"beam_pattern"`
-	BatteryType      string  `yaml:"battery_type"`
-	RechargeType     string  `yaml:"recharge_type"`
-	BatteryReplaceable *bool `yaml:"battery_replaceable"`
-
-	WeightG        float64 `yaml:"weight_g"`
-	LengthMM       float64 `yaml:"length_mm"`
-	HeadDiameterMM float64 `yaml:"head_diameter_mm"`
-	BodyDiameterMM float64 `yaml:"body_diameter_mm"`
-
-	SwitchType        string  `yaml:"switch_type"`
-	WaterproofRating  string  `yaml:"waterproof_rating"`
-	ImpactResistanceM float64 `yaml:"impact_resistance_m"`
-	BodyMaterial      string  `yaml:"body_material"`
-	LEDModel          string  `yaml:"led_model"`
-	CRI               int     `yaml:"cri"`
-
-	HasStrobe          *bool `yaml:"has_strobe"`
-	HasMemoryMode      *bool `yaml:"has_memory_mode"`
-	HasLockout         *bool `yaml:"has_lockout"`
-	HasMoonlightMode   *bool `yaml:"has_moonlight_mode"`
-	HasMagneticTailcap *bool `yaml:"has_magnetic_tailcap"`
-	HasPocketClip      *bool `yaml:"has_pocket_clip"`
+	MaxLumens          int    `yaml:"max_lumens" json:"max_lumens"`
+	SustainedLumens    int    `yaml:"sustained_lumens" json:"sustained_lumens"`
+	MaxCandela         int    `yaml:"max_candela" json:"max_candela"`
+	BeamDistanceM      int    `yaml:"beam_distance_m" json:"beam_distance_m"`
+	RuntimeHighMin     int    `yaml:"runtime_high_min" json:"runtime_high_min"`
+	Runtime500Min      int    `yaml:"runtime_500_min" json:"runtime_500_min"`
+	TurboStepdownSec   int    `yaml:"turbo_stepdown_sec" json:"turbo_stepdown_sec"`
+	BeamPattern        string `yaml:"beam_pattern" json:"beam_pattern"`
+	BatteryType        string `yaml:"battery_type" json:"battery_type"`
+	RechargeType       string `yaml:"recharge_type" json:"recharge_type"`
+	BatteryReplaceable *bool  `yaml:"battery_replaceable" json:"battery_replaceable"`
+
+	WeightG        float64 `yaml:"weight_g" json:"weight_g"`
+	LengthMM       float64 `yaml:"length_mm" json:"length_mm"`
+	HeadDiameterMM float64 `yaml:"head_diameter_mm" json:"head_diameter_mm"`
+	BodyDiameterMM float64 `yaml:"body_diameter_mm" json:"body_diameter_mm"`
+
+	SwitchType        string  `yaml:"switch_type" json:"switch_type"`
+	WaterproofRating  string  `yaml:"waterproof_rating" json:"waterproof_rating"`
+	ImpactResistanceM float64 `yaml:"impact_resistance_m" json:"impact_resistance_m"`
+	BodyMaterial      string  `yaml:"body_material" json:"body_material"`
+	LEDModel          string  `yaml:"led_model" json:"led_model"`
+	CRI               int     `yaml:"cri" json:"cri"`
+
+	HasStrobe          *bool `yaml:"has_strobe" json:"has_strobe"`
+	HasMemoryMode      *bool `yaml:"has_memory_mode" json:"has_memory_mode"`
+	HasLockout         *bool `yaml:"has_lockout" json:"has_lockout"`
+	HasMoonlightMode   *bool `yaml:"has_moonlight_mode" json:"has_moonlight_mode"`
+	HasMagneticTailcap *bool `yaml:"has_magnetic_tailcap" json:"has_magnetic_tailcap"`
+	HasPocketClip      *bool `yaml:"has_pocket_clip" json:"has_pocket_clip"`
 }
 
 var slugRe = regexp.MustCompile(`[^a-z0-9]+`)
@@ -89,56 +105,88 @@ func ParseFile(path string) (*Catalog, error) {
 	}
 
 	for i := range cat.Products {
-		p := &cat.Products[i]
-		p.Brand = strings.TrimSpace(p.Brand)
-		p.Name = strings.TrimSpace(p.Name)
-		if p.BrandSlug == "" {
-			p.BrandSlug = makeSlug(p.Brand)
-		}
-		if p.Slug == "" {
-			p.Slug = makeSlug(p.Brand + "-" + p.Name)
-		}
-		p.BrandSlug = strings.Trim(p.BrandSlug, "-")
-		p.Slug = strings.Trim(p.Slug, "-")
-		p.BrandCountry = strings.ToUpper(strings.TrimSpace(p.BrandCountry))
-		p.ASIN = strings.ToUpper(strings.TrimSpace(p.ASIN))
-		p.Description = strings.TrimSpace(p.Description)
+		cat.Products[i].Normalize()
 	}
 
 	return &cat, nil
 }
 
+// Normalize trims p and derives any missing slugs from the brand and name.
+func (p *Product) Normalize() {
+	p.Brand = strings.TrimSpace(p.Brand)
+	p.Name = strings.TrimSpace(p.Name)
+	if p.BrandSlug == "" {
+		p.BrandSlug = makeSlug(p.Brand)
+	}
+	if p.Slug == "" {
+		p.Slug = makeSlug(p.Brand + "-" + p.Name)
+	}
+	p.BrandSlug = strings.Trim(p.BrandSlug, "-")
+	p.Slug = strings.Trim(p.Slug, "-")
+	for j, old := range p.PreviousSlugs {
+		p.PreviousSlugs[j] = makeSlug(old)
+	}
+	p.BrandCountry = strings.ToUpper(strings.TrimSpace(p.BrandCountry))
+	p.ASIN = strings.ToUpper(strings.TrimSpace(p.ASIN))
+	p.Description = strings.TrimSpace(p.Description)
+}
+
+// Check reports problems with a single product. Errors leave nothing usable
+// to store; warnings mark data the site can go without but should not.
+func (p Product) Check() (errs, warnings []string) {
+	if p.Brand == "" {
+		errs = append(errs, "missing brand")
+	}
+	if p.Name == "" {
+		errs = append(errs, "missing name")
+	}
+	if p.ASIN == "" {
+		warnings = append(warnings, "missing ASIN")
+	}
+	if len(p.Images) == 0 {
+		warnings = append(warnings, "no images")
+	}
+	if p.Description == "" {
+		warnings = append(warnings, "missing description")
+	}
+	if p.PriceUSD == 0 {
+		warnings = append(warnings, "missing price")
+	}
+	if p.Specs.MaxLumens == 0 {
+		warnings = append(warnings, "missing max_lumens")
+	}
+	for _, m := range p.Modes {
+		if strings.TrimSpace(m.Name) == "" {
+			errs = append(errs, "mode without name")
+		}
+	}
+	return errs, warnings
+}
+
 func (c *Catalog) Validate() []string {
 	var warnings []string
 	slugs := map[string]bool{}
 	for i, p := range c.Products {
 		label := fmt.Sprintf("[%d] %s %s", i, p.Brand, p.Name)
-		if p.Brand == "" {
-			warnings = append(warnings, label+": missing brand")
-		}
-		if p.Name == "" {
-			warnings = append(warnings, label+": missing name")
-		}
-		if p.ASIN == "" {
-			warnings = append(warnings, label+": missing ASIN")
-		}
-		if len(p.Images) == 0 {
-			warnings = append(warnings, label+": no images")
-		}
-		if p.Description == "" {
-			warnings = append(warnings, label+": missing description")
-		}
-		if p.PriceUSD == 0 {
-			warnings = append(warnings, label+": missing price")
-		}
-		if p.Specs.MaxLumens == 0 {
-			warnings = append(warnings, label+": missing max_lumens")
+		errs, warns := p.Check()
+		for _, w := range append(errs, warns...) {
+			warnings = append(warnings, label+": "+w)
 		}
 		if slugs[p.Slug] {
 			warnings = append(warnings, label+": duplicate slug "+p.Slug)
 		}
 		slugs[p.Slug] = true
 	}
+	for i, p := range c.Products {
+		label := fmt.Sprintf("[%d] %s %s", i, p.Brand, p.Name)
+		for _, old := range p.PreviousSlugs {
+			if old == "" || old == p.Slug {
+				warnings = append(warnings, label+": invalid previous slug "+old)
+			} else if slugs[old] {
+				warnings = append(warnings, label+": previous slug "+old+" is still in use")
+			}
+		}
+	}
 	return warnings
 }
 
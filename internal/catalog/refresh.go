@@ -3,7 +3,7 @@ package catalog
 import (
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"strings"
@@ -49,16 +49,23 @@ type RefreshReport struct {
 	Warnings        []string
 }
 
-func (r *RefreshReport) Print() {
-	log.Printf("refresh report: %d products checked", r.Total)
-	log.Printf("  ASINs:  %d valid, %d invalid", r.ValidASIN, r.InvalidASIN)
-	log.Printf("  Images: %d valid, %d broken, %d scraped from manufacturer sites", r.ValidImages, r.BrokenImages, r.ScrapedImages)
-	log.Printf("  Dropped: %d products (incomplete after validation)", r.Dropped)
+// Log writes the report to the default logger: one summary record, then one
+// record per dropped product and per warning.
+func (r *RefreshReport) Log() {
+	slog.Info("catalog refresh report",
+		"products", r.Total,
+		"valid_asins", r.ValidASIN,
+		"invalid_asins", r.InvalidASIN,
+		"valid_images", r.ValidImages,
+		"broken_images", r.BrokenImages,
+		"scraped_images", r.ScrapedImages,
+		"dropped", r.Dropped,
+	)
 	for _, p := range r.DroppedProducts {
-		log.Printf("    - %s", p)
+		slog.Warn("product dropped, incomplete after validation", "product", p)
 	}
 	for _, w := range r.Warnings {
-		log.Printf("  WARN: %s", w)
+		slog.Warn("catalog refresh warning", "warning", w)
 	}
 }
 
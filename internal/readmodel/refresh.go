@@ -0,0 +1,46 @@
+// Package readmodel keeps flashlight_read_model, the per-light denormalized
+// table the read API queries, in step with the tables it is derived from.
+package readmodel
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier is satisfied by *sql.DB and *sql.Tx.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Refresh rebuilds the read model and returns how many rows changed. It also
+// bumps data_versions.version, so call it last: the version row stays locked
+// until the transaction commits. Pass the transaction that publishes new data
+// so readers never see new source rows without the matching read model. With
+// a *sql.DB the refresh is its own single-statement transaction, which suits
+// writers that commit piecemeal.
+func Refresh(ctx context.Context, db Querier) (int, error) {
+	var changed int
+	err := db.QueryRowContext(ctx, `SELECT refresh_flashlight_read_model()`).Scan(&changed)
+	return changed, err
+}
+
+// PublishVersion bumps data_versions.version, which the API folds into ETags
+// and its response cache key. Refresh and RefreshFlashlights already do;
+// writers of served tables outside the read model call it as the last
+// statement of their transaction.
+func PublishVersion(ctx context.Context, db Querier) error {
+	var version int64
+	return db.QueryRowContext(ctx, `SELECT publish_data_version()`).Scan(&version)
+}
+
+// RefreshFlashlights rebuilds the read model rows of just the given lights.
+// Writers that touch a few lights call it inside their own transaction, so
+// the new source rows and their read model rows commit together.
+func RefreshFlashlights(ctx context.Context, db Querier, ids ...int64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	var changed int
+	err := db.QueryRowContext(ctx, `SELECT refresh_flashlight_read_model($1::BIGINT[])`, ids).Scan(&changed)
+	return changed, err
+}
@@ -8,6 +8,9 @@ import (
 	"math"
 	"strings"
 	"time"
+
+	"flashlight-ratings-go/internal/readmodel"
+	"flashlight-ratings-go/internal/telemetry"
 )
 
 type Engine struct {
@@ -51,7 +54,12 @@ type scoreBreakdown struct {
 	Formula    string                        `json:"formula_version"`
 }
 
-func (e *Engine) RunBatch(ctx context.Context, opts RunOptions) (int64, error) {
+func (e *Engine) RunBatch(ctx context.Context, opts RunOptions) (runID int64, err error) {
+	start := time.Now()
+	defer func() {
+		telemetry.ScoringRunDuration.WithLabelValues(telemetry.Result(err)).Observe(telemetry.Since(start))
+	}()
+
 	if strings.TrimSpace(opts.RunLabel) == "" {
 		opts.RunLabel = fmt.Sprintf("batch-%s", time.Now().UTC().Format("20060102-150405"))
 	}
@@ -62,7 +70,7 @@ func (e *Engine) RunBatch(ctx context.Context, opts RunOptions) (int64, error) {
 		opts.InitiatedBy = "scorejob"
 	}
 
-	runID, err := startRun(ctx, e.db, opts)
+	runID, err = startRun(ctx, e.db, opts)
 	if err != nil {
 		return 0, err
 	}
@@ -114,11 +122,18 @@ func (e *Engine) RunBatch(ctx context.Context, opts RunOptions) (int64, error) {
 		_ = failRun(ctx, e.db, runID, err)
 		return runID, err
 	}
-	if err := tx.Commit(); err != nil {
+	// Completing the run and refreshing the read model in the scoring
+	// transaction publishes the new scores to every read endpoint at once.
+	if err := completeRun(ctx, tx, runID); err != nil {
 		_ = failRun(ctx, e.db, runID, err)
 		return runID, err
 	}
-	if err := completeRun(ctx, e.db, runID); err != nil {
+	if _, err := readmodel.Refresh(ctx, tx); err != nil {
+		_ = failRun(ctx, e.db, runID, fmt.Errorf("refresh read model: %w", err))
+		return runID, err
+	}
+	if err := tx.Commit(); err != nil {
+		_ = failRun(ctx, e.db, runID, err)
 		return runID, err
 	}
 
@@ -196,30 +211,30 @@ func computeScores(row SpecRow, formulaVersion string) (ScoreOutput, []byte) {
 
 	if lumens > 0 {
 		raw["max_lumens"] = lumens
-		norm["max_lumens"] = normalizeHigherLog(lumens, 100, 5000)
+		norm["max_lumens"] = LumensAxis(lumens)
 	}
 	if candela > 0 {
 		raw["max_candela"] = candela
-		norm["max_candela"] = normalizeHigherLog(candela, 1000, 120000)
+		norm["max_candela"] = CandelaAxis(candela)
 	}
 	if beam > 0 {
 		raw["beam_distance_m"] = beam
-		norm["beam_distance_m"] = normalizeHigherLog(beam, 60, 700)
+		norm["beam_distance_m"] = BeamDistanceAxis(beam)
 	}
 	if runtimeHigh > 0 {
 		raw["runtime_high_min"] = runtimeHigh
-		norm["runtime_high_min"] = normalizeHigherLog(runtimeHigh, 20, 300)
+		norm["runtime_high_min"] = RuntimeHighAxis(runtimeHigh)
 	}
 	if runtimeMedium > 0 {
 		raw["runtime_medium_min"] = runtimeMedium
-		norm["runtime_medium_min"] = normalizeHigherLog(runtimeMedium, 60, 900)
+		norm["runtime_medium_min"] = RuntimeMediumAxis(runtimeMedium)
 	}
 	raw["durability"] = durability
 	norm["durability"] = durability
 
 	if price > 0 {
 		raw["price_usd"] = price
-		norm["price"] = normalizeLowerLinear(price, 20, 300)
+		norm["price"] = PriceAxis(price)
 	}
 
 	throwScore := weightedMean("throw", []namedPair{
@@ -32,3 +32,54 @@ func normalizeLowerLinear(v, best, worst float64) float64 {
 	}
 	return (1 - clamp01((v-best)/(worst-best))) * 100
 }
+
+// The axis helpers below expose the engine's normalization so the API can
+// place lights on the same 0–100 scales the scores are built from. A zero or
+// negative input means "unknown" and yields 0.
+
+// LumensAxis is higher for brighter lights, on a log scale.
+func LumensAxis(v float64) float64 { return normalizeHigherLog(v, 100, 5000) }
+
+// CandelaAxis is higher for lights with more peak beam intensity.
+func CandelaAxis(v float64) float64 { return normalizeHigherLog(v, 1000, 120000) }
+
+// BeamDistanceAxis is higher for lights that reach further, in metres.
+func BeamDistanceAxis(v float64) float64 { return normalizeHigherLog(v, 60, 700) }
+
+// RuntimeHighAxis is higher for longer runtimes on high, in minutes.
+func RuntimeHighAxis(v float64) float64 { return normalizeHigherLog(v, 20, 300) }
+
+// RuntimeMediumAxis is higher for longer runtimes on medium, in minutes.
+func RuntimeMediumAxis(v float64) float64 { return normalizeHigherLog(v, 60, 900) }
+
+// PriceAxis is higher for cheaper lights.
+func PriceAxis(v float64) float64 {
+	if v <= 0 {
+		return 0
+	}
+	return normalizeLowerLinear(v, 20, 300)
+}
+
+// SizeAxis is higher for larger lights. It averages weight and length when
+// both are known.
+func SizeAxis(weightG, lengthMM float64) float64 {
+	var sum, n float64
+	if weightG > 0 {
+		sum += normalizeHigherLog(weightG, 20, 800)
+		n++
+	}
+	if lengthMM > 0 {
+		sum += normalizeHigherLog(lengthMM, 50, 350)
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / n
+}
+
+// DurabilityAxis is higher for better water and impact resistance. It weighs
+// the IP rating over the impact rating in metres.
+func DurabilityAxis(waterproof string, impactM float64) float64 {
+	return durabilityScore(waterproof, impactM)
+}
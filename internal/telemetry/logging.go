@@ -0,0 +1,59 @@
+// Package telemetry sets up structured logging and the Prometheus metrics
+// shared by the API, the worker and the one-shot jobs.
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// SetupLogging installs a slog default logger tagged with service. LOG_FORMAT
+// is json (default) or text; LOG_LEVEL is debug, info (default), warn or
+// error. The standard log package writes through the same handler.
+func SetupLogging(service string) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(os.Getenv("LOG_LEVEL")))); err != nil {
+		level = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var h slog.Handler
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "text") {
+		h = slog.NewTextHandler(os.Stderr, opts)
+	} else {
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	logger := slog.New(h).With("service", service)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// Fatal logs msg at error level and exits, for startup failures in mains.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying id, which Logger attaches to records.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request id carried by ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Logger returns the default logger, tagged with ctx's request id when it
+// has one.
+func Logger(ctx context.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With("request_id", id)
+	}
+	return slog.Default()
+}
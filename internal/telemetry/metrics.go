@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "flashlight"
+
+var (
+	// HTTPRequestDuration is labelled by mux route pattern, never by raw
+	// path, so ids and slugs cannot blow up the series count.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "API request latency by route, method and status code.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"route", "method", "status"})
+
+	// HTTPLegacyRequests counts requests to unversioned API paths, which
+	// are aliases of /v1 kept until their sunset date.
+	HTTPLegacyRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_legacy_requests_total",
+		Help:      "API requests to deprecated unversioned paths by route.",
+	}, []string{"route"})
+
+	WorkerCycleDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "worker_cycle_duration_seconds",
+		Help:      "Duration of worker cycles and their stages.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"stage", "result"})
+
+	AmazonSyncRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "amazon_sync_runs_total",
+		Help:      "Amazon PA-API sync runs by result.",
+	}, []string{"result"})
+
+	AmazonSyncDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "amazon_sync_duration_seconds",
+		Help:      "Duration of Amazon PA-API sync runs.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	})
+
+	ScoringRunDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scoring_run_duration_seconds",
+		Help:      "Duration of scoring batch runs by result.",
+		Buckets:   prometheus.ExponentialBuckets(0.25, 2, 10),
+	}, []string{"result"})
+)
+
+// Result is the result label for err.
+func Result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// Since returns the seconds elapsed since start, for Observe calls.
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// RegisterDBStats exports db's connection pool statistics.
+func RegisterDBStats(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ServeMetrics serves /metrics on addr until ctx is done. It listens on its
+// own address so the endpoint stays off the public proxy. An empty addr
+// disables it.
+func ServeMetrics(ctx context.Context, addr string) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		slog.Info("metrics listening", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server failed", "err", err)
+		}
+	}()
+}
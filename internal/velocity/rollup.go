@@ -0,0 +1,226 @@
+package velocity
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+
+	"flashlight-ratings-go/internal/readmodel"
+)
+
+// maxScore is the largest value flashlight_review_velocity_daily.velocity_score
+// (NUMERIC(6,3)) can hold.
+const maxScore = 999.999
+
+type RollupConfig struct {
+	// Days is how many trailing days, ending today, are recomputed on each
+	// run. Recomputing a few days back picks up snapshots that arrived late.
+	Days int
+}
+
+type Rollup struct {
+	db  *sql.DB
+	cfg RollupConfig
+}
+
+func NewRollup(db *sql.DB, cfg RollupConfig) *Rollup {
+	if cfg.Days <= 0 {
+		cfg.Days = 3
+	}
+	return &Rollup{db: db, cfg: cfg}
+}
+
+// Counts holds a light's total rating count at the end of a day and at the
+// end of the days used for deltas. Nil means no snapshot existed yet.
+type Counts struct {
+	Current int64
+	Prev1d  *int64
+	Prev7d  *int64
+	Prev30d *int64
+}
+
+type Row struct {
+	FlashlightID int64
+	MetricDate   time.Time
+	RatingCount  int64
+	Delta1d      *int64
+	Delta7d      *int64
+	Delta30d     *int64
+	Score        float64
+}
+
+// Run rolls amazon_product_snapshots.rating_count up into
+// flashlight_review_velocity_daily for the configured window ending at asOf
+// (UTC) and returns the number of rows written. A light's count for a day is
+// the sum over its ASINs of the latest snapshot captured before the day ends.
+func (r *Rollup) Run(ctx context.Context, asOf time.Time) (int, error) {
+	end := asOf.UTC().Truncate(24 * time.Hour)
+
+	rows, err := r.db.QueryContext(ctx, `
+WITH days AS (
+	SELECT d::DATE AS metric_date
+	FROM generate_series($1::DATE - ($2::INT - 1), $1::DATE, INTERVAL '1 day') d
+),
+points AS (
+	SELECT days.metric_date, offs.offset_days
+	FROM days
+	CROSS JOIN (VALUES (0), (1), (7), (30)) AS offs(offset_days)
+),
+counts AS (
+	SELECT p.metric_date, p.offset_days, s.flashlight_id, SUM(s.rating_count)::BIGINT AS rating_count
+	FROM points p
+	CROSS JOIN LATERAL (
+		SELECT DISTINCT ON (aps.flashlight_id, aps.asin)
+			aps.flashlight_id,
+			aps.rating_count
+		FROM amazon_product_snapshots aps
+		WHERE aps.rating_count IS NOT NULL
+		  AND aps.captured_at < ((p.metric_date - p.offset_days + 1)::TIMESTAMP AT TIME ZONE 'UTC')
+		ORDER BY aps.flashlight_id, aps.asin, aps.captured_at DESC, aps.id DESC
+	) s
+	GROUP BY p.metric_date, p.offset_days, s.flashlight_id
+)
+SELECT
+	c0.flashlight_id,
+	c0.metric_date,
+	c0.rating_count,
+	c1.rating_count,
+	c7.rating_count,
+	c30.rating_count
+FROM counts c0
+LEFT JOIN counts c1 ON c1.flashlight_id = c0.flashlight_id AND c1.metric_date = c0.metric_date AND c1.offset_days = 1
+LEFT JOIN counts c7 ON c7.flashlight_id = c0.flashlight_id AND c7.metric_date = c0.metric_date AND c7.offset_days = 7
+LEFT JOIN counts c30 ON c30.flashlight_id = c0.flashlight_id AND c30.metric_date = c0.metric_date AND c30.offset_days = 30
+WHERE c0.offset_days = 0
+ORDER BY c0.metric_date, c0.flashlight_id
+`, end, r.cfg.Days)
+	if err != nil {
+		return 0, err
+	}
+
+	out := make([]Row, 0)
+	for rows.Next() {
+		var (
+			id                   int64
+			day                  time.Time
+			c                    Counts
+			prev1, prev7, prev30 sql.NullInt64
+		)
+		if err := rows.Scan(&id, &day, &c.Current, &prev1, &prev7, &prev30); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		c.Prev1d = nullInt(prev1)
+		c.Prev7d = nullInt(prev7)
+		c.Prev30d = nullInt(prev30)
+		out = append(out, BuildRow(id, day, c))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO flashlight_review_velocity_daily (
+	flashlight_id, metric_date, rating_count, rating_count_delta_1d, rating_count_delta_7d, rating_count_delta_30d, velocity_score
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (flashlight_id, metric_date) DO UPDATE SET
+	rating_count = EXCLUDED.rating_count,
+	rating_count_delta_1d = EXCLUDED.rating_count_delta_1d,
+	rating_count_delta_7d = EXCLUDED.rating_count_delta_7d,
+	rating_count_delta_30d = EXCLUDED.rating_count_delta_30d,
+	velocity_score = EXCLUDED.velocity_score
+`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, row := range out {
+		if _, err := stmt.ExecContext(ctx,
+			row.FlashlightID,
+			row.MetricDate,
+			row.RatingCount,
+			row.Delta1d,
+			row.Delta7d,
+			row.Delta30d,
+			row.Score,
+		); err != nil {
+			return 0, err
+		}
+	}
+	if err := readmodel.PublishVersion(ctx, tx); err != nil {
+		return 0, err
+	}
+	return len(out), tx.Commit()
+}
+
+// BuildRow derives the deltas and velocity score for one light and day.
+func BuildRow(flashlightID int64, day time.Time, c Counts) Row {
+	row := Row{
+		FlashlightID: flashlightID,
+		MetricDate:   day,
+		RatingCount:  c.Current,
+		Delta1d:      delta(c.Current, c.Prev1d),
+		Delta7d:      delta(c.Current, c.Prev7d),
+		Delta30d:     delta(c.Current, c.Prev30d),
+	}
+	row.Score = Score(c)
+	return row
+}
+
+// Score rates how fast a light is gathering ratings. The recent daily rate
+// (7-day, blended with the 30-day rate) is log-scaled so best sellers do not
+// dominate linearly, then boosted up to 2x by how large the gain is relative
+// to the count a month earlier, which favours lights that are newly taking
+// off. Lights without history, or losing ratings, score 0.
+func Score(c Counts) float64 {
+	if c.Prev7d == nil && c.Prev1d == nil {
+		return 0
+	}
+
+	recent := 0.0
+	switch {
+	case c.Prev7d != nil:
+		recent = float64(c.Current-*c.Prev7d) / 7
+	default:
+		recent = float64(c.Current - *c.Prev1d)
+	}
+	long := recent
+	base := float64(c.Current)
+	if c.Prev30d != nil {
+		long = float64(c.Current-*c.Prev30d) / 30
+		base = float64(*c.Prev30d)
+	}
+
+	rate := 0.7*recent + 0.3*long
+	if rate <= 0 {
+		return 0
+	}
+	growth := math.Min(rate*30/math.Max(base, 25), 1)
+	score := 100 * math.Log10(1+rate) * (1 + growth)
+	return math.Min(math.Round(score*1000)/1000, maxScore)
+}
+
+func delta(current int64, prev *int64) *int64 {
+	if prev == nil {
+		return nil
+	}
+	d := current - *prev
+	return &d
+}
+
+func nullInt(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
@@ -0,0 +1,51 @@
+package velocity
+
+import (
+	"testing"
+	"time"
+)
+
+func ptr(v int64) *int64 { return &v }
+
+func TestScoreWithoutHistoryIsZero(t *testing.T) {
+	if got := Score(Counts{Current: 500}); got != 0 {
+		t.Fatalf("Score = %v, want 0", got)
+	}
+	if got := Score(Counts{Current: 500, Prev1d: ptr(510), Prev7d: ptr(520)}); got != 0 {
+		t.Fatalf("Score for shrinking count = %v, want 0", got)
+	}
+}
+
+func TestScoreFavoursRisingLights(t *testing.T) {
+	// Same absolute gain; the light with the smaller base is newly taking off.
+	rising := Score(Counts{Current: 300, Prev1d: ptr(290), Prev7d: ptr(230), Prev30d: ptr(100)})
+	steady := Score(Counts{Current: 20200, Prev1d: ptr(20190), Prev7d: ptr(20130), Prev30d: ptr(20000)})
+	if rising <= steady {
+		t.Fatalf("rising=%v steady=%v, want rising > steady", rising, steady)
+	}
+
+	busier := Score(Counts{Current: 21000, Prev1d: ptr(20900), Prev7d: ptr(20300), Prev30d: ptr(18000)})
+	if busier <= steady {
+		t.Fatalf("busier=%v steady=%v, want busier > steady", busier, steady)
+	}
+	if busier > maxScore {
+		t.Fatalf("score %v exceeds column range", busier)
+	}
+}
+
+func TestBuildRowDeltas(t *testing.T) {
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	row := BuildRow(4, day, Counts{Current: 120, Prev1d: ptr(118), Prev7d: ptr(100)})
+	if row.Delta1d == nil || *row.Delta1d != 2 {
+		t.Fatalf("Delta1d = %v", row.Delta1d)
+	}
+	if row.Delta7d == nil || *row.Delta7d != 20 {
+		t.Fatalf("Delta7d = %v", row.Delta7d)
+	}
+	if row.Delta30d != nil {
+		t.Fatalf("Delta30d = %v, want nil", *row.Delta30d)
+	}
+	if row.Score <= 0 {
+		t.Fatalf("Score = %v, want > 0", row.Score)
+	}
+}
@@ -0,0 +1,435 @@
+// Package client is a typed Go client for version 1 of the flashlight ratings
+// API.
+//
+// Client talks to a running API and Fake stands in for one in tests. Both
+// implement API, so code that takes an API works with either, and the page
+// iterators AllFlashlights and AllRankings do too.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiVersion is the path prefix of the API version this package speaks.
+const apiVersion = "/v1"
+
+// API is the set of calls the client makes. Accept it rather than *Client
+// so tests can pass a Fake.
+type API interface {
+	// List filters, sorts and pages the catalog.
+	List(ctx context.Context, opts ListOptions) (*PaginatedFlashlightsResponse, error)
+	// Get returns one flashlight with specs, modes and offers.
+	Get(ctx context.Context, id int64) (*FlashlightDetail, error)
+	// Compare returns a side-by-side comparison of up to 20 lights.
+	Compare(ctx context.Context, ids ...int64) (*CompareResponse, error)
+	// Rankings returns lights ranked for a use case by the latest scoring run.
+	Rankings(ctx context.Context, opts RankingsOptions) (*RankingsResponse, error)
+	// Finder returns the best lights under a budget and spec floor.
+	Finder(ctx context.Context, opts FinderOptions) (*FinderResponse, error)
+	// Recommendations scores the catalog for a buyer profile without
+	// storing the result.
+	Recommendations(ctx context.Context, req IntelligenceRunRequest) (*IntelligenceResponse, error)
+	// CreateRun scores the catalog for a buyer profile and stores the result
+	// as a run that GetRun can fetch later.
+	CreateRun(ctx context.Context, req IntelligenceRunRequest) (*IntelligenceRunResponse, error)
+	// GetRun returns a stored run.
+	GetRun(ctx context.Context, id int64) (*IntelligenceRunResponse, error)
+}
+
+var _ API = (*Client)(nil)
+
+type Config struct {
+	// BaseURL is where the API is served, such as https://example.com/api.
+	// The version prefix is added by the client.
+	BaseURL string
+	// APIKey is sent as X-API-Key. Without one, requests share the
+	// per-address rate limit.
+	APIKey string
+	// Region selects the Amazon marketplace for offers and prices, such as
+	// DE. Empty lets the API pick from the request's country headers.
+	Region     string
+	UserAgent  string
+	HTTPClient *http.Client
+	// MaxRetries is how often a failed request is retried: 2 when zero,
+	// none when negative. Network errors, 502, 503 and 504 are retried for
+	// calls without side effects; 429 is retried for all calls.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, growing linearly
+	// with each attempt. A longer Retry-After from the server wins.
+	RetryBackoff time.Duration
+}
+
+// maxRetryAfter is the longest Retry-After the client waits out. Longer
+// waits, such as an exhausted daily quota, are returned as errors.
+const maxRetryAfter = time.Minute
+
+type Client struct {
+	baseURL      string
+	apiKey       string
+	region       string
+	userAgent    string
+	httpClient   *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+func New(cfg Config) (*Client, error) {
+	base := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("client: invalid base url %q", cfg.BaseURL)
+	}
+	if !strings.HasSuffix(base, apiVersion) {
+		base += apiVersion
+	}
+
+	hc := cfg.HTTPClient
+	if hc == nil {
+		hc = &http.Client{Timeout: 15 * time.Second}
+	}
+	switch {
+	case cfg.MaxRetries == 0:
+		cfg.MaxRetries = 2
+	case cfg.MaxRetries < 0:
+		cfg.MaxRetries = 0
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 500 * time.Millisecond
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "flashlight-ratings-go/client"
+	}
+
+	return &Client{
+		baseURL:      base,
+		apiKey:       cfg.APIKey,
+		region:       strings.ToUpper(strings.TrimSpace(cfg.Region)),
+		userAgent:    cfg.UserAgent,
+		httpClient:   hc,
+		maxRetries:   cfg.MaxRetries,
+		retryBackoff: cfg.RetryBackoff,
+	}, nil
+}
+
+// WithRegion returns a copy of c that asks for offers in region.
+func (c *Client) WithRegion(region string) *Client {
+	c2 := *c
+	c2.region = strings.ToUpper(strings.TrimSpace(region))
+	return &c2
+}
+
+// ListOptions filters and pages List. Zero values leave a filter unset and
+// take the server's defaults for sorting and paging.
+type ListOptions struct {
+	Brands        []string
+	BatteryTypes  []string
+	IPRatings     []string
+	MinIPRating   string
+	LEDModel      string
+	SwitchTypes   []string
+	BeamPatterns  []string
+	RechargeTypes []string
+	// Min and Max bound numeric specs by range name, such as price, lumens
+	// or runtime_high; see /v1/parameters for the full list.
+	Min map[string]float64
+	Max map[string]float64
+	// Flags filter on boolean specs, such as usb_c_rechargeable.
+	Flags    map[string]bool
+	SortBy   string
+	Order    string
+	Page     int
+	PageSize int
+	// Cursor is a NextCursor or PrevCursor from an earlier response. It
+	// replaces Page and must keep the SortBy and Order it was made with.
+	Cursor string
+	Facets bool
+}
+
+func (o ListOptions) values() url.Values {
+	v := url.Values{}
+	setList(v, "brand", o.Brands)
+	setList(v, "battery_type", o.BatteryTypes)
+	setList(v, "ip_rating", o.IPRatings)
+	setList(v, "switch_type", o.SwitchTypes)
+	setList(v, "beam_pattern", o.BeamPatterns)
+	setList(v, "recharge_type", o.RechargeTypes)
+	setString(v, "min_ip_rating", o.MinIPRating)
+	setString(v, "led_model", o.LEDModel)
+	for name, n := range o.Min {
+		v.Set("min_"+name, strconv.FormatFloat(n, 'f', -1, 64))
+	}
+	for name, n := range o.Max {
+		v.Set("max_"+name, strconv.FormatFloat(n, 'f', -1, 64))
+	}
+	for name, b := range o.Flags {
+		v.Set(name, strconv.FormatBool(b))
+	}
+	setString(v, "sort_by", o.SortBy)
+	setString(v, "order", o.Order)
+	setInt(v, "page", o.Page)
+	setInt(v, "page_size", o.PageSize)
+	setString(v, "cursor", o.Cursor)
+	if o.Facets {
+		v.Set("facets", "true")
+	}
+	return v
+}
+
+type RankingsOptions struct {
+	// UseCase is a scoring profile such as edc or tactical; overall when
+	// empty.
+	UseCase  string
+	Page     int
+	PageSize int
+	// Cursor is a NextCursor or PrevCursor from an earlier response for the
+	// same UseCase. It replaces Page.
+	Cursor string
+}
+
+func (o RankingsOptions) values() url.Values {
+	v := url.Values{}
+	setString(v, "use_case", o.UseCase)
+	setInt(v, "page", o.Page)
+	setInt(v, "page_size", o.PageSize)
+	setString(v, "cursor", o.Cursor)
+	return v
+}
+
+// FinderOptions are the finder's filters, echoed back in the response, and
+// how many lights to return.
+type FinderOptions struct {
+	FinderFilters
+	Limit int
+}
+
+func (o FinderOptions) values() url.Values {
+	v := url.Values{}
+	if o.Budget != nil {
+		v.Set("budget", strconv.FormatFloat(*o.Budget, 'f', -1, 64))
+	}
+	if o.USBC != nil {
+		v.Set("usb_c", strconv.FormatBool(*o.USBC))
+	}
+	if o.MinThrow != nil {
+		v.Set("min_throw", strconv.FormatInt(*o.MinThrow, 10))
+	}
+	setInt(v, "limit", o.Limit)
+	return v
+}
+
+func setString(v url.Values, name, s string) {
+	if s != "" {
+		v.Set(name, s)
+	}
+}
+
+func setInt(v url.Values, name string, n int) {
+	if n != 0 {
+		v.Set(name, strconv.Itoa(n))
+	}
+}
+
+func setList(v url.Values, name string, values []string) {
+	if len(values) > 0 {
+		v.Set(name, strings.Join(values, ","))
+	}
+}
+
+func (c *Client) List(ctx context.Context, opts ListOptions) (*PaginatedFlashlightsResponse, error) {
+	return call[PaginatedFlashlightsResponse](ctx, c, http.MethodGet, "/flashlights", c.regional(opts.values()), nil, true)
+}
+
+func (c *Client) Get(ctx context.Context, id int64) (*FlashlightDetail, error) {
+	return call[FlashlightDetail](ctx, c, http.MethodGet, "/flashlights/"+strconv.FormatInt(id, 10), c.regional(nil), nil, true)
+}
+
+func (c *Client) Compare(ctx context.Context, ids ...int64) (*CompareResponse, error) {
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = strconv.FormatInt(id, 10)
+	}
+	return call[CompareResponse](ctx, c, http.MethodGet, "/compare", c.regional(url.Values{"ids": {strings.Join(list, ",")}}), nil, true)
+}
+
+func (c *Client) Rankings(ctx context.Context, opts RankingsOptions) (*RankingsResponse, error) {
+	return call[RankingsResponse](ctx, c, http.MethodGet, "/rankings", c.regional(opts.values()), nil, true)
+}
+
+func (c *Client) Finder(ctx context.Context, opts FinderOptions) (*FinderResponse, error) {
+	return call[FinderResponse](ctx, c, http.MethodGet, "/finder", c.regional(opts.values()), nil, true)
+}
+
+func (c *Client) Recommendations(ctx context.Context, req IntelligenceRunRequest) (*IntelligenceResponse, error) {
+	// Nothing is stored, so failures are as safe to retry as a GET.
+	return call[IntelligenceResponse](ctx, c, http.MethodPost, "/intelligence/recommendations", c.regional(nil), req, true)
+}
+
+func (c *Client) CreateRun(ctx context.Context, req IntelligenceRunRequest) (*IntelligenceRunResponse, error) {
+	return call[IntelligenceRunResponse](ctx, c, http.MethodPost, "/intelligence/runs", c.regional(nil), req, false)
+}
+
+func (c *Client) GetRun(ctx context.Context, id int64) (*IntelligenceRunResponse, error) {
+	return call[IntelligenceRunResponse](ctx, c, http.MethodGet, "/intelligence/runs/"+strconv.FormatInt(id, 10), nil, nil, true)
+}
+
+// regional adds the client's region to the query of a call that accepts one.
+func (c *Client) regional(v url.Values) url.Values {
+	if v == nil {
+		v = url.Values{}
+	}
+	if c.region != "" {
+		v.Set("region", c.region)
+	}
+	return v
+}
+
+// call sends a request and decodes a 2xx body as a T.
+func call[T any](ctx context.Context, c *Client, method, path string, query url.Values, body any, idempotent bool) (*T, error) {
+	var out T
+	if err := c.do(ctx, method, path, query, body, idempotent, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// do sends a request and decodes a 2xx body into out. idempotent says
+// whether a request that may have reached the handler can be sent again.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any, idempotent bool, out any) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("client: encode %s body: %w", path, err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		wait, err := c.send(ctx, method, target, payload, idempotent, out)
+		if wait < 0 || attempt == c.maxRetries {
+			return err
+		}
+		wait = max(wait, c.retryBackoff*time.Duration(attempt+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one attempt. It returns how long the server asked to wait
+// when the attempt may be retried, or -1 when it must not be.
+func (c *Client) send(ctx context.Context, method, target string, payload []byte, idempotent bool, out any) (time.Duration, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return -1, fmt.Errorf("client: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		err = fmt.Errorf("client: %s %s: %w", method, req.URL.Path, err)
+		if !idempotent {
+			return -1, err
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return -1, fmt.Errorf("client: decode %s %s: %w", method, req.URL.Path, err)
+		}
+		return -1, nil
+	}
+
+	apiErr := readError(resp)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		// The rate limiter answers before any handler runs, so even calls
+		// with side effects can be retried.
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if !idempotent {
+			return -1, apiErr
+		}
+	default:
+		return -1, apiErr
+	}
+	if apiErr.RetryAfter > maxRetryAfter {
+		return -1, apiErr
+	}
+	return apiErr.RetryAfter, apiErr
+}
+
+// Error is a response with a non-2xx status. Problem is set for 400s, which
+// list each invalid field.
+type Error struct {
+	StatusCode int
+	Message    string
+	Problem    *Problem
+	// RetryAfter is the server's Retry-After, zero when not sent.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("client: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the API.
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+func readError(resp *http.Response) *Error {
+	e := &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") {
+		var p Problem
+		if json.Unmarshal(raw, &p) == nil {
+			e.Problem = &p
+			e.Message = p.Detail
+		}
+		return e
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(raw, &body) == nil && body.Error != "" {
+		e.Message = body.Error
+	}
+	return e
+}
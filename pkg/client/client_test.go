@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c, err := New(Config{BaseURL: srv.URL + "/", APIKey: "k", Region: "de", RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClientSendsVersionedRequests(t *testing.T) {
+	var got []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Method+" "+r.URL.String()+" key="+r.Header.Get("X-API-Key"))
+		w.Write([]byte(`{}`))
+	})
+	ctx := context.Background()
+	budget := 60.0
+	calls := []func() error{
+		func() error {
+			_, err := c.List(ctx, ListOptions{Brands: []string{"acme", "zed"}, Min: map[string]float64{"lumens": 1000}})
+			return err
+		},
+		func() error { _, err := c.Get(ctx, 7); return err },
+		func() error { _, err := c.Compare(ctx, 1, 2); return err },
+		func() error { _, err := c.Rankings(ctx, RankingsOptions{UseCase: "edc", Page: 2}); return err },
+		func() error {
+			_, err := c.Finder(ctx, FinderOptions{FinderFilters: FinderFilters{Budget: &budget}})
+			return err
+		},
+		func() error { _, err := c.GetRun(ctx, 3); return err },
+	}
+	for _, call := range calls {
+		if err := call(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"GET /v1/flashlights?brand=acme%2Czed&min_lumens=1000&region=DE key=k",
+		"GET /v1/flashlights/7?region=DE key=k",
+		"GET /v1/compare?ids=1%2C2&region=DE key=k",
+		"GET /v1/rankings?page=2&region=DE&use_case=edc key=k",
+		"GET /v1/finder?budget=60&region=DE key=k",
+		"GET /v1/intelligence/runs/3 key=k",
+	}
+	for i := range want {
+		if i >= len(got) || got[i] != want[i] {
+			t.Errorf("request %d = %q, want %q", i, got, want[i])
+			break
+		}
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	var n atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id": 7, "name": "One"}`))
+	})
+	d, err := c.Get(context.Background(), 7)
+	if err != nil || d.Name != "One" || n.Load() != 3 {
+		t.Fatalf("Get = %+v, %v after %d attempts", d, err, n.Load())
+	}
+
+	// A run may have been stored before a 503, so it is not sent again.
+	n.Store(0)
+	_, err = c.CreateRun(context.Background(), IntelligenceRunRequest{IntendedUse: "edc"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || n.Load() != 1 {
+		t.Fatalf("CreateRun: %v after %d attempts", err, n.Load())
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/flashlights/404":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "flashlight not found"}`))
+		default:
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status": 400, "detail": "invalid parameter page", "errors": [{"field": "page", "message": "must be an integer"}]}`))
+		}
+	})
+	_, err := c.Get(context.Background(), 404)
+	if !IsNotFound(err) || err.Error() != "client: 404 Not Found: flashlight not found" {
+		t.Errorf("Get: %v", err)
+	}
+	_, err = c.Rankings(context.Background(), RankingsOptions{})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Problem == nil || len(apiErr.Problem.Errors) != 1 || apiErr.Problem.Errors[0].Field != "page" {
+		t.Errorf("Rankings: %#v", err)
+	}
+}
+
+func TestClientStopsWhenContextIsCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	c, err := New(Config{BaseURL: srv.URL, RetryBackoff: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Get(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get: %v, want deadline exceeded", err)
+	}
+}
+
+func TestNewRejectsBadBaseURL(t *testing.T) {
+	for _, base := range []string{"", "localhost:8080", "ftp://example.com"} {
+		if _, err := New(Config{BaseURL: base}); err == nil {
+			t.Errorf("New(%q): no error", base)
+		}
+	}
+}
+
+func TestAllFlashlights(t *testing.T) {
+	var cursors []string
+	fake := &Fake{ListFunc: func(_ context.Context, opts ListOptions) (*PaginatedFlashlightsResponse, error) {
+		cursors = append(cursors, opts.Cursor)
+		n := len(cursors)
+		resp := &PaginatedFlashlightsResponse{
+			Items: []FlashlightItem{{ID: int64(n*10 + 1)}, {ID: int64(n*10 + 2)}},
+		}
+		if n < 3 {
+			resp.NextCursor = fmt.Sprintf("c%d", n)
+		}
+		return resp, nil
+	}}
+
+	var ids []int64
+	for item, err := range AllFlashlights(context.Background(), fake, ListOptions{Page: 1}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, item.ID)
+	}
+	if len(ids) != 6 || ids[0] != 11 || ids[5] != 32 || !slices.Equal(cursors, []string{"", "c1", "c2"}) {
+		t.Fatalf("ids %v from cursors %q", ids, cursors)
+	}
+
+	// Breaking out of the loop fetches no further pages.
+	cursors = nil
+	for range AllFlashlights(context.Background(), fake, ListOptions{}) {
+		break
+	}
+	if len(cursors) != 1 {
+		t.Fatalf("cursors %q after break", cursors)
+	}
+}
+
+func TestFakeReportsUnsetMethods(t *testing.T) {
+	var api API = &Fake{}
+	for _, err := range AllRankings(context.Background(), api, RankingsOptions{}) {
+		if err == nil || err.Error() != "client: Fake.RankingsFunc not set" {
+			t.Fatalf("err = %v", err)
+		}
+	}
+}
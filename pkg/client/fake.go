@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// Fake is an API for tests. Each method calls the function field of the same
+// name, so a test sets only the calls it expects; calling a method whose
+// field is nil fails with an error naming it.
+type Fake struct {
+	ListFunc            func(ctx context.Context, opts ListOptions) (*PaginatedFlashlightsResponse, error)
+	GetFunc             func(ctx context.Context, id int64) (*FlashlightDetail, error)
+	CompareFunc         func(ctx context.Context, ids ...int64) (*CompareResponse, error)
+	RankingsFunc        func(ctx context.Context, opts RankingsOptions) (*RankingsResponse, error)
+	FinderFunc          func(ctx context.Context, opts FinderOptions) (*FinderResponse, error)
+	RecommendationsFunc func(ctx context.Context, req IntelligenceRunRequest) (*IntelligenceResponse, error)
+	CreateRunFunc       func(ctx context.Context, req IntelligenceRunRequest) (*IntelligenceRunResponse, error)
+	GetRunFunc          func(ctx context.Context, id int64) (*IntelligenceRunResponse, error)
+}
+
+var _ API = (*Fake)(nil)
+
+func notFaked(method string) error {
+	return fmt.Errorf("client: Fake.%sFunc not set", method)
+}
+
+func (f *Fake) List(ctx context.Context, opts ListOptions) (*PaginatedFlashlightsResponse, error) {
+	if f.ListFunc == nil {
+		return nil, notFaked("List")
+	}
+	return f.ListFunc(ctx, opts)
+}
+
+func (f *Fake) Get(ctx context.Context, id int64) (*FlashlightDetail, error) {
+	if f.GetFunc == nil {
+		return nil, notFaked("Get")
+	}
+	return f.GetFunc(ctx, id)
+}
+
+func (f *Fake) Compare(ctx context.Context, ids ...int64) (*CompareResponse, error) {
+	if f.CompareFunc == nil {
+		return nil, notFaked("Compare")
+	}
+	return f.CompareFunc(ctx, ids...)
+}
+
+func (f *Fake) Rankings(ctx context.Context, opts RankingsOptions) (*RankingsResponse, error) {
+	if f.RankingsFunc == nil {
+		return nil, notFaked("Rankings")
+	}
+	return f.RankingsFunc(ctx, opts)
+}
+
+func (f *Fake) Finder(ctx context.Context, opts FinderOptions) (*FinderResponse, error) {
+	if f.FinderFunc == nil {
+		return nil, notFaked("Finder")
+	}
+	return f.FinderFunc(ctx, opts)
+}
+
+func (f *Fake) Recommendations(ctx context.Context, req IntelligenceRunRequest) (*IntelligenceResponse, error) {
+	if f.RecommendationsFunc == nil {
+		return nil, notFaked("Recommendations")
+	}
+	return f.RecommendationsFunc(ctx, req)
+}
+
+func (f *Fake) CreateRun(ctx context.Context, req IntelligenceRunRequest) (*IntelligenceRunResponse, error) {
+	if f.CreateRunFunc == nil {
+		return nil, notFaked("CreateRun")
+	}
+	return f.CreateRunFunc(ctx, req)
+}
+
+func (f *Fake) GetRun(ctx context.Context, id int64) (*IntelligenceRunResponse, error) {
+	if f.GetRunFunc == nil {
+		return nil, notFaked("GetRun")
+	}
+	return f.GetRunFunc(ctx, id)
+}
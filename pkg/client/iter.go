@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"iter"
+)
+
+// AllFlashlights yields every flashlight List returns for opts, starting at
+// opts.Page or opts.Cursor and following NextCursor as the loop reaches the
+// end of each page. Cursors keep the scoring run of the first page, so a run
+// published mid-iteration neither repeats nor skips lights. An error is
+// yielded once and ends the iteration; stopping the loop early fetches
+// nothing more.
+func AllFlashlights(ctx context.Context, api API, opts ListOptions) iter.Seq2[FlashlightItem, error] {
+	return func(yield func(FlashlightItem, error) bool) {
+		for {
+			resp, err := api.List(ctx, opts)
+			if err != nil {
+				yield(FlashlightItem{}, err)
+				return
+			}
+			for _, item := range resp.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if resp.NextCursor == "" {
+				return
+			}
+			opts.Page, opts.Cursor = 0, resp.NextCursor
+		}
+	}
+}
+
+// AllRankings yields every ranked light for opts.UseCase the way
+// AllFlashlights does for List.
+func AllRankings(ctx context.Context, api API, opts RankingsOptions) iter.Seq2[RankedResponse, error] {
+	return func(yield func(RankedResponse, error) bool) {
+		for {
+			resp, err := api.Rankings(ctx, opts)
+			if err != nil {
+				yield(RankedResponse{}, err)
+				return
+			}
+			for _, item := range resp.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if resp.NextCursor == "" {
+				return
+			}
+			opts.Page, opts.Cursor = 0, resp.NextCursor
+		}
+	}
+}
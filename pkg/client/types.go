@@ -0,0 +1,297 @@
+package client
+
+// The types below are the request and response bodies of the API. The server
+// in internal/api encodes these same types, so they cannot drift from what it
+// sends.
+
+// RegionalOffer is the region-specific part of a listing: the marketplace
+// the Amazon link points at and the price in that marketplace's currency.
+// RegionFallback is set when the requested region has no listing and the US
+// one was used.
+type RegionalOffer struct {
+	AmazonRegion   *string  `json:"amazon_region,omitempty"`
+	RegionFallback bool     `json:"region_fallback,omitempty"`
+	Price          *float64 `json:"price,omitempty"`
+	Currency       *string  `json:"currency,omitempty"`
+}
+
+type FlashlightItem struct {
+	ID          int64   `json:"id"`
+	Brand       string  `json:"brand"`
+	Name        string  `json:"name"`
+	Slug        string  `json:"slug"`
+	ModelCode   *string `json:"model_code,omitempty"`
+	Description *string `json:"description,omitempty"`
+	ImageURL    *string `json:"image_url,omitempty"`
+	AmazonURL   *string `json:"amazon_url,omitempty"`
+	RegionalOffer
+	MaxLumens      *int64   `json:"max_lumens,omitempty"`
+	MaxCandela     *int64   `json:"max_candela,omitempty"`
+	BeamDistanceM  *int64   `json:"beam_distance_m,omitempty"`
+	RuntimeHighMin *int64   `json:"runtime_high_min,omitempty"`
+	Waterproof     *string  `json:"waterproof_rating,omitempty"`
+	PriceUSD       *float64 `json:"price_usd,omitempty"`
+	TacticalScore  *float64 `json:"tactical_score,omitempty"`
+	EDCScore       *float64 `json:"edc_score,omitempty"`
+	ValueScore     *float64 `json:"value_score,omitempty"`
+	ThrowScore     *float64 `json:"throw_score,omitempty"`
+	FloodScore     *float64 `json:"flood_score,omitempty"`
+}
+
+// PaginatedFlashlightsResponse is one page of the catalog. Page, Total and
+// TotalPage are set for pages requested by number and left out for pages
+// requested by cursor, which skip the count. NextCursor and PrevCursor are
+// set when there are lights after or before the page.
+type PaginatedFlashlightsResponse struct {
+	Page       int                     `json:"page,omitempty"`
+	PageSize   int                     `json:"page_size"`
+	Total      *int                    `json:"total,omitempty"`
+	TotalPage  *int                    `json:"total_pages,omitempty"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+	PrevCursor string                  `json:"prev_cursor,omitempty"`
+	Items      []FlashlightItem        `json:"items"`
+	Facets     map[string][]FacetValue `json:"facets,omitempty"`
+}
+
+// FacetValue is one bucket of a facet: a filter value, or for ranges the
+// Min and Max of a bucket, with the number of matching lights.
+type FacetValue struct {
+	Value string   `json:"value"`
+	Label string   `json:"label,omitempty"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+type FlashlightDetail struct {
+	FlashlightItem
+	ReleaseYear         *int64           `json:"release_year,omitempty"`
+	MSRPUSD             *float64         `json:"msrp_usd,omitempty"`
+	ASIN                *string          `json:"asin,omitempty"`
+	WeightG             *float64         `json:"weight_g,omitempty"`
+	LengthMM            *float64         `json:"length_mm,omitempty"`
+	HeadDiameterMM      *float64         `json:"head_diameter_mm,omitempty"`
+	BodyDiameterMM      *float64         `json:"body_diameter_mm,omitempty"`
+	ImpactResistance    *float64         `json:"impact_resistance_m,omitempty"`
+	SustainedLumens     *int64           `json:"sustained_lumens,omitempty"`
+	RuntimeLowMin       *int64           `json:"runtime_low_min,omitempty"`
+	RuntimeMediumMin    *int64           `json:"runtime_medium_min,omitempty"`
+	RuntimeTurboMin     *int64           `json:"runtime_turbo_min,omitempty"`
+	Runtime500Min       *int64           `json:"runtime_500_min,omitempty"`
+	TurboStepdownSec    *int64           `json:"turbo_stepdown_sec,omitempty"`
+	BeamPattern         *string          `json:"beam_pattern,omitempty"`
+	RechargeType        *string          `json:"recharge_type,omitempty"`
+	BatteryReplaceable  *bool            `json:"battery_replaceable,omitempty"`
+	HasTailSwitch       *bool            `json:"has_tail_switch,omitempty"`
+	HasSideSwitch       *bool            `json:"has_side_switch,omitempty"`
+	BodyMaterial        *string          `json:"body_material,omitempty"`
+	USBCRechargeable    *bool            `json:"usb_c_rechargeable,omitempty"`
+	BatteryIncluded     *bool            `json:"battery_included,omitempty"`
+	BatteryRech         *bool            `json:"battery_rechargeable,omitempty"`
+	HasStrobe           *bool            `json:"has_strobe,omitempty"`
+	HasMemoryMode       *bool            `json:"has_memory_mode,omitempty"`
+	HasLockout          *bool            `json:"has_lockout,omitempty"`
+	HasMoonlightMode    *bool            `json:"has_moonlight_mode,omitempty"`
+	HasMagTailcap       *bool            `json:"has_magnetic_tailcap,omitempty"`
+	HasPocketClip       *bool            `json:"has_pocket_clip,omitempty"`
+	SwitchType          *string          `json:"switch_type,omitempty"`
+	LEDModel            *string          `json:"led_model,omitempty"`
+	CRI                 *int64           `json:"cri,omitempty"`
+	CCTMinK             *int64           `json:"cct_min_k,omitempty"`
+	CCTMaxK             *int64           `json:"cct_max_k,omitempty"`
+	AmazonRatingCount   *int64           `json:"amazon_rating_count,omitempty"`
+	AmazonAverageRating *float64         `json:"amazon_average_rating,omitempty"`
+	AmazonLastSyncedAt  *string          `json:"amazon_last_synced_at,omitempty"`
+	PriceLastUpdatedAt  *string          `json:"price_last_updated_at,omitempty"`
+	Modes               []FlashlightMode `json:"modes"`
+	ImageURLs           []string         `json:"image_urls"`
+	BatteryTypes        []string         `json:"battery_types"`
+	UseCaseTags         []string         `json:"use_case_tags"`
+}
+
+type FlashlightMode struct {
+	Name          string `json:"name"`
+	OutputLumens  *int64 `json:"output_lumens,omitempty"`
+	RuntimeMin    *int64 `json:"runtime_min,omitempty"`
+	Candela       *int64 `json:"candela,omitempty"`
+	BeamDistanceM *int64 `json:"beam_distance_m,omitempty"`
+}
+
+type CompareResponse struct {
+	Items      []FlashlightDetail `json:"items"`
+	Attributes []CompareAttribute `json:"attributes"`
+	Axes       []CompareAxes      `json:"axes"`
+	Summary    []CompareSummary   `json:"summary"`
+	MissingIDs []int64            `json:"missing_ids,omitempty"`
+}
+
+// CompareAttribute reports one spec across all compared lights. DeltaPct is
+// each light's distance from the best value, in percent of the best value.
+type CompareAttribute struct {
+	Key       string         `json:"key"`
+	Label     string         `json:"label"`
+	Unit      string         `json:"unit,omitempty"`
+	Better    string         `json:"better"`
+	Best      *float64       `json:"best,omitempty"`
+	WinnerIDs []int64        `json:"winner_ids"`
+	Values    []CompareValue `json:"values"`
+}
+
+type CompareValue struct {
+	FlashlightID int64    `json:"flashlight_id"`
+	Value        *float64 `json:"value,omitempty"`
+	DeltaPct     *float64 `json:"delta_pct,omitempty"`
+}
+
+// CompareAxes places each light on 0–100 scales from the scoring engine, for
+// radar charts. Higher is always better on every axis.
+type CompareAxes struct {
+	FlashlightID int64   `json:"flashlight_id"`
+	Output       float64 `json:"output"`
+	Throw        float64 `json:"throw"`
+	Runtime      float64 `json:"runtime"`
+	Portability  float64 `json:"portability"`
+	Value        float64 `json:"value"`
+	Durability   float64 `json:"durability"`
+}
+
+type CompareSummary struct {
+	FlashlightID int64    `json:"flashlight_id"`
+	Wins         []string `json:"wins"`
+	Text         string   `json:"text"`
+}
+
+// RankingsResponse is one page of a ranking, paged like
+// PaginatedFlashlightsResponse. Pages fetched by cursor keep the scores of
+// the run the first page was read from.
+type RankingsResponse struct {
+	UseCase    string           `json:"use_case"`
+	Page       int              `json:"page,omitempty"`
+	PageSize   int              `json:"page_size"`
+	Total      *int             `json:"total,omitempty"`
+	TotalPage  *int             `json:"total_pages,omitempty"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	PrevCursor string           `json:"prev_cursor,omitempty"`
+	Items      []RankedResponse `json:"items"`
+}
+
+type RankedResponse struct {
+	Rank       int              `json:"rank"`
+	Score      float64          `json:"score"`
+	Profile    string           `json:"profile"`
+	Flashlight RankedFlashlight `json:"flashlight"`
+}
+
+type RankedFlashlight struct {
+	ID        int64   `json:"id"`
+	Brand     string  `json:"brand"`
+	Name      string  `json:"name"`
+	Slug      string  `json:"slug"`
+	ImageURL  *string `json:"image_url,omitempty"`
+	AmazonURL *string `json:"amazon_url,omitempty"`
+	RegionalOffer
+}
+
+type FinderResponse struct {
+	Filters FinderFilters   `json:"filters"`
+	Items   []FinderRanking `json:"items"`
+}
+
+type FinderFilters struct {
+	Budget   *float64 `json:"budget,omitempty"`
+	USBC     *bool    `json:"usb_c,omitempty"`
+	MinThrow *int64   `json:"min_throw,omitempty"`
+}
+
+type FinderRanking struct {
+	FlashlightID int64   `json:"flashlight_id"`
+	Brand        string  `json:"brand"`
+	Name         string  `json:"name"`
+	AmazonURL    *string `json:"amazon_url,omitempty"`
+	RegionalOffer
+	PriceUSD      *float64 `json:"price_usd,omitempty"`
+	BeamDistanceM *int64   `json:"beam_distance_m,omitempty"`
+	TacticalScore *float64 `json:"tactical_score,omitempty"`
+	ThrowScore    *float64 `json:"throw_score,omitempty"`
+	ValueScore    *float64 `json:"value_score,omitempty"`
+	FinderScore   float64  `json:"finder_score"`
+}
+
+// IntelligenceRunRequest is the buyer profile sent to the intelligence
+// endpoints. Empty fields take the server's defaults.
+type IntelligenceRunRequest struct {
+	IntendedUse       string  `json:"intended_use"`
+	BudgetUSD         float64 `json:"budget_usd"`
+	BatteryPreference string  `json:"battery_preference"`
+	SizeConstraint    string  `json:"size_constraint"`
+}
+
+type IntelligenceRunResult struct {
+	ModelID   int64   `json:"model_id"`
+	Brand     string  `json:"brand"`
+	Name      string  `json:"name"`
+	Category  string  `json:"category"`
+	ImageURL  *string `json:"image_url,omitempty"`
+	AmazonURL *string `json:"amazon_url,omitempty"`
+	RegionalOffer
+	PriceUSD          *float64 `json:"price_usd,omitempty"`
+	MaxLumens         *int64   `json:"max_lumens,omitempty"`
+	MaxCandela        *int64   `json:"max_candela,omitempty"`
+	BeamDistanceM     *int64   `json:"beam_distance_m,omitempty"`
+	RuntimeHighMin    *int64   `json:"runtime_high_min,omitempty"`
+	RuntimeMediumMin  *int64   `json:"runtime_medium_min,omitempty"`
+	WeightG           *float64 `json:"weight_g,omitempty"`
+	LengthMM          *float64 `json:"length_mm,omitempty"`
+	WaterproofRating  *string  `json:"waterproof_rating,omitempty"`
+	BatteryType       *string  `json:"battery_type,omitempty"`
+	OverallScore      float64  `json:"overall_score"`
+	UseCaseScore      float64  `json:"use_case_score"`
+	BudgetScore       float64  `json:"budget_score"`
+	BatteryMatchScore float64  `json:"battery_match_score"`
+	SizeFitScore      float64  `json:"size_fit_score"`
+	TacticalScore     *float64 `json:"tactical_score,omitempty"`
+	EDCScore          *float64 `json:"edc_score,omitempty"`
+	ValueScore        *float64 `json:"value_score,omitempty"`
+	ThrowScore        *float64 `json:"throw_score,omitempty"`
+	FloodScore        *float64 `json:"flood_score,omitempty"`
+}
+
+type IntelligenceRunResponse struct {
+	RunID             int64                   `json:"run_id"`
+	CreatedAt         string                  `json:"created_at"`
+	IntendedUse       string                  `json:"intended_use"`
+	BudgetUSD         float64                 `json:"budget_usd"`
+	BatteryPreference string                  `json:"battery_preference"`
+	SizeConstraint    string                  `json:"size_constraint"`
+	AlgorithmVersion  string                  `json:"algorithm_version"`
+	TopResults        []IntelligenceRunResult `json:"top_results"`
+}
+
+type IntelligenceResponse struct {
+	IntendedUse       string                  `json:"intended_use"`
+	BudgetUSD         float64                 `json:"budget_usd"`
+	BatteryPreference string                  `json:"battery_preference"`
+	SizeConstraint    string                  `json:"size_constraint"`
+	AlgorithmVersion  string                  `json:"algorithm_version"`
+	TopResults        []IntelligenceRunResult `json:"top_results"`
+}
+
+// Problem is the RFC 7807 body of every 400 response. Errors lists each
+// invalid field.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is one invalid parameter or body field. Allowed lists the
+// accepted values when there is a fixed set.
+type FieldError struct {
+	Field   string   `json:"field"`
+	Value   string   `json:"value,omitempty"`
+	Message string   `json:"message"`
+	Allowed []string `json:"allowed,omitempty"`
+}
@@ -241,9 +241,10 @@ do_deploy() {
   # ── Health check ─────────────────────────────────────────────────
   echo ""
   echo "→ Health checks:"
-  api_status=$(curl -sf -o /dev/null -w "%{http_code}" http://localhost:8080/rankings || echo "000")
+  api_status=$(curl -sf -o /dev/null -w "%{http_code}" http://localhost:8080/readyz || echo "000")
   web_status=$(curl -sf -o /dev/null -w "%{http_code}" http://localhost:3000/ || echo "000")
   echo "  API:  ${api_status}"
+  curl -s http://localhost:8080/status | sed 's/^/  Status: /' || true
   echo "  Web:  ${web_status}"
 
   echo ""
@@ -21,18 +21,24 @@ export default async function sitemap(): Promise<MetadataRoute.Sitemap> {
     priority: 0.85
   }));
 
-  let productPages: MetadataRoute.Sitemap = [];
+  const productPages: MetadataRoute.Sitemap = [];
   try {
     const API_BASE = process.env.API_BASE_URL || process.env.NEXT_PUBLIC_API_BASE_URL || "http://localhost:8080";
-    const res = await fetch(`${API_BASE}/flashlights?page=1&page_size=500`, { cache: "no-store" });
-    if (res.ok) {
+    const headers: Record<string, string> = process.env.API_KEY ? { "X-API-Key": process.env.API_KEY } : {};
+    // page_size is capped at 100, so walk the pages up to total_pages.
+    for (let page = 1, totalPages = 1; page <= totalPages && page <= 200; page++) {
+      const res = await fetch(`${API_BASE.replace(/\/+$/, "")}/v1/flashlights?page=${page}&page_size=100`, { headers, cache: "no-store" });
+      if (!res.ok) break;
       const data = await res.json();
-      productPages = (data.items || []).map((item: { id: number; slug?: string }) => ({
-        url: `${BASE_URL}/flashlights/${item.id}`,
-        lastModified: new Date(),
-        changeFrequency: "weekly" as const,
-        priority: 0.8
-      }));
+      productPages.push(
+        ...(data.items || []).map((item: { id: number; slug?: string }) => ({
+          url: `${BASE_URL}/flashlights/${item.id}`,
+          lastModified: new Date(),
+          changeFrequency: "weekly" as const,
+          priority: 0.8
+        }))
+      );
+      totalPages = data.total_pages || 0;
     }
   } catch {
     // API unavailable during build; product pages will be added on next regeneration
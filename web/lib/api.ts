@@ -90,6 +90,8 @@ export type FlashlightListResponse = {
   page_size: number;
   total: number;
   total_pages: number;
+  next_cursor?: string;
+  prev_cursor?: string;
   items: FlashlightItem[];
 };
 
@@ -154,8 +156,20 @@ const API_BASE =
   process.env.NEXT_PUBLIC_API_BASE_URL ||
   "http://localhost:8080";
 
+// Unversioned paths are deprecated aliases; always call the versioned API.
+const API_URL = `${API_BASE.replace(/\/+$/, "")}/v1`;
+
+// Internal API key; keeps server-side rendering out of the per-address rate
+// limit. Never exposed to the browser.
+const API_KEY = process.env.API_KEY || "";
+
+function apiHeaders(extra: Record<string, string> = {}): Record<string, string> {
+  return API_KEY ? { ...extra, "X-API-Key": API_KEY } : extra;
+}
+
 async function getJSON<T>(path: string): Promise<T> {
-  const res = await fetch(`${API_BASE}${path}`, {
+  const res = await fetch(`${API_URL}${path}`, {
+    headers: apiHeaders(),
     cache: "no-store"
   });
   if (!res.ok) {
@@ -183,9 +197,9 @@ export async function fetchFlashlights() {
 }
 
 export async function createIntelligenceRun(input: IntelligenceRunInput) {
-  const res = await fetch(`${API_BASE}/intelligence/runs`, {
+  const res = await fetch(`${API_URL}/intelligence/runs`, {
     method: "POST",
-    headers: { "Content-Type": "application/json" },
+    headers: apiHeaders({ "Content-Type": "application/json" }),
     body: JSON.stringify(input),
     cache: "no-store"
   });
@@ -200,9 +214,9 @@ export async function fetchIntelligenceRun(runID: string | number) {
 }
 
 export async function fetchIntelligenceRecommendations(input: IntelligenceRunInput) {
-  const res = await fetch(`${API_BASE}/intelligence/recommendations`, {
+  const res = await fetch(`${API_URL}/intelligence/recommendations`, {
     method: "POST",
-    headers: { "Content-Type": "application/json" },
+    headers: apiHeaders({ "Content-Type": "application/json" }),
     body: JSON.stringify(input),
     cache: "no-store"
   });